@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go-starter/cmd/server/router"
+	"go-starter/database"
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/buildinfo"
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/migrate"
+	"go-starter/internal/pkg/ptr"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// errUsage is returned when a command is called with invalid arguments, the process exits with code 2
+var errUsage = errors.New("invalid usage, see --help")
+
+// command is a subcommand of the server binary
+type command struct {
+	name    string // e.g. "config print"
+	args    string // Synopsis of the positional arguments
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+// commands returns the subcommands, the first one runs when none is given
+func commands() []command {
+	return []command{
+		{name: "serve", args: "", summary: "Run the HTTP server and the scheduler", run: serve},
+		{name: "migrate", args: "[up [N] | down N|all | version]", summary: "Apply or roll back database migrations", run: runMigrate},
+		{name: "seed", args: "", summary: "Insert sample posts, skipping the existing ones", run: runSeed},
+		{name: "routes", args: "", summary: "Print every HTTP route with its middlewares", run: runRoutes},
+		{name: "config print", args: "", summary: "Print the effective configuration and where each value came from", run: printConfig},
+		{name: "config validate", args: "", summary: "Check that the configuration is complete and valid", run: validateConfig},
+		{name: "version", args: "", summary: "Print the version", run: runVersion},
+	}
+}
+
+// execute runs the command named by the leading arguments, serve by default
+func execute(ctx context.Context, args []string) error {
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help") {
+		return serve(ctx, args)
+	}
+
+	for _, c := range commands() {
+		words := strings.Fields(c.name)
+		if len(args) >= len(words) && slices.Equal(args[:len(words)], words) {
+			return c.run(ctx, args[len(words):])
+		}
+	}
+
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(os.Stdout)
+		return nil
+	}
+
+	printUsage(os.Stderr)
+	return fmt.Errorf("%w: unknown command %q", errUsage, strings.Join(args, " "))
+}
+
+// printUsage lists the commands
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: server <command> [flags] [args]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, c := range commands() {
+		fmt.Fprintf(tw, "  %s %s\t%s\n", c.name, c.args, c.summary)
+	}
+	_ = tw.Flush()
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "server <command> --help" for the flags of a command.`)
+}
+
+// newFlagSet creates the flag set of a command, printing its usage on --help
+func newFlagSet(name, argsUsage, summary string) *flag.FlagSet {
+	fs := flag.NewFlagSet("server "+name, flag.ContinueOnError)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: server %s [flags] %s\n\n%s\n\nFlags:\n", name, argsUsage, summary)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// usageError marks flag parsing errors as usage errors, except for --help
+func usageError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err //nolint:wrapcheck // sentinel checked by main
+	}
+	return fmt.Errorf("%w: %w", errUsage, err)
+}
+
+// load parses the flags of a command expecting no argument, loads the
+// configuration and sets up the logger
+func load(ctx context.Context, name, summary string, args []string) (context.Context, config, func(), error) {
+	loader, rest, err := newConfigLoader(name, "", summary, args)
+	if err != nil {
+		return nil, config{}, nil, err
+	}
+	if len(rest) > 0 {
+		return nil, config{}, nil, fmt.Errorf("%w: unexpected arguments %q", errUsage, rest)
+	}
+
+	c, err := loader.load(nil)
+	if err != nil {
+		return nil, config{}, nil, err
+	}
+	ctx, closeLog, err := setup(ctx, c, new(slog.LevelVar))
+	if err != nil {
+		return nil, config{}, nil, err
+	}
+	return ctx, c, closeLog, nil
+}
+
+// runMigrate applies the embedded migrations
+func runMigrate(ctx context.Context, args []string) error {
+	loader, rest, err := newConfigLoader("migrate", "[up [N] | down N|all | version]",
+		"Apply all or N pending migrations, roll back N or all of them, or print the current version.", args)
+	if err != nil {
+		return err
+	}
+
+	action, n, err := parseMigrateArgs(rest)
+	if err != nil {
+		return err
+	}
+
+	c, err := loader.load(nil)
+	if err != nil {
+		return err
+	}
+	ctx, closeLog, err := setup(ctx, c, new(slog.LevelVar))
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	migrations, err := fs.Sub(database.Migrations, "migrations")
+	if err != nil {
+		return fmt.Errorf("fs.Sub: %w", err)
+	}
+	m, err := migrate.New(migrations)
+	if err != nil {
+		return fmt.Errorf("migrate.New: %w", err)
+	}
+
+	pool, err := connect(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("pool.Acquire: %w", err)
+	}
+	defer conn.Release()
+
+	switch action {
+	case "up":
+		if _, err := m.Up(ctx, conn, n); err != nil {
+			return fmt.Errorf("migrate.Up: %w", err)
+		}
+	case "down":
+		if _, err := m.Down(ctx, conn, n); err != nil {
+			return fmt.Errorf("migrate.Down: %w", err)
+		}
+	}
+
+	version, dirty, err := m.Version(ctx, conn)
+	if err != nil {
+		return fmt.Errorf("migrate.Version: %w", err)
+	}
+	fmt.Fprintf(os.Stdout, "version %d, dirty %t\n", version, dirty)
+	return nil
+}
+
+// parseMigrateArgs returns the migrate action and its number of steps, 0 for all
+func parseMigrateArgs(args []string) (string, int, error) {
+	if len(args) == 0 {
+		return "up", 0, nil
+	}
+
+	action := args[0]
+	switch {
+	case action == "version" && len(args) == 1:
+		return action, 0, nil
+	case action == "up" && len(args) == 1:
+		return action, 0, nil
+	case action == "down" && len(args) == 2 && args[1] == "all":
+		return action, 0, nil
+	case (action == "up" || action == "down") && len(args) == 2:
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", 0, fmt.Errorf("%w: invalid number of migrations %q", errUsage, args[1])
+		}
+		return action, n, nil
+	default:
+		return "", 0, fmt.Errorf("%w: migrate %s", errUsage, strings.Join(args, " "))
+	}
+}
+
+// seedPosts are the sample posts inserted by the seed command
+var seedPosts = []models.CreatePostParams{
+	{
+		ID:          uuid.MustParse("00000000-0000-4000-8000-000000000001"),
+		Title:       "Welcome to go-starter",
+		Description: ptr.Ref("A sample post created by `server seed`."),
+		Slug:        "", // Allocated when seeding
+	},
+	{
+		ID:          uuid.MustParse("00000000-0000-4000-8000-000000000002"),
+		Title:       "Streaming post changes",
+		Description: ptr.Ref("Subscribe to /api/v1/posts/stream to receive changes as Server-Sent Events."),
+		Slug:        "",
+	},
+	{
+		ID:          uuid.MustParse("00000000-0000-4000-8000-000000000003"),
+		Title:       "Editing together",
+		Description: ptr.Ref("Connect to /api/v1/posts/{id}/ws to see who else is editing a post."),
+		Slug:        "",
+	},
+}
+
+// runSeed inserts the sample posts in a transaction, skipping the existing ones
+func runSeed(ctx context.Context, args []string) error {
+	ctx, c, closeLog, err := load(ctx, "seed", "Insert sample posts, skipping the existing ones.", args)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	pool, err := connect(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	q := models.New()
+	created := 0
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		for _, p := range seedPosts {
+			_, err := q.GetPost(ctx, tx, p.ID)
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("q.GetPost: %w", err)
+			}
+			if p.Slug, err = router.PostSlug(ctx, q, tx, p.ID, p.Title); err != nil {
+				return err
+			}
+			if _, err := q.CreatePost(ctx, tx, p); err != nil {
+				return fmt.Errorf("q.CreatePost: %w", err)
+			}
+			created++
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("pgx.BeginFunc: %w", err)
+	}
+
+	slogr.FromContext(ctx).Info("[seed] done", slog.Int("created", created), slog.Int("skipped", len(seedPosts)-created))
+	return nil
+}
+
+// runRoutes prints the routes of the HTTP handler, without connecting to the
+// database or starting anything
+func runRoutes(_ context.Context, args []string) error {
+	loader, rest, err := newConfigLoader("routes", "", "Print every HTTP route with its middlewares.", args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, rest)
+	}
+
+	sources, err := loader.sources()
+	if err != nil {
+		return err
+	}
+	// Missing and invalid variables, e.g. the database URL, keep their default
+	// as the routes only depend on the compressed content types
+	var c config
+	_ = envvar.Load(&c, envvar.WithSources(sources...))
+
+	handler := router.Routes(c.router(router.NewLiveSettings(c.settings()), new(slog.LevelVar)))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "METHOD\tROUTE\tMIDDLEWARES")
+	err = chi.Walk(handler, func(method, route string, _ http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		names := make([]string, len(middlewares))
+		for i, mw := range middlewares {
+			names[i] = funcName(mw)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", method, route, strings.Join(names, ", "))
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("chi.Walk: %w", err)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("tw.Flush: %w", err)
+	}
+	return nil
+}
+
+// funcName returns the short name of a function, e.g. "router.requireFeature"
+// for a closure returned by router.requireFeature
+func funcName(f any) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "?"
+	}
+	name := fn.Name()
+	name = name[strings.LastIndex(name, "/")+1:]
+	// Drop the suffixes of closures, e.g. ".func1" or ".1"
+	parts := strings.Split(name, ".")
+	for len(parts) > 2 && strings.TrimLeft(strings.TrimPrefix(parts[len(parts)-1], "func"), "0123456789") == "" {
+		parts = parts[:len(parts)-1]
+	}
+	return strings.Join(parts, ".")
+}
+
+// runVersion prints the version, or every build information with --json
+func runVersion(_ context.Context, args []string) error {
+	fs := newFlagSet("version", "", "Print the version.")
+	asJSON := fs.Bool("json", false, "print the build information and module dependencies as JSON")
+	if err := fs.Parse(args); err != nil {
+		return usageError(err)
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, fs.Args())
+	}
+
+	info := buildinfo.Get()
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(info); err != nil {
+			return fmt.Errorf("enc.Encode: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Fprintf(os.Stdout, "server %s\n", info.Version)
+	fmt.Fprintf(os.Stdout, "  revision:   %s (modified: %t)\n", info.Revision, info.Modified)
+	fmt.Fprintf(os.Stdout, "  build time: %s\n", info.BuildTime)
+	fmt.Fprintf(os.Stdout, "  go version: %s\n", info.GoVersion)
+	return nil
+}
@@ -0,0 +1,394 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/pkg/certs"
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/listen"
+	"go-starter/internal/pkg/slogr"
+)
+
+// config holds all the configuration options for the server.
+// These are loaded from layered sources, see configLoader and envvar.Load for the tags.
+// Settings tagged reload:"hot" are applied on reload, any other change needs a restart.
+type config struct {
+	Log       logConfig       `prefix:"LOG_"`
+	Database  databaseConfig  `prefix:"DATABASE_"`
+	Server    serverConfig    `prefix:"SERVER_"`
+	Scheduler schedulerConfig `prefix:"SCHEDULER_"`
+	Admin     adminConfig     `prefix:"ADMIN_"`
+	Quote     quoteConfig     `prefix:"QUOTE_"`
+	Import    importConfig    `prefix:"IMPORT_"`
+	CORS      corsConfig      `prefix:"CORS_"       reload:"hot"`
+	RateLimit rateLimitConfig `prefix:"RATE_LIMIT_" reload:"hot"`
+
+	// Features enables optional routes, e.g. "post_stream=true,post_presence=false"
+	Features map[string]bool `env:"FEATURE_FLAGS" default:"post_stream=true,post_presence=true" reload:"hot"`
+}
+
+// logConfig holds the logging configuration
+type logConfig struct {
+	Level  slog.Level      `env:"LEVEL"  default:"INFO" reload:"hot"` // Minimum severity level for console output
+	Format slogr.Format    `env:"FORMAT" default:"json"`              // Console output format: json, text or pretty
+	File   logFileConfig   `prefix:"FILE_"`
+	Redact logRedactConfig `prefix:"REDACT_"`
+	Sample logSampleConfig `prefix:"SAMPLE_"`
+}
+
+// logRedactConfig holds the rules to remove sensitive values from logs
+type logRedactConfig struct {
+	Keys   []string             `env:"KEYS"   default:"password,secret,*token*,authorization,cookie,*api_key*"` // Attribute keys whose value is redacted, with wildcards
+	Values []slogr.ValuePattern `env:"VALUES" default:"email,bearer,card"`                                      // Patterns redacted in values: email, bearer or card
+}
+
+// logSampleConfig holds the sampling of repetitive INFO and DEBUG logs
+type logSampleConfig struct {
+	Interval   time.Duration `env:"INTERVAL"   default:"1s"`  // Period the counters are reset after, 0 disables sampling
+	Initial    int           `env:"INITIAL"    default:"100"` // Records of each message kept per interval
+	Thereafter int           `env:"THEREAFTER" default:"100"` // Then keep every nth record, 0 drops them all
+}
+
+// logFileConfig holds the configuration of the optional log file
+type logFileConfig struct {
+	Path           string        `env:"PATH"`                           // File to write logs to as well, disabled when empty
+	Level          slog.Level    `env:"LEVEL"           default:"INFO"` // Minimum severity level for the file
+	Format         slogr.Format  `env:"FORMAT"          default:"json"` // Output format: json, text or pretty
+	MaxSizeMB      int64         `env:"MAX_SIZE_MB"     default:"100"`  // Rotate once the file reaches this size, 0 disables it
+	RotateInterval time.Duration `env:"ROTATE_INTERVAL" default:"0s"`   // Rotate once the file is this old, 0 disables it
+	MaxBackups     int           `env:"MAX_BACKUPS"     default:"7"`    // Rotated files to keep, 0 keeps them all
+	MaxAge         time.Duration `env:"MAX_AGE"         default:"0s"`   // Remove rotated files older than this, 0 disables it
+	Compress       bool          `env:"COMPRESS"        default:"true"` // Gzip rotated files
+}
+
+// databaseConfig holds the database configuration
+type databaseConfig struct {
+	URL             envvar.Secret `env:"URL"               required:"true"` // PostgreSQL connection URL
+	IdleConnTimeout time.Duration `env:"IDLE_CONN_TIMEOUT" default:"30m"`   // Maximum time a connection can be idle
+	Conns           int32         `env:"CONNS"             default:"4"`     // Maximum number of database connections
+}
+
+// serverConfig holds the HTTP server configuration
+type serverConfig struct {
+	Addrs           []string          `env:"ADDR"             default:":8080"`       // Addresses to listen on: "host:port", "unix:/path/to.sock", "systemd" or "systemd:name"
+	ReadTimeout     time.Duration     `env:"READ_TIMEOUT"     default:"60s"`         // Maximum duration for reading entire request
+	WriteTimeout    time.Duration     `env:"WRITE_TIMEOUT"    default:"60s"`         // Maximum duration for writing response
+	IdleTimeout     time.Duration     `env:"IDLE_TIMEOUT"     default:"120s"`        // Maximum duration for idle keep-alive connections
+	ShutdownTimeout time.Duration     `env:"SHUTDOWN_TIMEOUT" default:"30s"`         // How long in-flight requests may finish on shutdown
+	RestartTimeout  time.Duration     `env:"RESTART_TIMEOUT"  default:"30s"`         // How long the process started on SIGUSR2 may take to become ready
+	Protocols       []router.Protocol `env:"PROTOCOLS"        default:"http1,http2"` // Accepted protocols: http1, http2 (over TLS) and h2c (unencrypted HTTP/2)
+	MaxHeaderBytes  int               `env:"MAX_HEADER_BYTES" default:"1048576"`     // Maximum size of request headers
+	HTTP2           http2Config       `prefix:"HTTP2_"`
+	Compress        compressConfig    `prefix:"COMPRESS_"`
+	Socket          socketConfig      `prefix:"SOCKET_"`
+	TLS             tlsConfig         `prefix:"TLS_"`
+}
+
+// http2Config holds the HTTP/2 settings of both http2 and h2c connections
+type http2Config struct {
+	MaxConcurrentStreams    int           `env:"MAX_CONCURRENT_STREAMS"            default:"250"`     // Streams a client may open at once per connection
+	MaxReadFrameSize        int           `env:"MAX_READ_FRAME_SIZE"               default:"1048576"` // Largest frame accepted, from 16KiB to 16MiB
+	MaxReceiveBufferPerConn int           `env:"MAX_RECEIVE_BUFFER_PER_CONNECTION" default:"1048576"` // Flow control window of a connection
+	MaxReceiveBufferStream  int           `env:"MAX_RECEIVE_BUFFER_PER_STREAM"     default:"1048576"` // Flow control window of a stream
+	PingTimeout             time.Duration `env:"PING_TIMEOUT"                      default:"15s"`     // How long a ping may go unanswered before the connection is closed
+	SendPingTimeout         time.Duration `env:"SEND_PING_TIMEOUT"                 default:"0s"`      // Idle time before checking the connection with a ping, 0 disables
+}
+
+// compressConfig holds the response compression settings, negotiated with
+// Accept-Encoding among zstd, gzip and deflate
+type compressConfig struct {
+	MinSize int      `env:"MIN_SIZE" default:"1024"`                                                                                // Smallest response body compressed, in bytes
+	Types   []string `env:"TYPES"    default:"application/json,application/x-ndjson,application/xml,text/csv,text/html,text/plain"` // Content types compressed, with wildcards such as "text/*", compression is disabled when empty
+}
+
+// socketConfig holds the settings of the Unix domain sockets of SERVER_ADDR
+type socketConfig struct {
+	Mode  listen.FileMode `env:"MODE"  default:"0660"` // Permissions of the socket file, in octal
+	Owner listen.Owner    `env:"OWNER"`                // Owner of the socket file: "user", "user:group" or ":group", unchanged when empty
+}
+
+// tlsConfig holds the TLS configuration of the HTTP server. Certificate files
+// are reloaded when they change, e.g. when renewed by cert-manager.
+type tlsConfig struct {
+	CertFile     string              `env:"CERT_FILE"`                         // PEM certificate chain, TLS is disabled when empty
+	KeyFile      string              `env:"KEY_FILE"`                          // PEM private key of the certificate
+	ClientCAFile string              `env:"CLIENT_CA_FILE"`                    // PEM bundle of CAs verifying client certificates, mutual TLS is disabled when empty
+	ClientAuth   certs.ClientAuth    `env:"CLIENT_AUTH"    default:"optional"` // Client certificates: optional (verified when presented) or require
+	MinVersion   certs.Version       `env:"MIN_VERSION"    default:"1.2"`      // Minimum TLS version: 1.2 or 1.3
+	Ciphers      []certs.CipherSuite `env:"CIPHERS"`                           // TLS 1.2 cipher suites by IANA name, Go defaults when empty
+}
+
+// schedulerConfig holds the scheduler configuration
+type schedulerConfig struct {
+	RunRetention time.Duration `env:"RUN_RETENTION" default:"720h"` // How long job run history is kept
+}
+
+// adminConfig holds the admin API configuration
+type adminConfig struct {
+	Token envvar.Secret `env:"TOKEN"` // Bearer token of the admin API and X-Debug-Log header, disabled when empty
+	Addr  string        `env:"ADDR"`  // Private address serving pprof, expvar and runtime diagnostics, disabled when empty
+}
+
+// quoteConfig holds the quote proxy configuration
+type quoteConfig struct {
+	Providers  router.QuoteProviders `env:"PROVIDERS"   default:"dummyjson=https://dummyjson.com/quotes/random"` // Upstream quote APIs, tried in order
+	CacheTTL   time.Duration         `env:"CACHE_TTL"   default:"1m"`                                            // How long quotes are served from cache
+	CacheStale time.Duration         `env:"CACHE_STALE" default:"5m"`                                            // How long stale quotes are served while revalidating
+}
+
+// importConfig holds the limits of post imports
+type importConfig struct {
+	MaxSize   int64 `env:"MAX_SIZE"   default:"67108864"` // Largest import file accepted, in bytes
+	AsyncSize int64 `env:"ASYNC_SIZE" default:"1048576"`  // Files at least this large are imported in the background, in bytes
+}
+
+// corsConfig holds the CORS configuration
+type corsConfig struct {
+	Origins []string `env:"ORIGINS" default:"*"` // Allowed origins, "*" allows any
+}
+
+// rateLimitConfig holds the per client IP rate limit configuration
+type rateLimitConfig struct {
+	RPS   float64 `env:"RPS"   default:"0"`  // Requests per second, 0 disables rate limiting
+	Burst int     `env:"BURST" default:"20"` // Requests allowed at once
+}
+
+// errInvalidConfig is returned when configuration values are inconsistent
+var errInvalidConfig = errors.New("invalid configuration")
+
+// defaultEnvFile is read when present, unless another file is given
+const defaultEnvFile = ".env"
+
+// configLoader loads the configuration from layers of increasing precedence:
+// default tags < config file (YAML or TOML) < .env file < process environment < flags
+type configLoader struct {
+	configFile string        // YAML or TOML file, from --config or CONFIG_FILE
+	envFile    string        // .env file, from --env-file or ENV_FILE
+	flags      envvar.Source // Variables overridden on the command line
+}
+
+// newConfigLoader parses the command line flags of a command and returns the
+// remaining arguments. Every variable can be set with a flag named after it,
+// e.g. --server-addr for SERVER_ADDR.
+func newConfigLoader(name, argsUsage, summary string, args []string) (*configLoader, []string, error) {
+	fs := newFlagSet(name, argsUsage, summary)
+
+	l := &configLoader{
+		configFile: "",
+		envFile:    "",
+		flags:      envvar.Flags(fs, &config{}),
+	}
+	fs.StringVar(&l.configFile, "config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file")
+	fs.StringVar(&l.envFile, "env-file", os.Getenv("ENV_FILE"), "`.env` file (default "+defaultEnvFile+" when present)")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, usageError(err)
+	}
+
+	return l, fs.Args(), nil
+}
+
+// files returns the configuration files to watch for changes
+func (l *configLoader) files() []string {
+	var files []string
+	if l.configFile != "" {
+		files = append(files, l.configFile)
+	}
+	if f := l.dotenvFile(); f != "" {
+		files = append(files, f)
+	}
+	return files
+}
+
+// dotenvFile returns the .env file to read, if any
+func (l *configLoader) dotenvFile() string {
+	if l.envFile != "" {
+		return l.envFile
+	}
+	if _, err := os.Stat(defaultEnvFile); err == nil {
+		return defaultEnvFile
+	}
+	return ""
+}
+
+// load reads every layer and validates the resulting configuration.
+// It returns an error listing every variable that is missing or invalid.
+// When report is not nil, the effective value and source of every variable is
+// appended to it, even if the configuration is invalid.
+func (l *configLoader) load(report *[]envvar.Setting) (config, error) {
+	sources, err := l.sources()
+	if err != nil {
+		return config{}, err
+	}
+
+	var c config
+	if err := envvar.Load(&c, envvar.WithSources(sources...), envvar.WithReport(report)); err != nil {
+		return config{}, err //nolint:wrapcheck // already prefixed by envvar.Load
+	}
+	if err := c.validate(); err != nil {
+		return config{}, err
+	}
+
+	return c, nil
+}
+
+// sources reads the configuration files and returns every layer, in
+// increasing order of precedence
+func (l *configLoader) sources() ([]envvar.Source, error) {
+	var sources []envvar.Source
+	for _, f := range []string{l.configFile, l.dotenvFile()} {
+		if f == "" {
+			continue
+		}
+		src, err := envvar.ReadFile(f)
+		if err != nil {
+			return nil, err //nolint:wrapcheck // already prefixed by envvar.ReadFile
+		}
+		sources = append(sources, src)
+	}
+	return append(sources, envvar.Env, l.flags), nil
+}
+
+// printConfig writes the effective configuration, where each value came from,
+// with secrets redacted
+func printConfig(_ context.Context, args []string) error {
+	l, rest, err := newConfigLoader("config print", "", "Print the effective configuration and the source of each value, with secrets redacted.", args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, rest)
+	}
+
+	var report []envvar.Setting
+	_, loadErr := l.load(&report)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range report {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	if err := tw.Flush(); err != nil {
+		return fmt.Errorf("tw.Flush: %w", err)
+	}
+
+	return loadErr
+}
+
+// validateConfig loads the configuration without starting anything, e.g. to
+// check a deployment, and reports every missing or invalid variable
+func validateConfig(_ context.Context, args []string) error {
+	l, rest, err := newConfigLoader("config validate", "", "Check that the configuration is complete and valid.", args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("%w: unexpected arguments %q", errUsage, rest)
+	}
+
+	if _, err := l.load(nil); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stdout, "configuration is valid")
+	return nil
+}
+
+// validate checks the values that can be parsed but make no sense
+func (c config) validate() error {
+	var errs []error
+	if c.Database.Conns < 1 {
+		errs = append(errs, fmt.Errorf("%w: DATABASE_CONNS must be at least 1", errInvalidConfig))
+	}
+	if len(c.CORS.Origins) == 0 {
+		errs = append(errs, fmt.Errorf("%w: CORS_ORIGINS must not be empty", errInvalidConfig))
+	}
+	if c.RateLimit.RPS < 0 {
+		errs = append(errs, fmt.Errorf("%w: RATE_LIMIT_RPS must not be negative", errInvalidConfig))
+	}
+	if c.RateLimit.RPS > 0 && c.RateLimit.Burst < 1 {
+		errs = append(errs, fmt.Errorf("%w: RATE_LIMIT_BURST must be at least 1", errInvalidConfig))
+	}
+	if c.Log.Sample.Initial < 0 || c.Log.Sample.Thereafter < 0 {
+		errs = append(errs, fmt.Errorf("%w: LOG_SAMPLE_INITIAL and LOG_SAMPLE_THEREAFTER must not be negative", errInvalidConfig))
+	}
+	if c.Server.ShutdownTimeout <= 0 || c.Server.RestartTimeout <= 0 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_SHUTDOWN_TIMEOUT and SERVER_RESTART_TIMEOUT must be positive", errInvalidConfig))
+	}
+	if len(c.Server.Protocols) == 0 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_PROTOCOLS must not be empty", errInvalidConfig))
+	}
+	if c.Server.MaxHeaderBytes < 1 || c.Server.HTTP2.MaxConcurrentStreams < 1 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_MAX_HEADER_BYTES and SERVER_HTTP2_MAX_CONCURRENT_STREAMS must be positive", errInvalidConfig))
+	}
+	if c.Server.HTTP2.MaxReadFrameSize < 16<<10 || c.Server.HTTP2.MaxReadFrameSize > 16<<20 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_HTTP2_MAX_READ_FRAME_SIZE must be between 16KiB and 16MiB", errInvalidConfig))
+	}
+	if c.Server.Compress.MinSize < 0 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_COMPRESS_MIN_SIZE must not be negative", errInvalidConfig))
+	}
+	if c.Import.MaxSize < 1 || c.Import.AsyncSize < 0 {
+		errs = append(errs, fmt.Errorf("%w: IMPORT_MAX_SIZE must be positive and IMPORT_ASYNC_SIZE not negative", errInvalidConfig))
+	}
+	if len(c.Server.Addrs) == 0 {
+		errs = append(errs, fmt.Errorf("%w: SERVER_ADDR must not be empty", errInvalidConfig))
+	}
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%w: SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set together", errInvalidConfig))
+	}
+	if c.Server.TLS.ClientCAFile != "" && c.Server.TLS.CertFile == "" {
+		errs = append(errs, fmt.Errorf("%w: SERVER_TLS_CLIENT_CA_FILE requires SERVER_TLS_CERT_FILE", errInvalidConfig))
+	}
+	return errors.Join(errs...)
+}
+
+// settings returns the router settings that may be reloaded
+func (c config) settings() router.Settings {
+	return router.Settings{
+		CORSOrigins: c.CORS.Origins,
+		RateLimit:   c.RateLimit.RPS,
+		RateBurst:   c.RateLimit.Burst,
+		Features:    c.Features,
+	}
+}
+
+// http2 returns the HTTP/2 settings of the server
+func (c serverConfig) http2() *http.HTTP2Config {
+	return &http.HTTP2Config{
+		MaxConcurrentStreams:          c.HTTP2.MaxConcurrentStreams,
+		MaxDecoderHeaderTableSize:     0,
+		MaxEncoderHeaderTableSize:     0,
+		MaxReadFrameSize:              c.HTTP2.MaxReadFrameSize,
+		MaxReceiveBufferPerConnection: c.HTTP2.MaxReceiveBufferPerConn,
+		MaxReceiveBufferPerStream:     c.HTTP2.MaxReceiveBufferStream,
+		SendPingTimeout:               c.HTTP2.SendPingTimeout,
+		PingTimeout:                   c.HTTP2.PingTimeout,
+		WriteByteTimeout:              0,
+		PermitProhibitedCipherSuites:  false,
+		CountError:                    nil,
+	}
+}
+
+// router returns the configuration of the HTTP handlers
+func (c config) router(settings *router.LiveSettings, level *slog.LevelVar) router.Config {
+	return router.Config{
+		Timeout:         c.Server.ReadTimeout + c.Server.WriteTimeout,
+		QuoteProviders:  c.Quote.Providers,
+		QuoteCacheTTL:   c.Quote.CacheTTL,
+		QuoteCacheStale: c.Quote.CacheStale,
+		Settings:        settings,
+		LogLevel:        level,
+		AdminToken:      c.Admin.Token,
+		CompressMinSize: c.Server.Compress.MinSize,
+		CompressTypes:   c.Server.Compress.Types,
+		ImportMaxSize:   c.Import.MaxSize,
+		ImportAsyncSize: c.Import.AsyncSize,
+	}
+}
@@ -2,111 +2,475 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
+	"io/fs"
 	"log/slog"
+	"maps"
+	"net"
 	"net/http"
 	"os"
+	"os/exec"
 	"os/signal"
+	"regexp"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
 	"go-starter/cmd/server/router"
+	"go-starter/cmd/server/scheduler"
+	"go-starter/internal/models"
 	"go-starter/internal/pkg/buildinfo"
+	"go-starter/internal/pkg/certs"
 	"go-starter/internal/pkg/db"
-	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/listen"
+	"go-starter/internal/pkg/reload"
 	"go-starter/internal/pkg/slogr"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	// Setup context with cancellation on SIGINT or SIGTERM
 	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
-	if err := run(ctx); err != nil {
-		cancel()
+
+	err := execute(ctx, os.Args[1:])
+	cancel()
+
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	default:
 		slog.Error("something happen", slog.Any("err", err))
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context) error {
-	// Load and validate configuration from environment variables
-	config, err := newConfig()
-	if err != nil {
-		return fmt.Errorf("newConfig: %w", err)
-	}
+// setup configures the logger shared by every command and adds it to ctx.
+// The returned function flushes the logs.
+func setup(ctx context.Context, c config, level *slog.LevelVar) (context.Context, func(), error) {
+	level.Set(c.Log.Level)
 
 	// Initialize structured logger with build information
-	slogr.SetDefaultJSON(config.logLevel)
+	closeLog, err := setupLogger(c.Log, level)
+	if err != nil {
+		return nil, nil, fmt.Errorf("setupLogger: %w", err)
+	}
+	info := buildinfo.Get()
 	logger := slog.Default().With(
-		slog.String("version", buildinfo.Version),
-		slog.String("build-time", buildinfo.BuildTime),
+		slog.String("version", info.Version),
+		slog.String("build-time", info.BuildTime),
 	)
-	ctx = slogr.ToContext(ctx, logger)
 
-	// Initialize database connection with configured parameters
-	db, err := db.Connect(ctx, config.databaseURL,
-		db.WithMaxConnIdleTime(config.databaseIdleConnTimeout),
-		db.WithMinConns(config.databaseConns),
-		db.WithMaxConns(config.databaseConns))
+	return slogr.ToContext(ctx, logger), closeLog, nil
+}
+
+// connect opens the database connection pool with the configured parameters
+func connect(ctx context.Context, c config) (*pgxpool.Pool, error) {
+	pool, err := db.Connect(ctx, c.Database.URL.Value(),
+		db.WithMaxConnIdleTime(c.Database.IdleConnTimeout),
+		db.WithMinConns(c.Database.Conns),
+		db.WithMaxConns(c.Database.Conns))
 	if err != nil {
-		return fmt.Errorf("db.Connect: %w", err)
+		return nil, fmt.Errorf("db.Connect: %w", err)
 	}
+	return pool, nil
+}
+
+// serve runs the HTTP server and the scheduler until ctx is canceled
+func serve(ctx context.Context, args []string) error {
+	// Load and validate layered configuration, reloading the hot settings
+	// on SIGHUP or when a configuration file changes
+	loader, _, err := newConfigLoader("serve", "", "Run the HTTP server and the scheduler.", args)
+	if err != nil {
+		return err
+	}
+
+	logLevel := new(slog.LevelVar)
+	settings := router.NewLiveSettings(router.Settings{})
+	reloader, err := reload.New(
+		func() (config, error) { return loader.load(nil) },
+		func(_ context.Context, c config) error {
+			logLevel.Set(c.Log.Level)
+			settings.Store(c.settings())
+			return nil
+		},
+		reload.WithFiles[config](loader.files()...))
+	if err != nil {
+		return fmt.Errorf("reload.New: %w", err)
+	}
+	config := reloader.Current()
+	settings.Store(config.settings())
+
+	ctx, closeLog, err := setup(ctx, config, logLevel)
+	if err != nil {
+		return err
+	}
+	defer closeLog()
+
+	db, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
 
 	// Setup HTTP router with configured timeout
-	handler, err := router.Handler(ctx, db, config.serverReadTimeout+config.serverWriteTimeout)
+	handler, err := router.Handler(ctx, db, config.router(settings, logLevel))
 	if err != nil {
 		return fmt.Errorf("router.Handler: %w", err)
 	}
 
 	// Initialize HTTP server
 	server := &http.Server{
-		Addr:                         config.serverAddr,
+		Addr:                         "", // Served on the listeners of config.Server.Addrs
 		Handler:                      handler,
 		DisableGeneralOptionsHandler: false,
 		TLSConfig:                    nil,
-		ReadTimeout:                  config.serverReadTimeout,
+		ReadTimeout:                  config.Server.ReadTimeout,
 		ReadHeaderTimeout:            10 * time.Second,
-		WriteTimeout:                 config.serverWriteTimeout,
-		IdleTimeout:                  config.serverIdleTimeout,
-		MaxHeaderBytes:               0,
+		WriteTimeout:                 config.Server.WriteTimeout,
+		IdleTimeout:                  config.Server.IdleTimeout,
+		MaxHeaderBytes:               config.Server.MaxHeaderBytes,
 		TLSNextProto:                 nil,
 		ConnState:                    nil,
 		ErrorLog:                     nil,
 		BaseContext:                  nil,
 		ConnContext:                  nil,
-		HTTP2:                        nil,
-		Protocols:                    nil,
+		HTTP2:                        config.Server.http2(),
+		Protocols:                    router.Protocols(config.Server.Protocols),
+	}
+
+	// Serve HTTPS when a certificate is configured, reloading it when the files change
+	var certReloader *certs.Reloader
+	if config.Server.TLS.CertFile != "" {
+		server.TLSConfig, certReloader, err = newTLSConfig(config.Server.TLS, server.Protocols)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Listen on every configured address: TCP, Unix sockets and systemd sockets
+	listeners, err := listenAll(ctx, config.Server.Addrs,
+		listen.WithMode(fs.FileMode(config.Server.Socket.Mode)),
+		listen.WithOwner(config.Server.Socket.Owner))
+	if err != nil {
+		return err
+	}
+	servers := []httpServer{{Server: server, listeners: listeners}}
+
+	// Serve profiling and diagnostics on a separate, private address
+	if config.Admin.Addr != "" {
+		adminListeners, err := listenAll(ctx, []string{config.Admin.Addr})
+		if err != nil {
+			closeListeners(listeners)
+			return err
+		}
+		servers = append(servers, httpServer{listeners: adminListeners, Server: &http.Server{
+			Addr:                         "",
+			Handler:                      router.DebugHandler(db),
+			DisableGeneralOptionsHandler: false,
+			TLSConfig:                    nil,
+			ReadTimeout:                  config.Server.ReadTimeout,
+			ReadHeaderTimeout:            10 * time.Second,
+			WriteTimeout:                 0, // CPU profiles and traces last as long as requested
+			IdleTimeout:                  config.Server.IdleTimeout,
+			MaxHeaderBytes:               0,
+			TLSNextProto:                 nil,
+			ConnState:                    nil,
+			ErrorLog:                     nil,
+			BaseContext:                  nil,
+			ConnContext:                  nil,
+			HTTP2:                        nil,
+			Protocols:                    nil,
+		}})
+	}
+
+	// Initialize scheduler for periodic jobs
+	sched, err := newScheduler(db, config)
+	if err != nil {
+		for _, s := range servers {
+			closeListeners(s.listeners)
+		}
+		return fmt.Errorf("newScheduler: %w", err)
 	}
 
-	// Start server and handle graceful shutdown
-	err = runHTTPServer(ctx, server)
-	if err != nil && !errors.Is(err, context.Canceled) {
-		return fmt.Errorf("runHTTPServer: %w", err)
+	// Run the server and the scheduler side by side, stopping both if either fails
+	g, gctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		// Start server and handle graceful shutdown
+		if err := runHTTPServer(gctx, config.Server.ShutdownTimeout, servers...); err != nil && !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("runHTTPServer: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := sched.Run(gctx); err != nil {
+			return fmt.Errorf("scheduler.Run: %w", err)
+		}
+		return nil
+	})
+	g.Go(func() error {
+		if err := reloader.Run(gctx); err != nil {
+			return fmt.Errorf("reloader.Run: %w", err)
+		}
+		return nil
+	})
+	if certReloader != nil {
+		g.Go(func() error {
+			if err := certReloader.Run(gctx); err != nil {
+				return fmt.Errorf("certReloader.Run: %w", err)
+			}
+			return nil
+		})
 	}
+	g.Go(func() error {
+		return restartOnSignal(gctx, config.Server.RestartTimeout, servers)
+	})
 
+	// Stopping for a new process is not a failure
+	if err := g.Wait(); err != nil && !errors.Is(err, errRestarted) {
+		return err //nolint:wrapcheck // errors are wrapped in each goroutine
+	}
 	return nil
 }
 
-// runHTTPServer starts the HTTP server and handles graceful shutdown.
-// It uses errgroup to manage concurrent operations and ensure proper cleanup.
-func runHTTPServer(ctx context.Context, server *http.Server) error {
+// errRestarted stops the server once a new process took over its listeners
+var errRestarted = errors.New("restarted by a new process")
+
+// restartOnSignal starts a new process of the server on SIGUSR2 and hands the
+// listeners over to it, for upgrades without refusing connections. Once the
+// new process is ready, errRestarted is returned for this one to drain and exit.
+// The server keeps running when the new process fails to start.
+func restartOnSignal(ctx context.Context, timeout time.Duration, servers []httpServer) error {
 	logger := slogr.FromContext(ctx)
 
-	// Create errgroup for managing server goroutine
-	g, gctx := errgroup.WithContext(ctx)
+	usr2 := make(chan os.Signal, 1)
+	signal.Notify(usr2, syscall.SIGUSR2)
+	defer signal.Stop(usr2)
 
-	// Start server in a goroutine
-	g.Go(func() error {
-		logger.Info("starting HTTP server", slog.String("addr", server.Addr))
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-usr2:
+		}
+
+		logger.Info("[restart] SIGUSR2 received, starting a new process")
+		pid, err := restart(ctx, timeout, servers)
+		if err != nil {
+			logger.Error("[restart] failed, still serving", slog.Any("err", err))
+			continue
+		}
+		logger.Info("[restart] new process ready, shutting down", slog.Int("pid", pid))
+		return errRestarted
+	}
+}
+
+// restart starts the server binary again with the same arguments and the
+// listeners of servers, and waits until it is ready. It returns its PID.
+func restart(ctx context.Context, timeout time.Duration, servers []httpServer) (int, error) {
+	// The path the binary was started from, rather than os.Executable, so that
+	// a binary replaced by an upgrade is started instead of the running one
+	exe, err := exec.LookPath(os.Args[0])
+	if err != nil {
+		return 0, fmt.Errorf("exec.LookPath: %w", err)
+	}
+
+	var handoff listen.Handoff
+	defer handoff.Close()
+	for _, s := range servers {
+		for addr, ls := range s.listeners {
+			if err := handoff.Add(addr, ls...); err != nil {
+				return 0, fmt.Errorf("handoff.Add: %w", err)
+			}
+		}
+	}
+
+	//nolint:gosec // the binary and arguments of this process
+	cmd := exec.Command(exe, os.Args[1:]...) //nolint:noctx // the new process outlives ctx
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+
+	startCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := handoff.Start(startCtx, cmd); err != nil {
+		return 0, fmt.Errorf("handoff.Start: %w", err)
+	}
+
+	return cmd.Process.Pid, nil
+}
+
+// newTLSConfig loads the server certificate and, for mutual TLS, the client CAs,
+// negotiating the TLS protocols of protocols with ALPN.
+// The returned reloader must run for rotated files to be picked up.
+func newTLSConfig(c tlsConfig, protocols *http.Protocols) (*tls.Config, *certs.Reloader, error) {
+	var opts []certs.Option
+	clientAuth := tls.NoClientCert
+	if c.ClientCAFile != "" {
+		opts = append(opts, certs.WithClientCA(c.ClientCAFile))
+		clientAuth = c.ClientAuth.Type()
+	}
+
+	reloader, err := certs.New(c.CertFile, c.KeyFile, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("certs.New: %w", err)
+	}
+
+	// Set rather than left to http.Server, whose copy the per client configs
+	// of mutual TLS do not see
+	var nextProtos []string
+	if protocols.HTTP2() {
+		nextProtos = append(nextProtos, "h2")
+	}
+	if protocols.HTTP1() {
+		nextProtos = append(nextProtos, "http/1.1")
+	}
+
+	//nolint:exhaustruct // zero values are the crypto/tls defaults
+	base := &tls.Config{
+		MinVersion:   uint16(c.MinVersion),
+		CipherSuites: certs.Ciphers(c.Ciphers),
+		ClientAuth:   clientAuth,
+		NextProtos:   nextProtos,
+	}
+
+	return reloader.Config(base), reloader, nil
+}
+
+// setupLogger configures the default logger to write to stdout and, when
+// configured, to a rotating log file. Sensitive values are redacted,
+// repetitive records sampled and the attributes of slogr.WithAttrs added.
+// The returned function closes the file.
+func setupLogger(c logConfig, level slog.Leveler) (func(), error) {
+	patterns := make([]*regexp.Regexp, 0, len(c.Redact.Values))
+	for _, p := range c.Redact.Values {
+		patterns = append(patterns, p.Regexp())
+	}
+	redact := slogr.Redact(slogr.WithRedactKeys(c.Redact.Keys...), slogr.WithRedactValues(patterns...))
 
-		// ListenAndServe returns ErrServerClosed on graceful shutdown
-		if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-			return fmt.Errorf("server.ListenAndServe: %w", err)
+	sinks := []slogr.Sink{{Writer: os.Stdout, Format: c.Format, Level: level, ReplaceAttr: redact}}
+
+	closeFile := func() {}
+	if c.File.Path != "" {
+		f, err := slogr.OpenRotatingFile(c.File.Path,
+			slogr.WithMaxSize(c.File.MaxSizeMB<<20),
+			slogr.WithRotateInterval(c.File.RotateInterval),
+			slogr.WithMaxBackups(c.File.MaxBackups),
+			slogr.WithMaxAge(c.File.MaxAge),
+			slogr.WithCompress(c.File.Compress))
+		if err != nil {
+			return nil, fmt.Errorf("slogr.OpenRotatingFile: %w", err)
+		}
+		sinks = append(sinks, slogr.Sink{Writer: f, Format: c.File.Format, Level: c.File.Level, ReplaceAttr: redact})
+		closeFile = func() { f.Close() }
+	}
+
+	handler := slogr.Sinks(sinks...)
+	if c.Sample.Interval > 0 {
+		handler = slogr.Sample(handler,
+			slogr.WithSampleInterval(c.Sample.Interval),
+			slogr.WithSampleInitial(c.Sample.Initial),
+			slogr.WithSampleThereafter(c.Sample.Thereafter))
+	}
+	slog.SetDefault(slog.New(slogr.ContextHandler(handler)))
+
+	return closeFile, nil
+}
+
+// newScheduler creates the scheduler and registers the periodic jobs
+func newScheduler(db *pgxpool.Pool, config config) (*scheduler.Scheduler, error) {
+	q := models.New()
+	s := scheduler.New(db, q)
+
+	// Purge job history older than the configured retention
+	if err := s.Register("purge-scheduler-runs", "0 3 * * *", func(ctx context.Context) error {
+		n, err := q.DeleteSchedulerRunsBefore(ctx, db, time.Now().Add(-config.Scheduler.RunRetention))
+		if err != nil {
+			return fmt.Errorf("q.DeleteSchedulerRunsBefore: %w", err)
 		}
+		slogr.FromContext(ctx).Info("purged scheduler runs", slog.Int64("rows", n))
 		return nil
-	})
+	}); err != nil {
+		return nil, fmt.Errorf("register purge-scheduler-runs: %w", err)
+	}
+
+	return s, nil
+}
+
+// httpServer is an HTTP server with the listeners it serves on, by address
+type httpServer struct {
+	*http.Server
+	listeners map[string][]net.Listener
+}
+
+// listenAll opens the listeners of every address, closing those already
+// opened when one fails
+func listenAll(ctx context.Context, addrs []string, opts ...listen.Option) (map[string][]net.Listener, error) {
+	listeners := make(map[string][]net.Listener, len(addrs))
+	for _, addr := range addrs {
+		ls, err := listen.Listen(ctx, addr, opts...)
+		if err != nil {
+			closeListeners(listeners)
+			return nil, fmt.Errorf("listen.Listen %s: %w", addr, err)
+		}
+		listeners[addr] = append(listeners[addr], ls...)
+	}
+	return listeners, nil
+}
+
+// closeListeners closes listeners that will not be served
+func closeListeners(listeners map[string][]net.Listener) {
+	for _, ls := range listeners {
+		for _, l := range ls {
+			l.Close()
+		}
+	}
+}
+
+// runHTTPServer serves HTTP on the listeners of each server and handles their
+// graceful shutdown. It uses errgroup to manage concurrent operations and ensure
+// proper cleanup: the servers stop together when ctx is canceled or when any
+// of their listeners fails.
+func runHTTPServer(ctx context.Context, shutdownTimeout time.Duration, servers ...httpServer) error {
+	logger := slogr.FromContext(ctx)
+
+	// Create errgroup for managing server goroutines
+	g, gctx := errgroup.WithContext(ctx)
+
+	// Serve each listener in a goroutine
+	for _, server := range servers {
+		for _, l := range slices.Concat(slices.Collect(maps.Values(server.listeners))...) {
+			g.Go(func() error {
+				logger.Info("starting HTTP server",
+					slog.String("network", l.Addr().Network()),
+					slog.String("addr", l.Addr().String()),
+					slog.Bool("tls", server.TLSConfig != nil))
+
+				// Serve returns ErrServerClosed on graceful shutdown.
+				// Certificates come from TLSConfig rather than from files.
+				var err error
+				if server.TLSConfig != nil {
+					err = server.ServeTLS(l, "", "")
+				} else {
+					err = server.Serve(l)
+				}
+				if !errors.Is(err, http.ErrServerClosed) {
+					return fmt.Errorf("server.Serve %s: %w", l.Addr(), err)
+				}
+				return nil
+			})
+		}
+	}
+
+	// Tell the process that handed its listeners over, if any, to shut down
+	if err := listen.Ready(); err != nil {
+		logger.Error("[restart] notifying readiness failed", slog.Any("err", err))
+	}
 
 	// Setup signal handling in another goroutine
 	g.Go(func() error {
@@ -115,18 +479,27 @@ func runHTTPServer(ctx context.Context, server *http.Server) error {
 
 		logger.Info("[server] shutting down...")
 
-		defer server.Close()
-
 		// Initiate graceful shutdown with timeout
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 
-		// Shutdown the server gracefully
-		//nolint:contextcheck // We need a fresh context here since the parent context is cancelled
-		if err := server.Shutdown(shutdownCtx); err != nil {
-			return fmt.Errorf("server.Shutdown: %w", err)
+		// Shutdown the servers gracefully, in parallel. Shutdown closes
+		// their listeners, removing the files of Unix sockets.
+		errs := make([]error, len(servers))
+		var wg sync.WaitGroup
+		for i, server := range servers {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer server.Close()
+				//nolint:contextcheck // We need a fresh context here since the parent context is cancelled
+				if err := server.Shutdown(shutdownCtx); err != nil {
+					errs[i] = fmt.Errorf("server.Shutdown: %w", err)
+				}
+			}()
 		}
-		return nil
+		wg.Wait()
+		return errors.Join(errs...)
 	})
 
 	// Wait for all goroutines to complete
@@ -136,59 +509,3 @@ func runHTTPServer(ctx context.Context, server *http.Server) error {
 
 	return nil
 }
-
-// config holds all the configuration options for the server.
-// These are typically set through environment variables.
-type config struct {
-	// logLevel determines the minimum severity level for log output
-	logLevel slog.Level
-
-	// Database configuration
-	databaseURL             string        // PostgreSQL connection URL
-	databaseIdleConnTimeout time.Duration // Maximum time a connection can be idle
-	databaseConns           int32         // Maximum number of database connections
-
-	// HTTP server configuration
-	serverAddr         string        // Address to listen on (e.g., ":8080")
-	serverReadTimeout  time.Duration // Maximum duration for reading entire request
-	serverWriteTimeout time.Duration // Maximum duration for writing response
-	serverIdleTimeout  time.Duration // Maximum duration for idle keep-alive connections
-}
-
-// newConfig loads and validates configuration from environment variables.
-// It returns an error if any required variables are missing or invalid.
-func newConfig() (config, error) {
-	var logLevel slog.Level
-	if err := envvar.UnmarshalText("LOG_LEVEL", &logLevel); err != nil {
-		return config{}, fmt.Errorf("fail to parse LOG_LEVEL: %w", err)
-	}
-
-	dbConns, err := envvar.ParseInt32("DATABASE_CONNS")
-	if err != nil {
-		return config{}, fmt.Errorf("fail to parse DATABASE_MAX_CONNS: %w", err)
-	}
-
-	readTimeout, err := envvar.ParseDuration("SERVER_READ_TIMEOUT")
-	if err != nil {
-		return config{}, fmt.Errorf("fail to parse SERVER_READ_TIMEOUT: %w", err)
-	}
-	writeTimeout, err := envvar.ParseDuration("SERVER_WRITE_TIMEOUT")
-	if err != nil {
-		return config{}, fmt.Errorf("fail to parse SERVER_WRITE_TIMEOUT: %w", err)
-	}
-	idleTimeout, err := envvar.ParseDuration("SERVER_IDLE_TIMEOUT")
-	if err != nil {
-		return config{}, fmt.Errorf("fail to parse SERVER_IDLE_TIMEOUT: %w", err)
-	}
-
-	return config{
-		logLevel:                logLevel,
-		databaseURL:             os.Getenv("DATABASE_URL"),
-		databaseIdleConnTimeout: 30 * time.Minute,
-		databaseConns:           dbConns,
-		serverAddr:              os.Getenv("SERVER_ADDR"),
-		serverReadTimeout:       readTimeout,
-		serverWriteTimeout:      writeTimeout,
-		serverIdleTimeout:       idleTimeout,
-	}, nil
-}
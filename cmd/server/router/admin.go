@@ -0,0 +1,77 @@
+package router
+
+import (
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+)
+
+// isAdmin reports whether the request carries the admin bearer token.
+// No request is authorized when the token is not configured.
+func isAdmin(r *http.Request, token envvar.Secret) bool {
+	if token == "" {
+		return false
+	}
+	got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(got), []byte(token.Value())) == 1
+}
+
+// requireAdmin rejects the requests that do not carry the admin bearer token
+func requireAdmin(token envvar.Secret) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isAdmin(r, token) {
+				jsonresp.Error(nil, "Unauthorized", http.StatusUnauthorized).
+					WithHeader("WWW-Authenticate", "Bearer").
+					Respond(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// logLevelHandler reads and changes the global log level at runtime
+type logLevelHandler struct {
+	level *slog.LevelVar
+}
+
+// NewLogLevelHandler creates a new log level handler changing level
+func NewLogLevelHandler(level *slog.LevelVar) *logLevelHandler {
+	return &logLevelHandler{
+		level: level,
+	}
+}
+
+// LogLevel is the global log level, e.g. "DEBUG", "INFO", "WARN" or "ERROR"
+type LogLevel struct {
+	Level string `json:"level"`
+}
+
+// Get returns the current global log level
+func (h *logLevelHandler) Get(_ *http.Request) httphandler.Responder {
+	return jsonresp.Success(&LogLevel{Level: h.level.Level().String()})
+}
+
+// Update changes the global log level until the next restart or configuration reload
+func (h *logLevelHandler) Update(r *http.Request, input LogLevel) httphandler.Responder {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(input.Level)); err != nil {
+		return jsonresp.Error(err, "Invalid level", http.StatusBadRequest)
+	}
+
+	old := h.level.Level()
+	h.level.Set(level)
+	slogr.FromContext(r.Context()).Warn("[admin] log level changed",
+		slog.String("old", old.String()),
+		slog.String("new", level.String()))
+
+	return jsonresp.Success(&LogLevel{Level: level.String()})
+}
@@ -0,0 +1,90 @@
+package router_test
+
+import (
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-starter/cmd/server/router"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_LogLevelHandler(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc       string
+		method     string
+		input      router.LogLevel
+		wantStatus int
+		wantBody   string
+		wantLevel  slog.Level
+	}{
+		{
+			desc:       "get",
+			method:     http.MethodGet,
+			input:      router.LogLevel{Level: ""},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"level":"INFO"}`,
+			wantLevel:  slog.LevelInfo,
+		},
+		{
+			desc:       "update",
+			method:     http.MethodPut,
+			input:      router.LogLevel{Level: "debug"},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"level":"DEBUG"}`,
+			wantLevel:  slog.LevelDebug,
+		},
+		{
+			desc:       "update | offset",
+			method:     http.MethodPut,
+			input:      router.LogLevel{Level: "WARN+2"},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"level":"WARN+2"}`,
+			wantLevel:  slog.LevelWarn + 2,
+		},
+		{
+			desc:       "update | invalid level",
+			method:     http.MethodPut,
+			input:      router.LogLevel{Level: "verbose"},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"Invalid level"}`,
+			wantLevel:  slog.LevelInfo,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			level := new(slog.LevelVar)
+			h := router.NewLogLevelHandler(level)
+			r := httptest.NewRequest(tc.method, "/api/v1/admin/log/level", strings.NewReader(""))
+			w := httptest.NewRecorder()
+
+			// When:
+			if tc.method == http.MethodGet {
+				h.Get(r).Respond(w, r)
+			} else {
+				h.Update(r, tc.input).Respond(w, r)
+			}
+
+			got := w.Result()
+			defer got.Body.Close()
+			gotBodyBytes, err := io.ReadAll(got.Body)
+			require.NoError(t, err)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, got.StatusCode)
+			assert.JSONEq(t, tc.wantBody, string(gotBodyBytes))
+			assert.Equal(t, tc.wantLevel, level.Level())
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package router
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// encoder is a pooled compressor, reset for each response
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encodings are the supported content codings, by server preference when the
+// client has no preference between them
+var encodings = []string{"zstd", "gzip", "deflate"}
+
+// encoderPools hold the encoders of each content coding, as allocating their
+// state for every response is expensive
+var encoderPools = map[string]*sync.Pool{
+	"zstd": {New: func() any {
+		// Single threaded, concurrency comes from the requests
+		e, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return e
+	}},
+	"gzip": {New: func() any {
+		return gzip.NewWriter(nil)
+	}},
+	"deflate": {New: func() any {
+		e, _ := flate.NewWriter(nil, flate.DefaultCompression)
+		return e
+	}},
+}
+
+// negotiateEncoding returns the supported coding preferred by an Accept-Encoding
+// header, e.g. "gzip;q=0.8, zstd", or "" when the response is sent as is
+func negotiateEncoding(header string) string {
+	quality := map[string]float64{}
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		quality[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range encodings {
+		q, ok := quality[coding]
+		if !ok {
+			q, ok = quality["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// Compress returns a middleware compressing the responses with the coding
+// preferred by the client when their Content-Type matches one of types, e.g.
+// "application/json" or "text/*", and their body is at least minSize bytes.
+// Server-Sent Events and responses already encoded are sent as is.
+func Compress(minSize int, types []string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			coding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if coding == "" || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				coding:         coding,
+				minSize:        minSize,
+				types:          types,
+				statusCode:     http.StatusOK,
+				wroteHeader:    false,
+				decided:        false,
+				buf:            nil,
+				enc:            nil,
+			}
+			// Not deferred: after a panic, Recoverer responds instead of the buffered body
+			next.ServeHTTP(cw, r)
+			cw.close()
+		})
+	}
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it: once minSize bytes are written, or on Flush or at the end of
+// the handler, whichever comes first
+type compressWriter struct {
+	http.ResponseWriter
+	coding      string
+	minSize     int
+	types       []string
+	statusCode  int
+	wroteHeader bool // WriteHeader was called by the handler
+	decided     bool // The header was sent, compressed or not
+	buf         []byte
+	enc         encoder // Nil when the response is sent as is
+}
+
+// WriteHeader delays the header until the encoding is decided
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader || cw.decided {
+		return
+	}
+	// Informational responses, e.g. 103 Early Hints, go through
+	if code >= 100 && code <= 199 && code != http.StatusSwitchingProtocols {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.statusCode = code
+	cw.wroteHeader = true
+}
+
+// Write buffers the body until minSize bytes, then writes it compressed
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+		if len(cw.buf) < cw.minSize {
+			return len(p), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(p) //nolint:wrapcheck // passthrough for the handler to inspect
+	}
+	return cw.ResponseWriter.Write(p) //nolint:wrapcheck // passthrough for the handler to inspect
+}
+
+// decide sends the header, with Content-Encoding when the response is to be
+// compressed, and the buffered body
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+
+	h := cw.ResponseWriter.Header()
+	if h.Get("Content-Type") == "" && len(cw.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if cw.compressible(len(cw.buf)) {
+		h.Set("Content-Encoding", cw.coding)
+		h.Del("Content-Length")
+		cw.enc = encoderPools[cw.coding].Get().(encoder) //nolint:forcetypeassert // pools only hold encoders
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.statusCode)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if cw.enc != nil {
+		_, err := cw.enc.Write(buf)
+		return err //nolint:wrapcheck // passthrough for the handler to inspect
+	}
+	_, err := cw.ResponseWriter.Write(buf)
+	return err //nolint:wrapcheck // passthrough for the handler to inspect
+}
+
+// compressible reports whether a response with a body of size bytes is compressed
+func (cw *compressWriter) compressible(size int) bool {
+	h := cw.ResponseWriter.Header()
+	if size < cw.minSize || h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "" {
+		return false
+	}
+	if cw.statusCode == http.StatusNoContent || cw.statusCode == http.StatusNotModified {
+		return false
+	}
+
+	mediaType, _, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || mediaType == "text/event-stream" {
+		return false
+	}
+	return slices.ContainsFunc(cw.types, func(pattern string) bool {
+		ok, _ := path.Match(pattern, mediaType)
+		return ok
+	})
+}
+
+// Flush sends what was written so far. A response flushed before reaching
+// minSize, such as a stream, is not compressed.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Flush()
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Hijack lets WebSocket handlers take over the connection, nothing is compressed then
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	cw.decided = true
+	return http.NewResponseController(cw.ResponseWriter).Hijack() //nolint:wrapcheck // passthrough for the caller to inspect
+}
+
+// Unwrap returns the underlying ResponseWriter so that http.ResponseController
+// can reach optional interfaces such as SetWriteDeadline
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// close sends a response shorter than minSize as is, or ends the compressed
+// stream and returns the encoder to its pool
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		// Small bodies are sent uncompressed, the server sets their length
+		if !cw.wroteHeader && len(cw.buf) == 0 {
+			return
+		}
+		_ = cw.decide()
+		return
+	}
+	if cw.enc != nil {
+		_ = cw.enc.Close()
+		cw.enc.Reset(nil)
+		encoderPools[cw.coding].Put(cw.enc)
+		cw.enc = nil
+	}
+}
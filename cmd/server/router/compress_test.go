@@ -0,0 +1,210 @@
+package router_test
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-starter/cmd/server/router"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// decode returns the body of a response sent with the given Content-Encoding
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+
+	var r io.Reader
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(body)
+		require.NoError(t, err)
+		r = gr
+	case "deflate":
+		r = flate.NewReader(body)
+	case "zstd":
+		zr, err := zstd.NewReader(body)
+		require.NoError(t, err)
+		defer zr.Close()
+		r = zr
+	default:
+		r = body
+	}
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func Test_Compress(t *testing.T) {
+	t.Parallel()
+
+	large := `[` + strings.Repeat(`{"title":"Post title"},`, 100) + `{}]`
+
+	testCases := []struct {
+		desc           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{desc: "gzip", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "gzip"},
+		{desc: "deflate", acceptEncoding: "deflate", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "deflate"},
+		{desc: "zstd", acceptEncoding: "zstd", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "zstd"},
+		{desc: "server preference on ties", acceptEncoding: "gzip, deflate, br, zstd", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "zstd"},
+		{desc: "highest q-value", acceptEncoding: "zstd;q=0.5, gzip;q=0.9, deflate", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "deflate"},
+		{desc: "wildcard", acceptEncoding: "*;q=0.1, zstd;q=0", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: "gzip"},
+		{desc: "refused", acceptEncoding: "gzip;q=0", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: ""},
+		{desc: "unsupported", acceptEncoding: "br", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: ""},
+		{desc: "no Accept-Encoding", acceptEncoding: "", contentType: "application/json", status: http.StatusOK, body: large, wantEncoding: ""},
+		{desc: "below threshold", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusOK, body: `{"title":"Post title"}`, wantEncoding: ""},
+		{desc: "content type not allowed", acceptEncoding: "gzip", contentType: "image/png", status: http.StatusOK, body: large, wantEncoding: ""},
+		{desc: "wildcard content type", acceptEncoding: "gzip", contentType: "text/csv; charset=utf-8", status: http.StatusOK, body: large, wantEncoding: "gzip"},
+		{desc: "error status", acceptEncoding: "gzip", contentType: "application/json", status: http.StatusInternalServerError, body: large, wantEncoding: "gzip"},
+		{desc: "no content", acceptEncoding: "gzip", contentType: "", status: http.StatusNoContent, body: "", wantEncoding: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			h := router.Compress(1024, []string{"application/json", "text/*"})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tc.contentType != "" {
+					w.Header().Set("Content-Type", tc.contentType)
+				}
+				w.WriteHeader(tc.status)
+				// Written in small chunks, as encoders do
+				for chunk := range slices.Chunk([]byte(tc.body), 64) {
+					_, _ = w.Write(chunk)
+				}
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/posts", nil)
+			if tc.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+
+			// When:
+			h.ServeHTTP(w, r)
+
+			// Then:
+			got := w.Result()
+			defer got.Body.Close()
+			assert.Equal(t, tc.status, got.StatusCode)
+			assert.Equal(t, tc.wantEncoding, got.Header.Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", got.Header.Get("Vary"))
+			assert.Equal(t, tc.body, decode(t, tc.wantEncoding, got.Body))
+		})
+	}
+}
+
+func Test_Compress_EventStream(t *testing.T) {
+	t.Parallel()
+
+	// Given: a stream flushing each event
+	srv := httptest.NewServer(router.Compress(0, []string{"*/*"})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		rc := http.NewResponseController(w)
+		for _, event := range []string{"data: one\n\n", "data: two\n\n"} {
+			_, _ = io.WriteString(w, event)
+			require.NoError(t, rc.Flush())
+		}
+	})))
+	t.Cleanup(srv.Close)
+
+	r, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	// When:
+	got, err := http.DefaultClient.Do(r)
+
+	// Then:
+	require.NoError(t, err)
+	defer got.Body.Close()
+	assert.Empty(t, got.Header.Get("Content-Encoding"))
+	gotBody, err := io.ReadAll(got.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "data: one\n\ndata: two\n\n", string(gotBody))
+}
+
+func Test_Compress_Flush(t *testing.T) {
+	t.Parallel()
+
+	// Given: a large response flushed midway
+	first := strings.Repeat("a", 2048)
+	flushed := make(chan struct{})
+	srv := httptest.NewServer(router.Compress(1024, []string{"text/plain"})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, first)
+		require.NoError(t, http.NewResponseController(w).Flush())
+		<-flushed
+		_, _ = io.WriteString(w, "b")
+	})))
+	t.Cleanup(srv.Close)
+
+	r, err := http.NewRequestWithContext(t.Context(), http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+	r.Header.Set("Accept-Encoding", "gzip")
+
+	// When:
+	got, err := http.DefaultTransport.RoundTrip(r)
+
+	// Then: the flushed part is readable before the handler returns
+	require.NoError(t, err)
+	defer got.Body.Close()
+	assert.Equal(t, "gzip", got.Header.Get("Content-Encoding"))
+	gr, err := gzip.NewReader(got.Body)
+	require.NoError(t, err)
+	buf := make([]byte, len(first))
+	_, err = io.ReadFull(gr, buf)
+	require.NoError(t, err)
+	assert.Equal(t, first, string(buf))
+
+	close(flushed)
+	rest, err := io.ReadAll(gr)
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(rest))
+}
+
+func Test_Compress_Hijack(t *testing.T) {
+	t.Parallel()
+
+	// Given: a handler taking over the connection
+	srv := httptest.NewServer(router.Compress(0, []string{"*/*"})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 200 OK\r\nContent-Length: 6\r\nConnection: close\r\n\r\nraw ok")
+		_ = brw.Flush()
+	})))
+	t.Cleanup(srv.Close)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// When:
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nAccept-Encoding: gzip\r\n\r\n")
+	require.NoError(t, err)
+	got, err := http.ReadResponse(bufio.NewReader(conn), nil)
+
+	// Then:
+	require.NoError(t, err)
+	defer got.Body.Close()
+	assert.Empty(t, got.Header.Get("Content-Encoding"))
+	gotBody, err := io.ReadAll(got.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "raw ok", string(gotBody))
+}
@@ -0,0 +1,120 @@
+package router
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"runtime/pprof"
+	"time"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DebugHandler returns the handler of the admin listener, serving profiling and
+// runtime diagnostics that must not be exposed on the public address:
+//   - /debug/pprof/ and /debug/vars: net/http/pprof and expvar
+//   - /debug/goroutines: stack traces of every goroutine
+//   - /debug/gc: garbage collector and memory statistics
+//   - /debug/pgxpool: database connection pool statistics
+func DebugHandler(db *pgxpool.Pool) http.Handler {
+	r := chi.NewRouter()
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.NoCache)
+
+	r.Mount("/debug", middleware.Profiler())
+	r.Get("/debug/goroutines", goroutinesHandler)
+	r.Get("/debug/gc", httphandler.Handle(gcStatsHandler))
+	r.Get("/debug/pgxpool", httphandler.Handle(poolStatsHandler(db)))
+
+	return r
+}
+
+// goroutinesHandler writes the stack traces of every goroutine, as in a panic
+func goroutinesHandler(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_ = pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+// GCStats are the garbage collector and memory statistics of the process
+type GCStats struct {
+	NumGC         int64           `json:"num_gc"`
+	LastGC        time.Time       `json:"last_gc"`
+	PauseTotal    time.Duration   `json:"pause_total_ns"`
+	RecentPauses  []time.Duration `json:"recent_pauses_ns"` // Most recent first
+	HeapAlloc     uint64          `json:"heap_alloc_bytes"`
+	HeapSys       uint64          `json:"heap_sys_bytes"`
+	HeapObjects   uint64          `json:"heap_objects"`
+	TotalAlloc    uint64          `json:"total_alloc_bytes"`
+	Sys           uint64          `json:"sys_bytes"`
+	NextGC        uint64          `json:"next_gc_bytes"`
+	GCCPUFraction float64         `json:"gc_cpu_fraction"`
+	Goroutines    int             `json:"goroutines"`
+	GOMAXPROCS    int             `json:"gomaxprocs"`
+}
+
+// gcStatsHandler returns the garbage collector and memory statistics
+func gcStatsHandler(_ *http.Request) httphandler.Responder {
+	var gc debug.GCStats
+	gc.Pause = make([]time.Duration, 0, 16)
+	debug.ReadGCStats(&gc)
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return jsonresp.Success(&GCStats{
+		NumGC:         gc.NumGC,
+		LastGC:        gc.LastGC,
+		PauseTotal:    gc.PauseTotal,
+		RecentPauses:  gc.Pause[:min(len(gc.Pause), 16)],
+		HeapAlloc:     mem.HeapAlloc,
+		HeapSys:       mem.HeapSys,
+		HeapObjects:   mem.HeapObjects,
+		TotalAlloc:    mem.TotalAlloc,
+		Sys:           mem.Sys,
+		NextGC:        mem.NextGC,
+		GCCPUFraction: mem.GCCPUFraction,
+		Goroutines:    runtime.NumGoroutine(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+	})
+}
+
+// PoolStats are the statistics of the database connection pool
+type PoolStats struct {
+	TotalConns              int32         `json:"total_conns"`
+	AcquiredConns           int32         `json:"acquired_conns"`
+	IdleConns               int32         `json:"idle_conns"`
+	ConstructingConns       int32         `json:"constructing_conns"`
+	MaxConns                int32         `json:"max_conns"`
+	AcquireCount            int64         `json:"acquire_count"`
+	AcquireDuration         time.Duration `json:"acquire_duration_ns"`
+	EmptyAcquireCount       int64         `json:"empty_acquire_count"`
+	CanceledAcquireCount    int64         `json:"canceled_acquire_count"`
+	NewConnsCount           int64         `json:"new_conns_count"`
+	MaxLifetimeDestroyCount int64         `json:"max_lifetime_destroy_count"`
+	MaxIdleDestroyCount     int64         `json:"max_idle_destroy_count"`
+}
+
+// poolStatsHandler returns the statistics of the database connection pool
+func poolStatsHandler(db *pgxpool.Pool) func(*http.Request) httphandler.Responder {
+	return func(_ *http.Request) httphandler.Responder {
+		s := db.Stat()
+		return jsonresp.Success(&PoolStats{
+			TotalConns:              s.TotalConns(),
+			AcquiredConns:           s.AcquiredConns(),
+			IdleConns:               s.IdleConns(),
+			ConstructingConns:       s.ConstructingConns(),
+			MaxConns:                s.MaxConns(),
+			AcquireCount:            s.AcquireCount(),
+			AcquireDuration:         s.AcquireDuration(),
+			EmptyAcquireCount:       s.EmptyAcquireCount(),
+			CanceledAcquireCount:    s.CanceledAcquireCount(),
+			NewConnsCount:           s.NewConnsCount(),
+			MaxLifetimeDestroyCount: s.MaxLifetimeDestroyCount(),
+			MaxIdleDestroyCount:     s.MaxIdleDestroyCount(),
+		})
+	}
+}
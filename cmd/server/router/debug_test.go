@@ -0,0 +1,62 @@
+package router_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-starter/cmd/server/router"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DebugHandler(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc         string
+		path         string
+		wantStatus   int
+		wantContains string
+	}{
+		{desc: "pprof index", path: "/debug/pprof/", wantStatus: http.StatusOK, wantContains: "goroutine"},
+		{desc: "expvar", path: "/debug/vars", wantStatus: http.StatusOK, wantContains: `"memstats"`},
+		{desc: "goroutines", path: "/debug/goroutines", wantStatus: http.StatusOK, wantContains: "goroutine "},
+		{desc: "gc stats", path: "/debug/gc", wantStatus: http.StatusOK, wantContains: `"num_gc"`},
+		{desc: "pool stats", path: "/debug/pgxpool", wantStatus: http.StatusOK, wantContains: `"max_conns":3`},
+		{desc: "not found", path: "/api/v1/posts", wantStatus: http.StatusNotFound, wantContains: ""},
+	}
+
+	// The pool connects lazily, no database is needed
+	poolConfig, err := pgxpool.ParseConfig("postgres://localhost:5432/appdb?pool_max_conns=3")
+	require.NoError(t, err)
+	pool, err := pgxpool.NewWithConfig(t.Context(), poolConfig)
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			h := router.DebugHandler(pool)
+			r := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			w := httptest.NewRecorder()
+
+			// When:
+			h.ServeHTTP(w, r)
+
+			got := w.Result()
+			defer got.Body.Close()
+			gotBodyBytes, err := io.ReadAll(got.Body)
+			require.NoError(t, err)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, got.StatusCode)
+			assert.Contains(t, string(gotBodyBytes), tc.wantContains)
+		})
+	}
+}
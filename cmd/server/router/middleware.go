@@ -1,10 +1,16 @@
 package router
 
 import (
+	"bufio"
+	"encoding/hex"
 	"log/slog"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"go-starter/internal/pkg/envvar"
 	"go-starter/internal/pkg/slogr"
 
 	"github.com/go-chi/chi/v5/middleware"
@@ -17,7 +23,7 @@ func corsMiddleware(allowedOrigins []string) func(next http.Handler) http.Handle
 		AllowedOrigins:     allowedOrigins,
 		AllowOriginFunc:    nil,
 		AllowedMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "X-Debug-Log"},
 		ExposedHeaders:     []string{"Link"},
 		AllowCredentials:   false,
 		MaxAge:             300,
@@ -35,36 +41,74 @@ func corsMiddleware(allowedOrigins []string) func(next http.Handler) http.Handle
 //   - Response status code
 //   - Request duration
 //
+// Authorized callers may send "X-Debug-Log: true" to log at DEBUG for just that request.
+//
 // It uses structured logging via slog to ensure consistent log format
 // and adds the logger to the request context for use by handlers.
-func requestLogger(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		ctx := r.Context()
+func requestLogger(adminToken envvar.Secret) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			ctx := r.Context()
 
-		logger := slogr.FromContext(ctx).With(
-			slog.String("path", r.URL.Path),
-			slog.String("method", r.Method),
-		)
+			logger := slogr.FromContext(ctx).With(
+				slog.String("path", r.URL.Path),
+				slog.String("method", r.Method),
+			)
 
-		reqID := middleware.GetReqID(ctx)
-		if reqID != "" {
-			logger = logger.With(slog.String("request-id", reqID))
-		}
+			// Request IDs also go with the context, for records logged with slog.InfoContext(ctx, ...)
+			var ids []slog.Attr
+			if reqID := middleware.GetReqID(ctx); reqID != "" {
+				ids = append(ids, slog.String("request-id", reqID))
+			}
+			if traceID := traceID(r); traceID != "" {
+				ids = append(ids, slog.String("trace-id", traceID))
+			}
+			if len(ids) > 0 {
+				logger = logger.With(attrsToArgs(ids)...)
+				ctx = slogr.WithAttrs(ctx, ids...)
+			}
 
-		logger.Info("START",
-			slog.String("user-agent", r.UserAgent()),
-		)
+			if debug, _ := strconv.ParseBool(r.Header.Get("X-Debug-Log")); debug && isAdmin(r, adminToken) {
+				logger = slogr.WithLevel(logger, slog.LevelDebug)
+			}
 
-		rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			logger.Info("START",
+				slog.String("user-agent", r.UserAgent()),
+			)
 
-		next.ServeHTTP(rw, r.WithContext(slogr.ToContext(ctx, logger)))
+			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 
-		logger.Info("END",
-			slog.Duration("duration", time.Since(start)),
-			slog.Int("status", rw.statusCode),
-		)
-	})
+			next.ServeHTTP(rw, r.WithContext(slogr.ToContext(ctx, logger)))
+
+			logger.Info("END",
+				slog.Duration("duration", time.Since(start)),
+				slog.Int("status", rw.statusCode),
+			)
+		})
+	}
+}
+
+// traceID returns the trace ID of a W3C traceparent header, e.g.
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+func traceID(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Traceparent"), "-")
+	if len(parts) != 4 || len(parts[1]) != 32 {
+		return ""
+	}
+	if _, err := hex.DecodeString(parts[1]); err != nil || parts[1] == strings.Repeat("0", 32) {
+		return ""
+	}
+	return parts[1]
+}
+
+// attrsToArgs converts attributes to the arguments of slog.Logger.With
+func attrsToArgs(attrs []slog.Attr) []any {
+	args := make([]any, len(attrs))
+	for i, a := range attrs {
+		args[i] = a
+	}
+	return args
 }
 
 // responseWriter is a custom http.ResponseWriter that captures the HTTP status code.
@@ -79,3 +123,20 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
+
+// Unwrap returns the underlying ResponseWriter so that http.ResponseController
+// can reach optional interfaces such as Flush and SetWriteDeadline.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Hijack lets WebSocket handlers take over the connection through this wrapper.
+// The status is recorded as 101 since the handshake is written on the raw connection.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(rw.ResponseWriter).Hijack()
+	if err != nil {
+		return nil, nil, err //nolint:wrapcheck // passthrough for the caller to inspect
+	}
+	rw.statusCode = http.StatusSwitchingProtocols
+	return conn, brw, nil
+}
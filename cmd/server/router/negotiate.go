@@ -0,0 +1,477 @@
+package router
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"github.com/jackc/pgx/v5"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Format is a representation of response bodies, chosen by content negotiation
+type Format string
+
+// Supported formats
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatNDJSON  Format = "ndjson"
+	FormatXML     Format = "xml"
+	FormatMsgPack Format = "msgpack"
+)
+
+// formats are the supported formats with their media types, the first one
+// being sent as Content-Type. JSON comes first as the default and preferred
+// format when the client accepts several equally.
+var formats = []struct {
+	format     Format
+	mediaTypes []string
+}{
+	{format: FormatJSON, mediaTypes: []string{"application/json"}},
+	{format: FormatCSV, mediaTypes: []string{"text/csv"}},
+	{format: FormatNDJSON, mediaTypes: []string{"application/x-ndjson", "application/jsonl"}},
+	{format: FormatXML, mediaTypes: []string{"application/xml", "text/xml"}},
+	{format: FormatMsgPack, mediaTypes: []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}},
+}
+
+// Errors returned by negotiateFormat
+var (
+	errUnknownFormat = errors.New("unknown format")
+	errNotAcceptable = errors.New("no acceptable format")
+)
+
+// ContentType returns the Content-Type of responses in the format
+func (f Format) ContentType() string {
+	for _, ff := range formats {
+		if ff.format == f {
+			if strings.HasPrefix(ff.mediaTypes[0], "text/") {
+				return ff.mediaTypes[0] + "; charset=utf-8"
+			}
+			return ff.mediaTypes[0]
+		}
+	}
+	return "application/octet-stream"
+}
+
+// streamed reports whether lists in the format are written item by item,
+// rather than encoded from a slice
+func (f Format) streamed() bool {
+	return f == FormatCSV || f == FormatNDJSON
+}
+
+// negotiateFormat returns the format of the ?format= query parameter, else
+// the one preferred by the Accept header, with q-values, else JSON
+func negotiateFormat(r *http.Request) (Format, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		for _, ff := range formats {
+			if string(ff.format) == strings.ToLower(name) {
+				return ff.format, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %q", errUnknownFormat, name)
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return FormatJSON, nil
+	}
+
+	type mediaRange struct {
+		mediaType string
+		q         float64
+	}
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(part)
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, q: q})
+	}
+
+	// The q-value of a media type is the one of its most specific range
+	quality := func(mediaType string) float64 {
+		q, specificity := 0.0, -1
+		for _, mr := range ranges {
+			s := -1
+			switch {
+			case mr.mediaType == mediaType:
+				s = 2
+			case mr.mediaType == strings.Split(mediaType, "/")[0]+"/*":
+				s = 1
+			case mr.mediaType == "*/*":
+				s = 0
+			}
+			if s > specificity {
+				q, specificity = mr.q, s
+			}
+		}
+		return q
+	}
+
+	best, bestQ := Format(""), 0.0
+	for _, ff := range formats {
+		for _, mediaType := range ff.mediaTypes {
+			if q := quality(mediaType); q > bestQ {
+				best, bestQ = ff.format, q
+			}
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("%w: %q", errNotAcceptable, accept)
+	}
+	return best, nil
+}
+
+// formatError responds to a request for an unsupported format
+func formatError(err error) httphandler.Responder {
+	if errors.Is(err, errNotAcceptable) {
+		return jsonresp.Error(err, "Not Acceptable", http.StatusNotAcceptable)
+	}
+	return jsonresp.Error(err, "Invalid format, expected json, csv, ndjson, xml or msgpack", http.StatusBadRequest)
+}
+
+// Negotiated returns a responder writing v, a struct or a slice of structs,
+// in the format requested by r with ?format= or Accept. Handlers return it
+// instead of jsonresp.Success to serve every format. Fields are named after
+// their json tags in all of them.
+func Negotiated[T any](r *http.Request, v *T) httphandler.Responder {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		return varyAccept(formatError(err))
+	}
+	return varyAccept(respondAs(format, v))
+}
+
+// varyAccept adds Vary: Accept to the response of res, for caches to tell
+// apart the formats of the same URL
+func varyAccept(res httphandler.Responder) httphandler.Responder {
+	return responderFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		res.Respond(w, r)
+	})
+}
+
+// respondAs returns a responder writing v, a struct or a slice of structs, in format
+func respondAs[T any](format Format, v *T) httphandler.Responder {
+	if format == FormatJSON {
+		return jsonresp.Success(v)
+	}
+	return &formatResponder{format: format, value: reflect.ValueOf(v).Elem()}
+}
+
+// formatResponder writes a value in a format other than JSON
+type formatResponder struct {
+	format Format
+	value  reflect.Value
+}
+
+// Respond writes the value, logging encoding errors as the status is already sent
+func (res *formatResponder) Respond(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", res.format.ContentType())
+	w.WriteHeader(http.StatusOK)
+
+	bw := bufio.NewWriter(w)
+	var err error
+	switch res.format {
+	case FormatMsgPack:
+		enc := msgpack.NewEncoder(bw)
+		enc.SetCustomStructTag("json")
+		enc.UseCompactInts(true)
+		err = enc.EncodeValue(res.value)
+	default:
+		elem, items := res.value.Type(), func(yield func(reflect.Value, error) bool) { yield(res.value, nil) }
+		if res.value.Kind() == reflect.Slice {
+			elem = res.value.Type().Elem()
+			items = func(yield func(reflect.Value, error) bool) {
+				for i := range res.value.Len() {
+					if !yield(res.value.Index(i), nil) {
+						return
+					}
+				}
+			}
+		}
+		err = writeItems(bw, res.format, elem, res.value.Kind() == reflect.Slice, items)
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		slogr.FromContext(r.Context()).Error("[negotiate] writing response failed", slog.String("format", string(res.format)), slog.Any("err", err))
+	}
+}
+
+// Stream returns a responder writing the items of rows, scanned with scan, in
+// format, for lists too large to be held in memory. CSV and
+// NDJSON are written row by row as they are read, other formats are encoded
+// once all the rows are read.
+func Stream[T any](format Format, rows pgx.Rows, scan pgx.RowToFunc[T]) httphandler.Responder {
+	return &streamResponder[T]{format: format, items: rowsSeq(rows, scan)}
+}
+
+// responderFunc adapts a function to httphandler.Responder
+type responderFunc func(w http.ResponseWriter, r *http.Request)
+
+// Respond implements httphandler.Responder
+func (f responderFunc) Respond(w http.ResponseWriter, r *http.Request) {
+	f(w, r)
+}
+
+// rowsSeq iterates over rows scanned with scan, closing them at the end
+func rowsSeq[T any](rows pgx.Rows, scan pgx.RowToFunc[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+		for rows.Next() {
+			v, err := scan(rows)
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
+
+// streamResponder writes items as they are read
+type streamResponder[T any] struct {
+	format Format
+	items  iter.Seq2[T, error]
+}
+
+// Respond writes the items. Errors before the first item are sent as a 500
+// response, later ones abort the response so that the client sees it is truncated.
+func (res *streamResponder[T]) Respond(w http.ResponseWriter, r *http.Request) {
+	if !res.format.streamed() {
+		items := []T{}
+		for v, err := range res.items {
+			if err != nil {
+				jsonresp.InternalServerError(err).Respond(w, r)
+				return
+			}
+			items = append(items, v)
+		}
+		respondAs(res.format, &items).Respond(w, r)
+		return
+	}
+
+	// The header is sent with the first item, or at the end when there is none
+	started := false
+	bw := bufio.NewWriter(w)
+	items := func(yield func(reflect.Value, error) bool) {
+		for v, err := range res.items {
+			if err != nil && !started {
+				jsonresp.InternalServerError(err).Respond(w, r)
+				yield(reflect.Value{}, errResponded)
+				return
+			}
+			if !started {
+				started = true
+				w.Header().Set("Content-Type", res.format.ContentType())
+				w.WriteHeader(http.StatusOK)
+			}
+			if !yield(reflect.ValueOf(v), err) {
+				return
+			}
+		}
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", res.format.ContentType())
+			w.WriteHeader(http.StatusOK)
+		}
+	}
+
+	err := writeItems(bw, res.format, reflect.TypeFor[T](), true, items)
+	if errors.Is(err, errResponded) {
+		return
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		slogr.FromContext(r.Context()).Error("[negotiate] streaming response failed", slog.String("format", string(res.format)), slog.Any("err", err))
+		// Ends the response without its terminating chunk
+		panic(http.ErrAbortHandler)
+	}
+}
+
+// errResponded stops writing items once an error response was sent
+var errResponded = errors.New("error response sent")
+
+// writeItems writes values of type elem in a text format, as a list when
+// list is true
+func writeItems(w io.Writer, format Format, elem reflect.Type, list bool, items iter.Seq2[reflect.Value, error]) error {
+	switch format {
+	case FormatJSON, FormatNDJSON:
+		enc := json.NewEncoder(w)
+		for v, err := range items {
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(v.Interface()); err != nil {
+				return fmt.Errorf("json.Encode: %w", err)
+			}
+		}
+		return nil
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(fieldNames(elem)); err != nil {
+			return fmt.Errorf("csv.Write: %w", err)
+		}
+		for v, err := range items {
+			if err != nil {
+				return err
+			}
+			fields := fieldValues(v)
+			record := make([]string, len(fields))
+			for i, f := range fields {
+				record[i] = f.value
+			}
+			if err := cw.Write(record); err != nil {
+				return fmt.Errorf("csv.Write: %w", err)
+			}
+		}
+		cw.Flush()
+		return cw.Error() //nolint:wrapcheck // csv.Writer errors come from w
+	case FormatXML:
+		return writeXML(w, list, items)
+	default:
+		return fmt.Errorf("%w: %q", errUnknownFormat, format)
+	}
+}
+
+// writeXML writes items as <items><item><field>value</field></item></items>,
+// or a single <item> unless list. Null fields are left out.
+func writeXML(w io.Writer, list bool, items iter.Seq2[reflect.Value, error]) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err //nolint:wrapcheck // passthrough of the writer error
+	}
+	enc := xml.NewEncoder(w)
+	root := xml.StartElement{Name: xml.Name{Space: "", Local: "items"}, Attr: nil}
+	if list {
+		if err := enc.EncodeToken(root); err != nil {
+			return fmt.Errorf("xml.EncodeToken: %w", err)
+		}
+	}
+	for v, err := range items {
+		if err != nil {
+			return err
+		}
+		item := xml.StartElement{Name: xml.Name{Space: "", Local: "item"}, Attr: nil}
+		if err := enc.EncodeToken(item); err != nil {
+			return fmt.Errorf("xml.EncodeToken: %w", err)
+		}
+		for _, f := range fieldValues(v) {
+			if f.null {
+				continue
+			}
+			if err := enc.EncodeElement(f.value, xml.StartElement{Name: xml.Name{Space: "", Local: f.name}, Attr: nil}); err != nil {
+				return fmt.Errorf("xml.EncodeElement: %w", err)
+			}
+		}
+		if err := enc.EncodeToken(item.End()); err != nil {
+			return fmt.Errorf("xml.EncodeToken: %w", err)
+		}
+	}
+	if list {
+		if err := enc.EncodeToken(root.End()); err != nil {
+			return fmt.Errorf("xml.EncodeToken: %w", err)
+		}
+	}
+	return enc.Flush() //nolint:wrapcheck // passthrough of the writer error
+}
+
+// field is a struct field formatted as text
+type field struct {
+	name  string
+	value string
+	null  bool
+}
+
+// jsonName returns the name of a struct field in JSON, "" when left out
+func jsonName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return f.Name
+	default:
+		return name
+	}
+}
+
+// fieldNames returns the JSON names of the fields of a struct type
+func fieldNames(t reflect.Type) []string {
+	var names []string
+	for i := range t.NumField() {
+		if name := jsonName(t.Field(i)); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fieldValues formats the fields of a struct: times in RFC 3339, types such
+// as UUIDs with their MarshalText or String method and nil pointers as null
+func fieldValues(v reflect.Value) []field {
+	var fields []field
+	for i := range v.NumField() {
+		name := jsonName(v.Type().Field(i))
+		if name == "" {
+			continue
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Pointer {
+			if fv.IsNil() {
+				fields = append(fields, field{name: name, value: "", null: true})
+				continue
+			}
+			fv = fv.Elem()
+		}
+
+		var s string
+		switch x := fv.Interface().(type) {
+		case time.Time:
+			s = x.Format(time.RFC3339Nano)
+		case encoding.TextMarshaler:
+			b, _ := x.MarshalText()
+			s = string(b)
+		case fmt.Stringer:
+			s = x.String()
+		default:
+			s = fmt.Sprint(x)
+		}
+		fields = append(fields, field{name: name, value: s, null: false})
+	}
+	return fields
+}
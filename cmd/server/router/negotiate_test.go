@@ -0,0 +1,289 @@
+package router_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// fakeRows returns values row by row, then err
+type fakeRows struct {
+	pgx.Rows
+	values [][]any
+	err    error
+	closed bool
+}
+
+func (r *fakeRows) Next() bool {
+	if r.closed || len(r.values) == 0 {
+		return false
+	}
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, v := range r.values[0] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(v))
+	}
+	r.values = r.values[1:]
+	return nil
+}
+
+func (r *fakeRows) RawValues() [][]byte { return make([][]byte, len(r.values[0])) }
+func (r *fakeRows) Err() error          { return r.err }
+func (r *fakeRows) Close()              { r.closed = true }
+
+func Test_PostHandler_List_Formats(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	posts := []models.Post{
+		{ID: fixedUUID, Title: "Post, title", Description: ptr.Ref("Post <description>"), CreatedAt: fixedTime, UpdatedAt: fixedTime, Slug: "post-title"},
+		{ID: fixedUUID, Title: "Untitled", Description: nil, CreatedAt: fixedTime, UpdatedAt: fixedTime, Slug: "untitled"},
+	}
+	rows := func() [][]any {
+		values := make([][]any, 0, len(posts))
+		for _, p := range posts {
+			values = append(values, []any{p.ID, p.Title, p.Description, p.CreatedAt, p.UpdatedAt, p.Slug})
+		}
+		return values
+	}
+
+	testCases := []struct {
+		desc            string
+		accept          string
+		query           string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			desc:            "default",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+			wantBody:        `[{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post, title","description":"Post <description>","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"post-title"},{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Untitled","description":null,"created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"untitled"}]`,
+		},
+		{
+			desc:            "csv",
+			accept:          "text/csv",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+			wantBody: "id,title,description,created_at,updated_at,slug\n" +
+				"550e8400-e29b-41d4-a716-446655440000,\"Post, title\",Post <description>,2025-01-18T00:13:02Z,2025-01-18T00:13:02Z,post-title\n" +
+				"550e8400-e29b-41d4-a716-446655440000,Untitled,,2025-01-18T00:13:02Z,2025-01-18T00:13:02Z,untitled\n",
+		},
+		{
+			desc:            "ndjson",
+			accept:          "application/x-ndjson",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+			wantBody: `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post, title","description":"Post \u003cdescription\u003e","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"post-title"}` + "\n" +
+				`{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Untitled","description":null,"created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"untitled"}` + "\n",
+		},
+		{
+			desc:            "xml",
+			accept:          "application/xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody: `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<items>` +
+				`<item><id>550e8400-e29b-41d4-a716-446655440000</id><title>Post, title</title><description>Post &lt;description&gt;</description><created_at>2025-01-18T00:13:02Z</created_at><updated_at>2025-01-18T00:13:02Z</updated_at><slug>post-title</slug></item>` +
+				`<item><id>550e8400-e29b-41d4-a716-446655440000</id><title>Untitled</title><created_at>2025-01-18T00:13:02Z</created_at><updated_at>2025-01-18T00:13:02Z</updated_at><slug>untitled</slug></item>` +
+				`</items>`,
+		},
+		{
+			desc:            "highest q-value",
+			accept:          "application/json;q=0.5, text/csv;q=0.9, application/xml;q=0.1",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			desc:            "most specific range",
+			accept:          "text/*;q=0.1, text/csv, */*;q=0.5",
+			wantStatus:      http.StatusOK,
+			wantContentType: "text/csv; charset=utf-8",
+		},
+		{
+			desc:            "server preference on ties",
+			accept:          "application/xml, */*",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/json",
+		},
+		{
+			desc:            "wildcard",
+			accept:          "application/*;q=0.2, application/json;q=0",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/x-ndjson",
+		},
+		{
+			desc:            "query overrides accept",
+			accept:          "application/json",
+			query:           "?format=xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+		},
+		{
+			desc:            "unknown format",
+			query:           "?format=yaml",
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"Invalid format, expected json, csv, ndjson, xml or msgpack"}`,
+		},
+		{
+			desc:            "not acceptable",
+			accept:          "image/png",
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/json",
+			wantBody:        `{"error":"Not Acceptable"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			mockQ := &mocks.Querier{}
+			mockQ.On("ListPosts", mock.Anything, mock.Anything).Return(posts, nil).Maybe()
+			mockQ.On("ListPostsRows", mock.Anything, mock.Anything).Return(&fakeRows{values: rows()}, nil).Maybe()
+			h := router.NewPostHandler(nil, mockQ)
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/posts"+tc.query, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			w := httptest.NewRecorder()
+
+			// When:
+			h.List(r).Respond(w, r)
+
+			got := w.Result()
+			defer got.Body.Close()
+			gotBodyBytes, err := io.ReadAll(got.Body)
+			require.NoError(t, err)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, got.StatusCode)
+			assert.Equal(t, tc.wantContentType, got.Header.Get("Content-Type"))
+			assert.Equal(t, "Accept", got.Header.Get("Vary"))
+			switch {
+			case tc.wantBody == "":
+			case tc.wantContentType == "application/json":
+				assert.JSONEq(t, tc.wantBody, string(gotBodyBytes))
+			default:
+				assert.Equal(t, tc.wantBody, string(gotBodyBytes))
+			}
+		})
+	}
+}
+
+func Test_PostHandler_List_StreamError(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	row := []any{uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), "Post title", (*string)(nil), fixedTime, fixedTime, "post-title"}
+
+	t.Run("query", func(t *testing.T) {
+		t.Parallel()
+
+		// Given:
+		mockQ := &mocks.Querier{}
+		mockQ.On("ListPostsRows", mock.Anything, mock.Anything).Return(nil, errors.New("db error"))
+		h := router.NewPostHandler(nil, mockQ)
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/posts?format=ndjson", nil)
+		w := httptest.NewRecorder()
+
+		// When:
+		h.List(r).Respond(w, r)
+
+		// Then:
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"error":"Internal Server Error"}`, w.Body.String())
+	})
+
+	t.Run("before the first row", func(t *testing.T) {
+		t.Parallel()
+
+		// Given:
+		mockQ := &mocks.Querier{}
+		mockQ.On("ListPostsRows", mock.Anything, mock.Anything).Return(&fakeRows{values: nil, err: errors.New("db error")}, nil)
+		h := router.NewPostHandler(nil, mockQ)
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/posts?format=ndjson", nil)
+		w := httptest.NewRecorder()
+
+		// When:
+		h.List(r).Respond(w, r)
+
+		// Then: the error is still sent as a response
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.JSONEq(t, `{"error":"Internal Server Error"}`, w.Body.String())
+	})
+
+	t.Run("after the first row", func(t *testing.T) {
+		t.Parallel()
+
+		// Given:
+		dbRows := &fakeRows{values: [][]any{row}, err: errors.New("db error")}
+		mockQ := &mocks.Querier{}
+		mockQ.On("ListPostsRows", mock.Anything, mock.Anything).Return(dbRows, nil)
+		h := router.NewPostHandler(nil, mockQ)
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/posts?format=csv", nil)
+		w := httptest.NewRecorder()
+
+		// When: Then: the response is aborted, leaving it truncated
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			h.List(r).Respond(w, r)
+		})
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, dbRows.closed)
+	})
+}
+
+func Test_PostHandler_Get_MsgPack(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	mockQ := &mocks.Querier{}
+	mockQ.On("GetPost", mock.Anything, mock.Anything, fixedUUID).
+		Return(models.Post{ID: fixedUUID, Title: "Post title", Description: nil, CreatedAt: fixedTime, UpdatedAt: fixedTime, Slug: "post-title"}, nil)
+	h := router.NewPostHandler(nil, mockQ)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/posts/"+fixedUUID.String(), nil)
+	r.Header.Set("Accept", "application/msgpack")
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", fixedUUID.String())
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+	w := httptest.NewRecorder()
+
+	// When:
+	h.Get(r).Respond(w, r)
+
+	// Then: fields are named after their json tags
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+	assert.Equal(t, "Accept", w.Header().Get("Vary"))
+	var got map[string]any
+	require.NoError(t, msgpack.NewDecoder(bytes.NewReader(w.Body.Bytes())).Decode(&got))
+	assert.Equal(t, "Post title", got["title"])
+	assert.Nil(t, got["description"])
+	assert.Contains(t, got, "created_at")
+}
@@ -1,8 +1,11 @@
 package router
 
 import (
+	"context"
 	"errors"
 	"net/http"
+	"path"
+	"strings"
 
 	"go-starter/internal/models"
 
@@ -16,11 +19,11 @@ import (
 // postHandler implements CRUD operations for blog posts
 type postHandler struct {
 	db      models.DBTX
-	querier models.Querier
+	querier models.RowsQuerier
 }
 
 // NewPostHandler creates a new post handler with database connection and query interface
-func NewPostHandler(db models.DBTX, q models.Querier) *postHandler {
+func NewPostHandler(db models.DBTX, q models.RowsQuerier) *postHandler {
 	return &postHandler{
 		db:      db,
 		querier: q,
@@ -37,10 +40,14 @@ type CreatePostParams struct {
 func (h *postHandler) Create(r *http.Request, params CreatePostParams) httphandler.Responder {
 	ctx := r.Context()
 
-	post, err := h.querier.CreatePost(ctx, h.db, models.CreatePostParams{
-		ID:          uuid.New(),
-		Title:       params.Title,
-		Description: &params.Description,
+	id := uuid.New()
+	post, err := savePostWithSlug(ctx, h.querier, h.db, id, params.Title, func(slug string) (models.Post, error) {
+		return h.querier.CreatePost(ctx, h.db, models.CreatePostParams{
+			ID:          id,
+			Title:       params.Title,
+			Description: &params.Description,
+			Slug:        slug,
+		})
 	})
 	if err != nil {
 		return jsonresp.InternalServerError(err)
@@ -49,27 +56,76 @@ func (h *postHandler) Create(r *http.Request, params CreatePostParams) httphandl
 	return jsonresp.Success(&post)
 }
 
-// List retrieves a list of all blog posts
+// List retrieves a list of all blog posts, in the format negotiated with
+// Accept or ?format=. CSV and NDJSON are streamed from the rows as they are read.
 func (h *postHandler) List(r *http.Request) httphandler.Responder {
 	ctx := r.Context()
 
+	format, err := negotiateFormat(r)
+	if err != nil {
+		return varyAccept(formatError(err))
+	}
+
+	if format.streamed() {
+		rows, err := h.querier.ListPostsRows(ctx, h.db)
+		if err != nil {
+			return jsonresp.InternalServerError(err)
+		}
+		return varyAccept(Stream(format, rows, pgx.RowToStructByPos[models.Post]))
+	}
+
 	posts, err := h.querier.ListPosts(ctx, h.db)
 	if err != nil {
 		return jsonresp.InternalServerError(err)
 	}
-	return jsonresp.Success(&posts)
+	return Negotiated(r, &posts)
 }
 
-// Get retrieves a single blog post by ID
-func (h *postHandler) Get(r *http.Request) httphandler.Responder {
+// Export streams every blog post as NDJSON, or CSV with ?format=csv, in the
+// format read by the import
+func (h *postHandler) Export(r *http.Request) httphandler.Responder {
 	ctx := r.Context()
 
-	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	format := FormatNDJSON
+	if s := r.URL.Query().Get("format"); s != "" {
+		format = Format(strings.ToLower(s))
+		if !format.streamed() {
+			return jsonresp.Error(nil, "Invalid format, expected ndjson or csv", http.StatusBadRequest)
+		}
+	}
+
+	rows, err := h.querier.ListPostsRows(ctx, h.db)
 	if err != nil {
-		return jsonresp.Error(err, "Invalid ID format", http.StatusBadRequest)
+		return jsonresp.InternalServerError(err)
 	}
+	stream := Stream(format, rows, pgx.RowToStructByPos[models.Post])
+	return responderFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="posts.`+string(format)+`"`)
+		stream.Respond(w, r)
+	})
+}
+
+// Get retrieves a single blog post by ID or slug, in the format negotiated
+// with Accept or ?format=. A former slug of a renamed post redirects to its
+// current slug with 301 Moved Permanently.
+func (h *postHandler) Get(r *http.Request) httphandler.Responder {
+	ctx := r.Context()
 
-	post, err := h.querier.GetPost(ctx, h.db, id)
+	param := chi.URLParam(r, "id")
+	post, err := h.getPost(ctx, param)
+	if errors.Is(err, pgx.ErrNoRows) && !isPostID(param) {
+		current, err := h.querier.GetCurrentPostSlug(ctx, h.db, param)
+		if err == nil {
+			url := path.Join(path.Dir(r.URL.Path), current)
+			if r.URL.RawQuery != "" {
+				url += "?" + r.URL.RawQuery
+			}
+			return httphandler.Redirect(url, http.StatusMovedPermanently)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return jsonresp.InternalServerError(err)
+		}
+	}
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return jsonresp.Error(err, "Post not found", http.StatusNotFound)
@@ -77,7 +133,15 @@ func (h *postHandler) Get(r *http.Request) httphandler.Responder {
 		return jsonresp.InternalServerError(err)
 	}
 
-	return jsonresp.Success(&post)
+	return Negotiated(r, &post)
+}
+
+// getPost retrieves a post by ID, or by its current slug
+func (h *postHandler) getPost(ctx context.Context, param string) (models.Post, error) {
+	if id, err := uuid.Parse(param); err == nil {
+		return h.querier.GetPost(ctx, h.db, id) //nolint:wrapcheck // Passthrough of the query error
+	}
+	return h.querier.GetPostBySlug(ctx, h.db, param) //nolint:wrapcheck // Passthrough of the query error
 }
 
 // UpdatePostParams defines the required fields for updating a post
@@ -86,20 +150,30 @@ type UpdatePostParams struct {
 	Description *string `json:"description"`
 }
 
-// Update ipdates an existing blog post by ID
+// Update ipdates an existing blog post by ID or slug. A new title gives the
+// post a new slug, the former one redirecting to it.
 func (h *postHandler) Update(r *http.Request, input UpdatePostParams) httphandler.Responder {
 	ctx := r.Context()
 
-	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	id, err := resolvePostID(ctx, h.querier, h.db, chi.URLParam(r, "id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return jsonresp.Error(err, "Post not found", http.StatusNotFound)
+	}
 	if err != nil {
-		return jsonresp.Error(err, "Invalid ID format", http.StatusBadRequest)
+		return jsonresp.InternalServerError(err)
 	}
 
-	post, err := h.querier.UpdatePost(ctx, h.db, models.UpdatePostParams{
-		ID:          id,
-		Title:       input.Title,
-		Description: input.Description,
+	post, err := savePostWithSlug(ctx, h.querier, h.db, id, input.Title, func(slug string) (models.Post, error) {
+		return h.querier.UpdatePost(ctx, h.db, models.UpdatePostParams{
+			ID:          id,
+			Title:       input.Title,
+			Description: input.Description,
+			Slug:        slug,
+		})
 	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return jsonresp.Error(err, "Post not found", http.StatusNotFound)
+	}
 	if err != nil {
 		return jsonresp.InternalServerError(err)
 	}
@@ -107,13 +181,16 @@ func (h *postHandler) Update(r *http.Request, input UpdatePostParams) httphandle
 	return jsonresp.Success(&post)
 }
 
-// Delete deletes a single blog post by ID
+// Delete deletes a single blog post by ID or slug
 func (h *postHandler) Delete(r *http.Request) httphandler.Responder {
 	ctx := r.Context()
 
-	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	id, err := resolvePostID(ctx, h.querier, h.db, chi.URLParam(r, "id"))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return jsonresp.Error(err, "Post not found", http.StatusNotFound)
+	}
 	if err != nil {
-		return jsonresp.Error(err, "Invalid ID format", http.StatusBadRequest)
+		return jsonresp.InternalServerError(err)
 	}
 
 	rows, err := h.querier.DeletePost(ctx, h.db, id)
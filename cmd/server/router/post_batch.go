@@ -0,0 +1,248 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxBatchOperations bounds the operations of a batch, as they hold a transaction open
+const maxBatchOperations = 100
+
+// Batch modes
+const (
+	BatchModeAtomic     = "atomic"      // Every operation is applied, or none when any fails
+	BatchModeBestEffort = "best_effort" // Failed operations are rolled back to their savepoint, the others applied
+)
+
+// Batch operations
+const (
+	BatchOpCreate = "create"
+	BatchOpUpdate = "update"
+	BatchOpDelete = "delete"
+)
+
+// errBatchRolledBack rolls an atomic batch back once an operation failed
+var errBatchRolledBack = errors.New("batch rolled back")
+
+// BatchParams defines an ordered list of post operations run in one transaction
+type BatchParams struct {
+	Mode       string           `json:"mode"` // atomic, the default, or best_effort
+	Operations []BatchOperation `json:"operations"`
+}
+
+// BatchOperation is a create, update or delete of a post. Create and update
+// take the title and description, update and delete the ID or current slug.
+type BatchOperation struct {
+	Op          string  `json:"op"`
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Description *string `json:"description"`
+}
+
+// BatchResult is the outcome of an operation, with the status it would have
+// had as a single request
+type BatchResult struct {
+	Index  int          `json:"index"`
+	Op     string       `json:"op"`
+	Status int          `json:"status"`
+	Post   *models.Post `json:"post"`  // Created or updated post
+	Error  *string      `json:"error"` // Cause of a failed operation
+}
+
+// BatchResponse holds the results of every operation, in order
+type BatchResponse struct {
+	Mode      string        `json:"mode"`
+	Committed bool          `json:"committed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// postBatchHandler runs several post operations in a single transaction
+type postBatchHandler struct {
+	db      txBeginner
+	querier models.Querier
+}
+
+// NewPostBatchHandler creates a new post batch handler with database connection and query interface
+func NewPostBatchHandler(db txBeginner, q models.Querier) *postBatchHandler {
+	return &postBatchHandler{
+		db:      db,
+		querier: q,
+	}
+}
+
+// Batch runs the operations in order in a single transaction. In atomic mode
+// the first failure rolls everything back and the response is 422 with the
+// results so far, the next operations being 424 Failed Dependency. In
+// best_effort mode each operation runs in a savepoint, so a failure only
+// undoes that operation.
+func (h *postBatchHandler) Batch(r *http.Request, params BatchParams) httphandler.Responder {
+	ctx := r.Context()
+
+	mode := params.Mode
+	if mode == "" {
+		mode = BatchModeAtomic
+	}
+	if mode != BatchModeAtomic && mode != BatchModeBestEffort {
+		return jsonresp.Error(nil, "Invalid mode, expected atomic or best_effort", http.StatusBadRequest)
+	}
+	if len(params.Operations) == 0 || len(params.Operations) > maxBatchOperations {
+		return jsonresp.Error(nil, fmt.Sprintf("A batch takes between 1 and %d operations", maxBatchOperations), http.StatusBadRequest)
+	}
+
+	res := BatchResponse{Mode: mode, Committed: false, Results: make([]BatchResult, 0, len(params.Operations))}
+	var opErr error
+	err := pgx.BeginFunc(ctx, h.db, func(tx pgx.Tx) error {
+		for i, op := range params.Operations {
+			var db models.DBTX = tx
+			var savepoint pgx.Tx
+			if mode == BatchModeBestEffort {
+				sp, err := tx.Begin(ctx)
+				if err != nil {
+					return fmt.Errorf("tx.Begin: %w", err)
+				}
+				db, savepoint = sp, sp
+			}
+
+			result, err := h.run(ctx, db, i, op)
+			res.Results = append(res.Results, result)
+			if err != nil {
+				slogr.FromContext(ctx).Error("[batch] operation failed", slog.Int("index", i), slog.Any("err", err))
+			}
+
+			switch {
+			case savepoint == nil && result.Error != nil:
+				opErr = err
+				return errBatchRolledBack
+			case savepoint != nil && result.Error != nil:
+				if err := savepoint.Rollback(ctx); err != nil {
+					return fmt.Errorf("savepoint.Rollback: %w", err)
+				}
+			case savepoint != nil:
+				if err := savepoint.Commit(ctx); err != nil {
+					return fmt.Errorf("savepoint.Commit: %w", err)
+				}
+			}
+		}
+		return nil
+	})
+
+	if errors.Is(err, errBatchRolledBack) {
+		if opErr != nil {
+			return jsonresp.InternalServerError(opErr)
+		}
+		msg := "Not run, an earlier operation failed"
+		for i := len(res.Results); i < len(params.Operations); i++ {
+			res.Results = append(res.Results, BatchResult{
+				Index:  i,
+				Op:     params.Operations[i].Op,
+				Status: http.StatusFailedDependency,
+				Post:   nil,
+				Error:  &msg,
+			})
+		}
+		return jsonresp.Success(&res).WithStatus(http.StatusUnprocessableEntity)
+	}
+	if err != nil {
+		return jsonresp.InternalServerError(err)
+	}
+
+	res.Committed = true
+	return jsonresp.Success(&res)
+}
+
+// run applies an operation with db. Failures are reported in the result, with
+// the error for server errors.
+func (h *postBatchHandler) run(ctx context.Context, db models.DBTX, i int, op BatchOperation) (BatchResult, error) {
+	result := BatchResult{Index: i, Op: op.Op, Status: http.StatusOK, Post: nil, Error: nil}
+	fail := func(status int, msg string, err error) (BatchResult, error) {
+		result.Status, result.Error = status, &msg
+		if status >= http.StatusInternalServerError {
+			return result, err
+		}
+		return result, nil
+	}
+
+	var id uuid.UUID
+	if slices.Contains([]string{BatchOpUpdate, BatchOpDelete}, op.Op) {
+		if op.ID == "" {
+			return fail(http.StatusBadRequest, "Invalid ID format", nil)
+		}
+		var err error
+		id, err = resolvePostID(ctx, h.querier, db, op.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fail(http.StatusNotFound, "Post not found", nil)
+		}
+		if err != nil {
+			return fail(http.StatusInternalServerError, "Internal Server Error", err)
+		}
+	}
+
+	var slug string
+	if slices.Contains([]string{BatchOpCreate, BatchOpUpdate}, op.Op) {
+		if op.Op == BatchOpCreate {
+			id = uuid.New()
+		}
+		var err error
+		if slug, err = PostSlug(ctx, h.querier, db, id, op.Title); err != nil {
+			return fail(http.StatusInternalServerError, "Internal Server Error", err)
+		}
+	}
+
+	switch op.Op {
+	case BatchOpCreate:
+		post, err := h.querier.CreatePost(ctx, db, models.CreatePostParams{
+			ID:          id,
+			Title:       op.Title,
+			Description: op.Description,
+			Slug:        slug,
+		})
+		if isSlugConflict(err) {
+			return fail(http.StatusConflict, "Slug taken by a concurrent request", nil)
+		}
+		if err != nil {
+			return fail(http.StatusInternalServerError, "Internal Server Error", fmt.Errorf("q.CreatePost: %w", err))
+		}
+		result.Post = &post
+	case BatchOpUpdate:
+		post, err := h.querier.UpdatePost(ctx, db, models.UpdatePostParams{
+			ID:          id,
+			Title:       op.Title,
+			Description: op.Description,
+			Slug:        slug,
+		})
+		if isSlugConflict(err) {
+			return fail(http.StatusConflict, "Slug taken by a concurrent request", nil)
+		}
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fail(http.StatusNotFound, "Post not found", nil)
+		}
+		if err != nil {
+			return fail(http.StatusInternalServerError, "Internal Server Error", fmt.Errorf("q.UpdatePost: %w", err))
+		}
+		result.Post = &post
+	case BatchOpDelete:
+		rows, err := h.querier.DeletePost(ctx, db, id)
+		if err != nil {
+			return fail(http.StatusInternalServerError, "Internal Server Error", fmt.Errorf("q.DeletePost: %w", err))
+		}
+		if rows == 0 {
+			return fail(http.StatusNotFound, "Post not found", nil)
+		}
+		result.Status = http.StatusNoContent
+	default:
+		return fail(http.StatusBadRequest, "Unknown operation, expected create, update or delete", nil)
+	}
+	return result, nil
+}
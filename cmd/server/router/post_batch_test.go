@@ -0,0 +1,224 @@
+package router_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_PostBatchHandler_Batch(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	missingUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	post := models.Post{
+		ID:          fixedUUID,
+		Title:       "Post title",
+		Description: ptr.Ref("Post description"),
+		CreatedAt:   fixedTime,
+		UpdatedAt:   fixedTime,
+		Slug:        "post-title",
+	}
+	postJSON := `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"post-title"}`
+
+	operations := []router.BatchOperation{
+		{Op: router.BatchOpCreate, ID: "", Title: "Post title", Description: ptr.Ref("Post description")},
+		{Op: router.BatchOpUpdate, ID: missingUUID.String(), Title: "Post title", Description: nil},
+		{Op: router.BatchOpDelete, ID: fixedUUID.String(), Title: "", Description: nil},
+	}
+	mockSlugs := func(m *mocks.Querier) {
+		m.On("ListPostSlugs", mock.Anything, mock.Anything, []string{"post-title", "post-title-%"}).
+			Return([]models.ListPostSlugsRow{}, nil)
+	}
+	mockCreate := func(m *mocks.Querier) {
+		mockSlugs(m)
+		m.On("CreatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.CreatePostParams) bool {
+			return p.Title == "Post title" && ptr.SameValue(p.Description, ptr.Ref("Post description")) && p.Slug == "post-title"
+		})).Return(post, nil)
+	}
+	mockUpdate := func(m *mocks.Querier) {
+		mockSlugs(m)
+		m.On("UpdatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.UpdatePostParams) bool {
+			return p.ID == missingUUID
+		})).Return(models.Post{}, pgx.ErrNoRows)
+	}
+	mockDelete := func(m *mocks.Querier) {
+		m.On("DeletePost", mock.Anything, mock.Anything, fixedUUID).Return(int64(1), nil)
+	}
+
+	testCases := []struct {
+		desc           string
+		params         router.BatchParams
+		mockFunc       func(*mocks.Querier)
+		wantStatus     int
+		wantBody       string
+		wantCommit     bool
+		wantSavepoints []bool // Committed savepoints, the others are rolled back
+	}{
+		{
+			desc:   "atomic | rolled back",
+			params: router.BatchParams{Mode: "", Operations: operations},
+			mockFunc: func(m *mocks.Querier) {
+				mockCreate(m)
+				mockUpdate(m)
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"mode":"atomic","committed":false,"results":[` +
+				`{"index":0,"op":"create","status":200,"post":` + postJSON + `,"error":null},` +
+				`{"index":1,"op":"update","status":404,"post":null,"error":"Post not found"},` +
+				`{"index":2,"op":"delete","status":424,"post":null,"error":"Not run, an earlier operation failed"}]}`,
+			wantCommit: false,
+		},
+		{
+			desc: "atomic | committed",
+			params: router.BatchParams{Mode: router.BatchModeAtomic, Operations: []router.BatchOperation{
+				operations[0], operations[2],
+			}},
+			mockFunc: func(m *mocks.Querier) {
+				mockCreate(m)
+				mockDelete(m)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"mode":"atomic","committed":true,"results":[` +
+				`{"index":0,"op":"create","status":200,"post":` + postJSON + `,"error":null},` +
+				`{"index":1,"op":"delete","status":204,"post":null,"error":null}]}`,
+			wantCommit: true,
+		},
+		{
+			desc: "atomic | by slug",
+			params: router.BatchParams{Mode: router.BatchModeAtomic, Operations: []router.BatchOperation{
+				{Op: router.BatchOpDelete, ID: "post-title", Title: "", Description: nil},
+			}},
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "post-title").Return(post, nil)
+				mockDelete(m)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"mode":"atomic","committed":true,"results":[` +
+				`{"index":0,"op":"delete","status":204,"post":null,"error":null}]}`,
+			wantCommit: true,
+		},
+		{
+			desc:   "best effort",
+			params: router.BatchParams{Mode: router.BatchModeBestEffort, Operations: operations},
+			mockFunc: func(m *mocks.Querier) {
+				mockCreate(m)
+				mockUpdate(m)
+				mockDelete(m)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"mode":"best_effort","committed":true,"results":[` +
+				`{"index":0,"op":"create","status":200,"post":` + postJSON + `,"error":null},` +
+				`{"index":1,"op":"update","status":404,"post":null,"error":"Post not found"},` +
+				`{"index":2,"op":"delete","status":204,"post":null,"error":null}]}`,
+			wantCommit:     true,
+			wantSavepoints: []bool{true, false, true},
+		},
+		{
+			desc: "best effort | invalid operations",
+			params: router.BatchParams{Mode: router.BatchModeBestEffort, Operations: []router.BatchOperation{
+				{Op: router.BatchOpDelete, ID: "", Title: "", Description: nil},
+				{Op: router.BatchOpDelete, ID: "unknown-slug", Title: "", Description: nil},
+				{Op: "upsert", ID: "", Title: "", Description: nil},
+			}},
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "unknown-slug").Return(models.Post{}, pgx.ErrNoRows)
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"mode":"best_effort","committed":true,"results":[` +
+				`{"index":0,"op":"delete","status":400,"post":null,"error":"Invalid ID format"},` +
+				`{"index":1,"op":"delete","status":404,"post":null,"error":"Post not found"},` +
+				`{"index":2,"op":"upsert","status":400,"post":null,"error":"Unknown operation, expected create, update or delete"}]}`,
+			wantCommit:     true,
+			wantSavepoints: []bool{false, false, false},
+		},
+		{
+			desc: "best effort | slug taken concurrently",
+			params: router.BatchParams{Mode: router.BatchModeBestEffort, Operations: []router.BatchOperation{
+				operations[0],
+			}},
+			mockFunc: func(m *mocks.Querier) {
+				mockSlugs(m)
+				m.On("CreatePost", mock.Anything, mock.Anything, mock.Anything).
+					Return(models.Post{}, &pgconn.PgError{Code: "23505", ConstraintName: "post_slug_key"})
+			},
+			wantStatus: http.StatusOK,
+			wantBody: `{"mode":"best_effort","committed":true,"results":[` +
+				`{"index":0,"op":"create","status":409,"post":null,"error":"Slug taken by a concurrent request"}]}`,
+			wantCommit:     true,
+			wantSavepoints: []bool{false},
+		},
+		{
+			desc: "atomic | db error",
+			params: router.BatchParams{Mode: router.BatchModeAtomic, Operations: []router.BatchOperation{
+				operations[2],
+			}},
+			mockFunc: func(m *mocks.Querier) {
+				m.On("DeletePost", mock.Anything, mock.Anything, fixedUUID).Return(int64(0), errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"Internal Server Error"}`,
+			wantCommit: false,
+		},
+		{
+			desc:       "invalid mode",
+			params:     router.BatchParams{Mode: "partial", Operations: operations},
+			mockFunc:   func(*mocks.Querier) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"Invalid mode, expected atomic or best_effort"}`,
+		},
+		{
+			desc:       "no operations",
+			params:     router.BatchParams{Mode: "", Operations: nil},
+			mockFunc:   func(*mocks.Querier) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"A batch takes between 1 and 100 operations"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			mockQ := &mocks.Querier{}
+			tc.mockFunc(mockQ)
+			db := &fakeTxDB{tx: &fakeTx{}}
+			h := router.NewPostBatchHandler(db, mockQ)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/posts:batch", nil)
+			w := httptest.NewRecorder()
+
+			// When:
+			h.Batch(r, tc.params).Respond(w, r)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.JSONEq(t, tc.wantBody, w.Body.String())
+			assert.Equal(t, tc.wantCommit, db.tx.committed)
+			gotSavepoints := make([]bool, 0, len(db.tx.savepoints))
+			for _, sp := range db.tx.savepoints {
+				assert.NotEqual(t, sp.committed, sp.rolledBack)
+				gotSavepoints = append(gotSavepoints, sp.committed)
+			}
+			if tc.wantSavepoints == nil {
+				tc.wantSavepoints = []bool{}
+			}
+			assert.Equal(t, tc.wantSavepoints, gotSavepoints)
+			mockQ.AssertExpectations(t)
+		})
+	}
+}
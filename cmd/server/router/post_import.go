@@ -0,0 +1,544 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Bounds of post imports
+const (
+	maxImportErrors    = 100       // Invalid rows listed in a summary, the others are only counted
+	maxImportLineSize  = 1 << 20   // Longest NDJSON line
+	importQueueSize    = 16        // Background imports waiting for the running one
+	importJobRetention = time.Hour // How long finished background imports can be looked up
+)
+
+// Conflict modes of post imports, chosen with ?on_conflict=
+const (
+	ImportOnConflictUpdate = "update" // Existing posts are updated, the default
+	ImportOnConflictSkip   = "skip"   // Existing posts are left as they are
+)
+
+// Statuses of background imports
+const (
+	ImportJobQueued    = "queued"
+	ImportJobRunning   = "running"
+	ImportJobSucceeded = "succeeded"
+	ImportJobFailed    = "failed"
+)
+
+// Errors of import files
+var (
+	errImportNoTitle     = errors.New("missing title column")
+	errImportTitle       = errors.New("title is required")
+	errImportDuplicateID = errors.New("duplicate id")
+	errImportQueueFull   = errors.New("import queue is full")
+)
+
+// ImportRowError is a row of an import file that failed validation
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportSummary reports the outcome of a post import. Nothing is imported
+// when any row is invalid.
+type ImportSummary struct {
+	Total      int              `json:"total"`    // Rows read
+	Invalid    int              `json:"invalid"`  // Rows failing validation
+	Inserted   int64            `json:"inserted"` // New posts
+	Updated    int64            `json:"updated"`  // Existing posts updated, with on_conflict=update
+	Skipped    int64            `json:"skipped"`  // Existing posts left as they are, with on_conflict=skip
+	DryRun     bool             `json:"dry_run"`
+	OnConflict string           `json:"on_conflict"`
+	Errors     []ImportRowError `json:"errors"` // The first invalid rows
+}
+
+// ImportJob is a post import running in the background
+type ImportJob struct {
+	ID         uuid.UUID      `json:"id"`
+	Status     string         `json:"status"`
+	CreatedAt  time.Time      `json:"created_at"`
+	FinishedAt *time.Time     `json:"finished_at"`
+	Summary    *ImportSummary `json:"summary"` // Totals once succeeded
+	Error      *string        `json:"error"`   // Cause once failed
+}
+
+// importPost is a row of an import file, only the title is required
+type importPost struct {
+	ID          *uuid.UUID `json:"id"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+}
+
+// txBeginner is a database connection starting transactions, e.g. *pgxpool.Pool
+type txBeginner interface {
+	models.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// queuedImport is a background import waiting to run
+type queuedImport struct {
+	job     *ImportJob
+	rows    []models.CopyPostImportParams
+	summary ImportSummary
+}
+
+// postImporter loads posts from NDJSON or CSV files with COPY. Files of at
+// least asyncSize bytes are imported one at a time in the background by Run.
+type postImporter struct {
+	db        txBeginner
+	querier   models.Querier
+	maxSize   int64
+	asyncSize int64
+	queue     chan queuedImport
+
+	mu   sync.Mutex
+	jobs map[uuid.UUID]*ImportJob
+}
+
+// NewPostImporter creates a post importer accepting files of up to maxSize
+// bytes and importing those of at least asyncSize bytes in the background
+func NewPostImporter(db txBeginner, q models.Querier, maxSize, asyncSize int64) *postImporter {
+	return &postImporter{
+		db:        db,
+		querier:   q,
+		maxSize:   maxSize,
+		asyncSize: asyncSize,
+		queue:     make(chan queuedImport, importQueueSize),
+		mu:        sync.Mutex{},
+		jobs:      map[uuid.UUID]*ImportJob{},
+	}
+}
+
+// Run imports the queued files until ctx is done, and forgets the jobs
+// finished for longer than importJobRetention
+func (h *postImporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(importJobRetention / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case q := <-h.queue:
+			h.runJob(ctx, q)
+		case now := <-ticker.C:
+			h.prune(now)
+		}
+	}
+}
+
+// Import loads the posts of an NDJSON or CSV body, validating every row first.
+// ?dry_run=true only validates them and ?on_conflict=skip keeps existing posts
+// instead of updating them. Large files are imported in the background, the
+// response is then 202 Accepted with the job to poll in Location.
+func (h *postImporter) Import(r *http.Request) httphandler.Responder {
+	ctx := r.Context()
+
+	dryRun := false
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return jsonresp.Error(err, "Invalid dry_run", http.StatusBadRequest)
+		}
+		dryRun = v
+	}
+	onConflict := ImportOnConflictUpdate
+	if s := r.URL.Query().Get("on_conflict"); s != "" {
+		if s != ImportOnConflictUpdate && s != ImportOnConflictSkip {
+			return jsonresp.Error(nil, "Invalid on_conflict, expected update or skip", http.StatusBadRequest)
+		}
+		onConflict = s
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var read func(io.Reader, func(int, importPost, error)) error
+	switch mediaType {
+	case "application/x-ndjson", "application/jsonl":
+		read = readImportNDJSON
+	case "text/csv":
+		read = readImportCSV
+	default:
+		return jsonresp.Error(nil, "Unsupported Content-Type, expected application/x-ndjson or text/csv", http.StatusUnsupportedMediaType)
+	}
+
+	body := &countingReader{r: http.MaxBytesReader(nil, r.Body, h.maxSize), n: 0}
+	batch := newImportBatch(dryRun, onConflict)
+	if err := read(body, batch.add); err != nil {
+		if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+			return jsonresp.Error(err, "Import file too large", http.StatusRequestEntityTooLarge)
+		}
+		return jsonresp.Error(err, "Invalid import file", http.StatusBadRequest)
+	}
+
+	summary := batch.summary
+	if summary.Invalid > 0 {
+		return jsonresp.Success(&summary).WithStatus(http.StatusUnprocessableEntity)
+	}
+	if dryRun || len(batch.rows) == 0 {
+		return jsonresp.Success(&summary)
+	}
+
+	if body.n >= h.asyncSize {
+		job, err := h.enqueue(batch.rows, summary)
+		if err != nil {
+			return jsonresp.Error(err, "Too many imports in progress, retry later", http.StatusServiceUnavailable).
+				WithHeader("Retry-After", "60")
+		}
+		return jsonresp.Success(job).
+			WithStatus(http.StatusAccepted).
+			WithHeader("Location", "/api/v1/posts:import/"+job.ID.String())
+	}
+
+	summary, err := h.importPosts(ctx, batch.rows, summary)
+	if err != nil {
+		return jsonresp.InternalServerError(err)
+	}
+	return jsonresp.Success(&summary)
+}
+
+// Job returns the status of a background import
+func (h *postImporter) Job(r *http.Request) httphandler.Responder {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		return jsonresp.Error(err, "Invalid ID format", http.StatusBadRequest)
+	}
+
+	h.mu.Lock()
+	job, ok := h.jobs[id]
+	var snapshot ImportJob
+	if ok {
+		snapshot = *job
+	}
+	h.mu.Unlock()
+
+	if !ok {
+		return jsonresp.Error(nil, "Import not found", http.StatusNotFound)
+	}
+	return jsonresp.Success(&snapshot)
+}
+
+// enqueue registers a background import, failing when the queue is full
+func (h *postImporter) enqueue(rows []models.CopyPostImportParams, summary ImportSummary) (*ImportJob, error) {
+	job := &ImportJob{
+		ID:         uuid.New(),
+		Status:     ImportJobQueued,
+		CreatedAt:  time.Now(),
+		FinishedAt: nil,
+		Summary:    nil,
+		Error:      nil,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	select {
+	case h.queue <- queuedImport{job: job, rows: rows, summary: summary}:
+	default:
+		return nil, errImportQueueFull
+	}
+	h.jobs[job.ID] = job
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// runJob runs a background import and records its outcome
+func (h *postImporter) runJob(ctx context.Context, q queuedImport) {
+	ctx = slogr.WithAttrs(ctx, slog.String("import-id", q.job.ID.String()))
+	logger := slogr.FromContext(ctx)
+
+	h.setJob(q.job, func(j *ImportJob) { j.Status = ImportJobRunning })
+	logger.Info("[import] started", slog.Int("rows", len(q.rows)))
+
+	summary, err := h.importPosts(ctx, q.rows, q.summary)
+	now := time.Now()
+	if err != nil {
+		logger.Error("[import] failed", slog.Any("err", err))
+		h.setJob(q.job, func(j *ImportJob) {
+			msg := err.Error()
+			j.Status, j.FinishedAt, j.Error = ImportJobFailed, &now, &msg
+		})
+		return
+	}
+
+	logger.Info("[import] succeeded",
+		slog.Int64("inserted", summary.Inserted),
+		slog.Int64("updated", summary.Updated),
+		slog.Int64("skipped", summary.Skipped))
+	h.setJob(q.job, func(j *ImportJob) {
+		j.Status, j.FinishedAt, j.Summary = ImportJobSucceeded, &now, &summary
+	})
+}
+
+// setJob updates a job under the lock
+func (h *postImporter) setJob(job *ImportJob, update func(*ImportJob)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	update(job)
+}
+
+// prune forgets the jobs finished for longer than importJobRetention
+func (h *postImporter) prune(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, job := range h.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importJobRetention {
+			delete(h.jobs, id)
+		}
+	}
+}
+
+// importPosts allocates the slugs of the rows, copies them into post_import
+// and upserts them into post in a single transaction, returning the summary
+// with the affected posts
+func (h *postImporter) importPosts(ctx context.Context, rows []models.CopyPostImportParams, summary ImportSummary) (ImportSummary, error) {
+	importID := uuid.New()
+	for i := range rows {
+		rows[i].ImportID = importID
+	}
+
+	err := pgx.BeginFunc(ctx, h.db, func(tx pgx.Tx) error {
+		titles := make([]string, len(rows))
+		for i, row := range rows {
+			titles[i] = row.Title
+		}
+		slugs, err := loadPostSlugs(ctx, h.querier, tx, titles)
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			rows[i].Slug = slugs.allocate(rows[i].ID, rows[i].Title)
+		}
+
+		if _, err := h.querier.CopyPostImport(ctx, tx, rows); err != nil {
+			return fmt.Errorf("q.CopyPostImport: %w", err)
+		}
+
+		switch summary.OnConflict {
+		case ImportOnConflictSkip:
+			inserted, err := h.querier.InsertPostImport(ctx, tx, importID)
+			if err != nil {
+				return fmt.Errorf("q.InsertPostImport: %w", err)
+			}
+			summary.Inserted, summary.Skipped = inserted, int64(len(rows))-inserted
+		default:
+			upserted, err := h.querier.UpsertPostImport(ctx, tx, importID)
+			if err != nil {
+				return fmt.Errorf("q.UpsertPostImport: %w", err)
+			}
+			summary.Inserted, summary.Updated = upserted.Inserted, upserted.Updated
+		}
+
+		if _, err := h.querier.DeletePostImport(ctx, tx, importID); err != nil {
+			return fmt.Errorf("q.DeletePostImport: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return summary, fmt.Errorf("pgx.BeginFunc: %w", err)
+	}
+	return summary, nil
+}
+
+// importBatch collects the valid rows of an import file and the errors of the others
+type importBatch struct {
+	rows    []models.CopyPostImportParams
+	lines   map[uuid.UUID]int // Line of each id, to report duplicates
+	summary ImportSummary
+}
+
+// newImportBatch returns an empty batch
+func newImportBatch(dryRun bool, onConflict string) *importBatch {
+	return &importBatch{
+		rows:  nil,
+		lines: map[uuid.UUID]int{},
+		summary: ImportSummary{
+			Total:      0,
+			Invalid:    0,
+			Inserted:   0,
+			Updated:    0,
+			Skipped:    0,
+			DryRun:     dryRun,
+			OnConflict: onConflict,
+			Errors:     []ImportRowError{},
+		},
+	}
+}
+
+// add validates a row read at line, or records the error of reading it
+func (b *importBatch) add(line int, p importPost, err error) {
+	b.summary.Total++
+
+	if err == nil {
+		err = b.validate(line, &p)
+	}
+	if err != nil {
+		b.summary.Invalid++
+		if len(b.summary.Errors) < maxImportErrors {
+			b.summary.Errors = append(b.summary.Errors, ImportRowError{Line: line, Error: err.Error()})
+		}
+		return
+	}
+
+	b.rows = append(b.rows, models.CopyPostImportParams{
+		ImportID:    uuid.Nil,
+		ID:          *p.ID,
+		Title:       p.Title,
+		Description: p.Description,
+		CreatedAt:   p.CreatedAt,
+		UpdatedAt:   p.UpdatedAt,
+		Slug:        "", // Allocated on import, against the slugs of the other posts
+	})
+}
+
+// validate checks a row, giving it a new id when it has none
+func (b *importBatch) validate(line int, p *importPost) error {
+	if strings.TrimSpace(p.Title) == "" {
+		return errImportTitle
+	}
+	if p.ID == nil {
+		id := uuid.New()
+		p.ID = &id
+	}
+	if first, ok := b.lines[*p.ID]; ok {
+		return fmt.Errorf("%w %s, first on line %d", errImportDuplicateID, p.ID, first)
+	}
+	b.lines[*p.ID] = line
+	return nil
+}
+
+// readImportNDJSON reads a JSON object per line, blank lines are ignored
+func readImportNDJSON(r io.Reader, add func(int, importPost, error)) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64<<10), maxImportLineSize)
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var p importPost
+		err := json.Unmarshal(sc.Bytes(), &p)
+		add(line, p, err)
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("bufio.Scanner: %w", err)
+	}
+	return nil
+}
+
+// readImportCSV reads records with a header naming their columns, as written
+// by the CSV export. Unknown columns are ignored, empty values are null.
+func readImportCSV(r io.Reader, add func(int, importPost, error)) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("csv.Read: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return errImportNoTitle
+	}
+
+	for {
+		record, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if parseErr := (*csv.ParseError)(nil); errors.As(err, &parseErr) && errors.Is(err, csv.ErrFieldCount) {
+			add(parseErr.StartLine, importPost{}, csv.ErrFieldCount) //nolint:exhaustruct // the row is invalid
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("csv.Read: %w", err)
+		}
+
+		line, _ := cr.FieldPos(0)
+		p, err := parseImportRecord(record, columns)
+		add(line, p, err)
+	}
+}
+
+// parseImportRecord converts a CSV record to a post
+func parseImportRecord(record []string, columns map[string]int) (importPost, error) {
+	value := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+	timestamp := func(name string) (*time.Time, error) {
+		s := value(name)
+		if s == "" {
+			return nil, nil //nolint:nilnil // an empty value is null
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		return &t, nil
+	}
+
+	p := importPost{ID: nil, Title: value("title"), Description: nil, CreatedAt: nil, UpdatedAt: nil}
+	if s := value("id"); s != "" {
+		id, err := uuid.Parse(s)
+		if err != nil {
+			return p, fmt.Errorf("invalid id: %w", err)
+		}
+		p.ID = &id
+	}
+	if s := value("description"); s != "" {
+		p.Description = &s
+	}
+
+	var err error
+	if p.CreatedAt, err = timestamp("created_at"); err != nil {
+		return p, err
+	}
+	if p.UpdatedAt, err = timestamp("updated_at"); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err //nolint:wrapcheck // passthrough for the caller to inspect
+}
@@ -0,0 +1,281 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx is a transaction recording whether it was committed, and its savepoints
+type fakeTx struct {
+	pgx.Tx
+	committed  bool
+	rolledBack bool
+	savepoints []*fakeTx
+}
+
+func (tx *fakeTx) Begin(context.Context) (pgx.Tx, error) {
+	sp := &fakeTx{}
+	tx.savepoints = append(tx.savepoints, sp)
+	return sp, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error { tx.committed = true; return nil }
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if !tx.committed {
+		tx.rolledBack = true
+	}
+	return nil
+}
+
+// fakeTxDB starts tx
+type fakeTxDB struct {
+	models.DBTX
+	tx *fakeTx
+}
+
+func (db *fakeTxDB) Begin(context.Context) (pgx.Tx, error) { return db.tx, nil }
+
+func Test_PostImporter_Import(t *testing.T) {
+	t.Parallel()
+
+	const (
+		id1 = "550e8400-e29b-41d4-a716-446655440000"
+		id2 = "550e8400-e29b-41d4-a716-446655440001"
+	)
+
+	testCases := []struct {
+		desc        string
+		query       string
+		contentType string
+		body        string
+		mockFunc    func(*mocks.Querier)
+		wantStatus  int
+		wantBody    string
+		wantCommit  bool
+	}{
+		{
+			desc:        "ndjson upsert",
+			contentType: "application/x-ndjson",
+			body: `{"id":"` + id1 + `","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z"}` + "\n" +
+				"\n" +
+				`{"title":"Untitled"}` + "\n",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, []string{"post-title", "post-title-%", "untitled", "untitled-%"}).
+					Return([]models.ListPostSlugsRow{{Slug: "post-title", PostID: uuid.MustParse(id1)}}, nil)
+				m.On("CopyPostImport", mock.Anything, mock.Anything, mock.MatchedBy(func(rows []models.CopyPostImportParams) bool {
+					return len(rows) == 2 && rows[0].ID.String() == id1 && *rows[0].Description == "Post description" &&
+						rows[0].CreatedAt.Equal(time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)) && rows[0].Slug == "post-title" &&
+						rows[1].Title == "Untitled" && rows[1].Description == nil && rows[1].Slug == "untitled" &&
+						rows[0].ImportID == rows[1].ImportID
+				})).Return(int64(2), nil)
+				m.On("UpsertPostImport", mock.Anything, mock.Anything, mock.Anything).
+					Return(models.UpsertPostImportRow{Inserted: 1, Updated: 1}, nil)
+				m.On("DeletePostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"total":2,"invalid":0,"inserted":1,"updated":1,"skipped":0,"dry_run":false,"on_conflict":"update","errors":[]}`,
+			wantCommit: true,
+		},
+		{
+			desc:        "csv skip existing",
+			query:       "?on_conflict=skip",
+			contentType: "text/csv; charset=utf-8",
+			body: "id,title,description,created_at,updated_at,extra\n" +
+				id1 + ",\"Post, title\",,2025-01-18T00:13:02Z,,ignored\n" +
+				id2 + ",Untitled,Post description,,,\n",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{{Slug: "untitled", PostID: uuid.MustParse(id1)}}, nil)
+				m.On("CopyPostImport", mock.Anything, mock.Anything, mock.MatchedBy(func(rows []models.CopyPostImportParams) bool {
+					return len(rows) == 2 && rows[0].Title == "Post, title" && rows[0].Description == nil &&
+						rows[0].UpdatedAt == nil && rows[1].ID.String() == id2 && rows[1].Slug == "untitled-2"
+				})).Return(int64(2), nil)
+				m.On("InsertPostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+				m.On("DeletePostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(2), nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `{"total":2,"invalid":0,"inserted":1,"updated":0,"skipped":1,"dry_run":false,"on_conflict":"skip","errors":[]}`,
+			wantCommit: true,
+		},
+		{
+			desc:        "dry run",
+			query:       "?dry_run=true",
+			contentType: "application/x-ndjson",
+			body:        `{"title":"Post title"}` + "\n",
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusOK,
+			wantBody:    `{"total":1,"invalid":0,"inserted":0,"updated":0,"skipped":0,"dry_run":true,"on_conflict":"update","errors":[]}`,
+		},
+		{
+			desc:        "invalid rows",
+			contentType: "application/x-ndjson",
+			body: `{"id":"` + id1 + `","title":"Post title"}` + "\n" +
+				`{"title":" "}` + "\n" +
+				`{"id":"invalid","title":"Post title"}` + "\n" +
+				`{"id":"` + id1 + `","title":"Post title"}` + "\n",
+			mockFunc:   func(*mocks.Querier) {},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody: `{"total":4,"invalid":3,"inserted":0,"updated":0,"skipped":0,"dry_run":false,"on_conflict":"update","errors":[` +
+				`{"line":2,"error":"title is required"},` +
+				`{"line":3,"error":"invalid UUID length: 7"},` +
+				`{"line":4,"error":"duplicate id ` + id1 + `, first on line 1"}]}`,
+		},
+		{
+			desc:        "invalid csv rows",
+			contentType: "text/csv",
+			body:        "title,created_at\nPost title,yesterday\nPost title\n",
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantBody: `{"total":2,"invalid":2,"inserted":0,"updated":0,"skipped":0,"dry_run":false,"on_conflict":"update","errors":[` +
+				`{"line":2,"error":"invalid created_at: parsing time \"yesterday\" as \"2006-01-02T15:04:05.999999999Z07:00\": cannot parse \"yesterday\" as \"2006\""},` +
+				`{"line":3,"error":"wrong number of fields"}]}`,
+		},
+		{
+			desc:        "no title column",
+			contentType: "text/csv",
+			body:        "id,description\n",
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"error":"Invalid import file"}`,
+		},
+		{
+			desc:        "too large",
+			contentType: "application/x-ndjson",
+			body:        `{"title":"` + strings.Repeat("a", 1024) + `"}`,
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantBody:    `{"error":"Import file too large"}`,
+		},
+		{
+			desc:        "unsupported content type",
+			contentType: "application/json",
+			body:        `[]`,
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantBody:    `{"error":"Unsupported Content-Type, expected application/x-ndjson or text/csv"}`,
+		},
+		{
+			desc:        "invalid on_conflict",
+			query:       "?on_conflict=replace",
+			contentType: "application/x-ndjson",
+			mockFunc:    func(*mocks.Querier) {},
+			wantStatus:  http.StatusBadRequest,
+			wantBody:    `{"error":"Invalid on_conflict, expected update or skip"}`,
+		},
+		{
+			desc:        "db error",
+			contentType: "application/x-ndjson",
+			body:        `{"title":"Post title"}`,
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).Return([]models.ListPostSlugsRow{}, nil)
+				m.On("CopyPostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(0), errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"Internal Server Error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			mockQ := &mocks.Querier{}
+			tc.mockFunc(mockQ)
+			db := &fakeTxDB{tx: &fakeTx{}}
+			h := router.NewPostImporter(db, mockQ, 1024, 1<<20)
+			r := httptest.NewRequest(http.MethodPost, "/api/v1/posts:import"+tc.query, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+
+			// When:
+			h.Import(r).Respond(w, r)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.JSONEq(t, tc.wantBody, w.Body.String())
+			assert.Equal(t, tc.wantCommit, db.tx.committed)
+			mockQ.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_PostImporter_Background(t *testing.T) {
+	t.Parallel()
+
+	// Given: an importer running every import in the background
+	mockQ := &mocks.Querier{}
+	mockQ.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).Return([]models.ListPostSlugsRow{}, nil)
+	mockQ.On("CopyPostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+	mockQ.On("UpsertPostImport", mock.Anything, mock.Anything, mock.Anything).
+		Return(models.UpsertPostImportRow{Inserted: 1, Updated: 0}, nil)
+	mockQ.On("DeletePostImport", mock.Anything, mock.Anything, mock.Anything).Return(int64(1), nil)
+	h := router.NewPostImporter(&fakeTxDB{tx: &fakeTx{}}, mockQ, 1024, 0)
+	go h.Run(t.Context())
+
+	r := httptest.NewRequest(http.MethodPost, "/api/v1/posts:import", strings.NewReader(`{"title":"Post title"}`))
+	r.Header.Set("Content-Type", "application/x-ndjson")
+	w := httptest.NewRecorder()
+
+	// When:
+	h.Import(r).Respond(w, r)
+
+	// Then: the job is accepted and succeeds
+	require.Equal(t, http.StatusAccepted, w.Code)
+	location := w.Header().Get("Location")
+	require.True(t, strings.HasPrefix(location, "/api/v1/posts:import/"))
+
+	job := func() string {
+		id := strings.TrimPrefix(location, "/api/v1/posts:import/")
+		r := httptest.NewRequest(http.MethodGet, location, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		h.Job(r).Respond(w, r)
+		return w.Body.String()
+	}
+	require.Eventually(t, func() bool {
+		return strings.Contains(job(), `"status":"succeeded"`)
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Contains(t, job(), `"summary":{"total":1,"invalid":0,"inserted":1,"updated":0,"skipped":0,"dry_run":false,"on_conflict":"update","errors":[]}`)
+}
+
+func Test_PostHandler_Export(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	row := []any{uuid.MustParse("550e8400-e29b-41d4-a716-446655440000"), "Post title", (*string)(nil), fixedTime, fixedTime, "post-title"}
+	mockQ := &mocks.Querier{}
+	mockQ.On("ListPostsRows", mock.Anything, mock.Anything).Return(&fakeRows{values: [][]any{row}}, nil)
+	h := router.NewPostHandler(nil, mockQ)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/posts:export?format=csv", nil)
+	w := httptest.NewRecorder()
+
+	// When:
+	h.Export(r).Respond(w, r)
+
+	// Then: the file can be imported back
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `attachment; filename="posts.csv"`, w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "id,title,description,created_at,updated_at,slug\n"+
+		"550e8400-e29b-41d4-a716-446655440000,Post title,,2025-01-18T00:13:02Z,2025-01-18T00:13:02Z,post-title\n", w.Body.String())
+}
@@ -0,0 +1,523 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/pgnotify"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostPresenceChannel is the Postgres NOTIFY channel used to fan out presence between replicas
+const PostPresenceChannel = "post_presence"
+
+// Presence message types exchanged over the WebSocket
+const (
+	PresenceSnapshot   = "presence"    // server → client: everyone in the room and the held locks
+	PresenceJoin       = "join"        // server → client: someone started editing
+	PresenceLeave      = "leave"       // server → client: someone stopped editing
+	PresenceHeartbeat  = "heartbeat"   // client → server: still here
+	PresenceCursor     = "cursor"      // both ways: cursor position, opaque to the server
+	PresenceLock       = "lock"        // both ways: request or announce a field lock
+	PresenceUnlock     = "unlock"      // both ways: release or announce a field unlock
+	PresenceLockDenied = "lock_denied" // server → client: the field is held by someone else
+)
+
+// maxPresenceMessageSize bounds incoming messages so they fit in a NOTIFY payload
+const maxPresenceMessageSize = 4096
+
+// PresenceClient identifies someone editing a post
+type PresenceClient struct {
+	ID   string `json:"id"`
+	User string `json:"user"`
+}
+
+// PresenceMessage is the JSON message exchanged with WebSocket clients
+type PresenceMessage struct {
+	Type     string            `json:"type"`
+	ClientID string            `json:"client_id,omitempty"`
+	User     string            `json:"user,omitempty"`
+	Field    string            `json:"field,omitempty"`
+	Data     json.RawMessage   `json:"data,omitempty"`
+	Clients  []PresenceClient  `json:"clients,omitempty"`
+	Locks    map[string]string `json:"locks,omitempty"`
+}
+
+// presenceEnvelope wraps a message sent to other replicas
+type presenceEnvelope struct {
+	Origin  string          `json:"origin"`
+	PostID  uuid.UUID       `json:"post_id"`
+	Message PresenceMessage `json:"message"`
+}
+
+// presenceMember is someone in a room. Members connected to other
+// replicas have no send channel and expire unless they keep sending heartbeats.
+type presenceMember struct {
+	client   PresenceClient
+	lastSeen time.Time
+	send     chan []byte
+}
+
+// presenceRoom tracks the members and field locks of a single post
+type presenceRoom struct {
+	members map[string]*presenceMember
+	locks   map[string]string // field → client id
+}
+
+// postPresence tracks who is editing which post and relays their
+// cursor and lock events, across replicas through Postgres NOTIFY
+type postPresence struct {
+	db        models.DBTX
+	querier   models.Querier
+	instance  string
+	heartbeat time.Duration
+	upgrader  websocket.Upgrader
+
+	mu    sync.Mutex
+	rooms map[uuid.UUID]*presenceRoom
+}
+
+// NewPostPresence creates a presence hub. Clients are expected to send a
+// heartbeat every heartbeat interval and are dropped after missing a few.
+func NewPostPresence(db models.DBTX, q models.Querier, heartbeat time.Duration) *postPresence {
+	return &postPresence{
+		db:        db,
+		querier:   q,
+		instance:  uuid.NewString(),
+		heartbeat: heartbeat,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+		mu:    sync.Mutex{},
+		rooms: map[uuid.UUID]*presenceRoom{},
+	}
+}
+
+// Run expires members of other replicas that stopped sending heartbeats,
+// e.g. because the replica crashed. It blocks until the context is canceled.
+func (p *postPresence) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			p.expire(now.Add(-3 * p.heartbeat))
+		}
+	}
+}
+
+// expire removes remote members last seen before the deadline, and the rooms
+// left without members
+func (p *postPresence) expire(deadline time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for postID, room := range p.rooms {
+		for id, m := range room.members {
+			if m.send == nil && m.lastSeen.Before(deadline) {
+				p.apply(postID, room, PresenceMessage{Type: PresenceLeave, ClientID: id})
+			}
+		}
+		if len(room.members) == 0 {
+			delete(p.rooms, postID)
+		}
+	}
+}
+
+// Notify handles presence messages from other replicas
+func (p *postPresence) Notify(ctx context.Context, n *pgconn.Notification) {
+	var env presenceEnvelope
+	if err := json.Unmarshal([]byte(n.Payload), &env); err != nil {
+		slogr.FromContext(ctx).Error("[presence] invalid payload", slog.String("payload", n.Payload), slog.Any("err", err))
+		return
+	}
+	if env.Origin == p.instance {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	room, ok := p.rooms[env.PostID]
+	if !ok {
+		// only members open a room, a lock or edit of a client that was never
+		// seen joining would leave one without them
+		if env.Message.Type != PresenceJoin && env.Message.Type != PresenceHeartbeat {
+			return
+		}
+		room = &presenceRoom{members: map[string]*presenceMember{}, locks: map[string]string{}}
+		p.rooms[env.PostID] = room
+	}
+
+	p.apply(env.PostID, room, env.Message)
+}
+
+// apply updates the room state and delivers the message to local members.
+// Must hold p.mu.
+func (p *postPresence) apply(postID uuid.UUID, room *presenceRoom, msg PresenceMessage) {
+	switch msg.Type {
+	case PresenceJoin, PresenceHeartbeat:
+		m, ok := room.members[msg.ClientID]
+		if !ok {
+			m = &presenceMember{client: PresenceClient{ID: msg.ClientID, User: msg.User}, lastSeen: time.Time{}, send: nil}
+			room.members[msg.ClientID] = m
+			// a heartbeat from an unknown member means we missed the join
+			msg.Type = PresenceJoin
+		} else if msg.Type == PresenceHeartbeat {
+			m.lastSeen = time.Now()
+			return
+		}
+		m.lastSeen = time.Now()
+
+	case PresenceLeave:
+		if _, ok := room.members[msg.ClientID]; !ok {
+			return
+		}
+		delete(room.members, msg.ClientID)
+		for field, holder := range room.locks {
+			if holder == msg.ClientID {
+				delete(room.locks, field)
+			}
+		}
+		if len(room.members) == 0 {
+			delete(p.rooms, postID)
+		}
+
+	case PresenceLock:
+		// Replicas may grant the same field concurrently. Resolve the conflict
+		// the same way everywhere so that all replicas converge on one holder.
+		if holder, ok := room.locks[msg.Field]; ok && holder != msg.ClientID && holder < msg.ClientID {
+			msg.ClientID = holder
+		}
+		room.locks[msg.Field] = msg.ClientID
+
+	case PresenceUnlock:
+		if room.locks[msg.Field] != msg.ClientID {
+			return
+		}
+		delete(room.locks, msg.Field)
+	}
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	for id, m := range room.members {
+		if m.send == nil || id == msg.ClientID {
+			continue
+		}
+		select {
+		case m.send <- b:
+		default:
+			// too slow to keep up, the write loop closes the connection
+			close(m.send)
+			m.send = nil
+		}
+	}
+}
+
+// broadcast applies a message from a local client and relays it to the other replicas
+func (p *postPresence) broadcast(ctx context.Context, postID uuid.UUID, msg PresenceMessage) {
+	p.mu.Lock()
+	if room, ok := p.rooms[postID]; ok {
+		p.apply(postID, room, msg)
+	}
+	p.mu.Unlock()
+
+	p.relay(ctx, postID, msg)
+}
+
+// lock applies a field lock request of a local client, unless the field is
+// held by someone else, and relays it to the other replicas. It returns the
+// holder of the field once settled, checked and applied in a single step.
+func (p *postPresence) lock(ctx context.Context, postID uuid.UUID, msg PresenceMessage) string {
+	p.mu.Lock()
+	room, ok := p.rooms[postID]
+	if !ok {
+		p.mu.Unlock()
+		return ""
+	}
+	if holder, held := room.locks[msg.Field]; held && holder != msg.ClientID {
+		p.mu.Unlock()
+		return holder
+	}
+	p.apply(postID, room, msg)
+	holder := room.locks[msg.Field]
+	p.mu.Unlock()
+
+	p.relay(ctx, postID, msg)
+	return holder
+}
+
+// relay sends a message from a local client to the other replicas
+func (p *postPresence) relay(ctx context.Context, postID uuid.UUID, msg PresenceMessage) {
+	b, err := json.Marshal(presenceEnvelope{Origin: p.instance, PostID: postID, Message: msg})
+	if err != nil {
+		return
+	}
+	if err := pgnotify.Notify(ctx, p.db, PostPresenceChannel, string(b)); err != nil {
+		slogr.FromContext(ctx).Warn("[presence] failed to relay message", slog.Any("err", err))
+	}
+}
+
+// join adds a local member to the room and returns the room snapshot
+func (p *postPresence) join(postID uuid.UUID, client PresenceClient) (chan []byte, PresenceMessage) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	room, ok := p.rooms[postID]
+	if !ok {
+		room = &presenceRoom{members: map[string]*presenceMember{}, locks: map[string]string{}}
+		p.rooms[postID] = room
+	}
+
+	snapshot := PresenceMessage{
+		Type:     PresenceSnapshot,
+		ClientID: client.ID,
+		Clients:  make([]PresenceClient, 0, len(room.members)),
+		Locks:    make(map[string]string, len(room.locks)),
+	}
+	for _, m := range room.members {
+		snapshot.Clients = append(snapshot.Clients, m.client)
+	}
+	sort.Slice(snapshot.Clients, func(i, j int) bool { return snapshot.Clients[i].ID < snapshot.Clients[j].ID })
+	for field, holder := range room.locks {
+		snapshot.Locks[field] = holder
+	}
+
+	send := make(chan []byte, 32)
+	room.members[client.ID] = &presenceMember{client: client, lastSeen: time.Now(), send: send}
+
+	return send, snapshot
+}
+
+// Connect upgrades the request to a WebSocket and joins the presence room of
+// the post, identified by its ID or slug
+func (p *postPresence) Connect(r *http.Request) httphandler.Responder {
+	ctx := r.Context()
+
+	// the post of a slug exists, an ID is checked
+	param := chi.URLParam(r, "id")
+	id, err := resolvePostID(ctx, p.querier, p.db, param)
+	if err == nil && isPostID(param) {
+		_, err = p.querier.GetPost(ctx, p.db, id)
+	}
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return jsonresp.Error(err, "Post not found", http.StatusNotFound)
+		}
+		return jsonresp.InternalServerError(err)
+	}
+
+	user := r.URL.Query().Get("user")
+	if user == "" {
+		user = "anonymous"
+	}
+	if len(user) > 64 {
+		return jsonresp.Error(nil, "Invalid user", http.StatusBadRequest)
+	}
+
+	return &presenceResponder{
+		presence: p,
+		postID:   id,
+		client:   PresenceClient{ID: uuid.NewString(), User: user},
+	}
+}
+
+// presenceResponder serves a single WebSocket connection
+type presenceResponder struct {
+	presence *postPresence
+	postID   uuid.UUID
+	client   PresenceClient
+}
+
+// Respond performs the WebSocket handshake and serves the connection until it closes
+func (res *presenceResponder) Respond(w http.ResponseWriter, r *http.Request) {
+	p := res.presence
+	ctx := slogr.WithAttrs(r.Context(), slog.String("post-id", res.postID.String()))
+	logger := slogr.FromContext(ctx).With(
+		slog.String("post-id", res.postID.String()),
+		slog.String("client-id", res.client.ID),
+	)
+
+	conn, err := p.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader already replied with an error status
+		logger.Warn("[presence] upgrade failed", slog.Any("err", err))
+		return
+	}
+	defer conn.Close()
+
+	// The connection outlives the request, detach from its cancellation
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	send, snapshot := p.join(res.postID, res.client)
+	defer func() {
+		// ctx is canceled by the write loop once the client is gone, the
+		// other replicas must still be told to drop the member and its locks
+		leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		p.broadcast(leaveCtx, res.postID, PresenceMessage{Type: PresenceLeave, ClientID: res.client.ID})
+	}()
+
+	if err := conn.WriteJSON(snapshot); err != nil {
+		return
+	}
+	p.broadcast(ctx, res.postID, PresenceMessage{Type: PresenceJoin, ClientID: res.client.ID, User: res.client.User})
+
+	go res.writeLoop(ctx, cancel, conn, send, logger)
+
+	res.readLoop(ctx, conn, logger)
+}
+
+// readLoop handles client messages until the connection fails or goes quiet
+func (res *presenceResponder) readLoop(ctx context.Context, conn *websocket.Conn, logger *slog.Logger) {
+	p := res.presence
+	timeout := 3 * p.heartbeat
+
+	conn.SetReadLimit(maxPresenceMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(timeout))
+	})
+
+	for {
+		var msg PresenceMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) &&
+				!errors.Is(err, context.Canceled) {
+				logger.Debug("[presence] connection closed", slog.Any("err", err))
+			}
+			return
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(timeout))
+
+		if err := res.handle(ctx, msg); err != nil {
+			logger.Warn("[presence] failed to handle message", slog.Any("err", err))
+			return
+		}
+	}
+}
+
+// handle processes a single client message
+func (res *presenceResponder) handle(ctx context.Context, msg PresenceMessage) (err error) {
+	// keep a misbehaving message from taking down the connection handler,
+	// the Recoverer middleware cannot respond on a hijacked connection
+	defer func() {
+		if r := recover(); r != nil {
+			err = errPresencePanic
+		}
+	}()
+
+	p := res.presence
+
+	// clients cannot impersonate each other
+	msg.ClientID = res.client.ID
+	msg.User = res.client.User
+	msg.Clients = nil
+	msg.Locks = nil
+
+	switch msg.Type {
+	case PresenceHeartbeat, PresenceCursor, PresenceUnlock:
+		p.broadcast(ctx, res.postID, msg)
+
+	case PresenceLock:
+		// confirm to the requester only once the holder is settled, others
+		// are told by the broadcast of the lock
+		reply := msg
+		if holder := p.lock(ctx, res.postID, msg); holder != res.client.ID {
+			reply = PresenceMessage{Type: PresenceLockDenied, ClientID: holder, Field: msg.Field}
+		}
+		b, _ := json.Marshal(reply)
+		res.reply(b)
+
+	default:
+		b, _ := json.Marshal(PresenceMessage{Type: "error", Data: json.RawMessage(`"unknown message type"`)})
+		res.reply(b)
+	}
+
+	return nil
+}
+
+// reply sends a message to this client only
+func (res *presenceResponder) reply(b []byte) {
+	p := res.presence
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if room, ok := p.rooms[res.postID]; ok {
+		if m, ok := room.members[res.client.ID]; ok && m.send != nil {
+			select {
+			case m.send <- b:
+			default:
+			}
+		}
+	}
+}
+
+// writeLoop writes queued messages and keep-alive pings to the client
+func (res *presenceResponder) writeLoop(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	conn *websocket.Conn,
+	send chan []byte,
+	logger *slog.Logger,
+) {
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("[presence] write loop panic", slog.Any("panic", r))
+		}
+		// unblock the read loop
+		_ = conn.Close()
+	}()
+
+	ticker := time.NewTicker(res.presence.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case b, ok := <-send:
+			if !ok {
+				_ = conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"),
+					time.Now().Add(time.Second))
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, b); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// errPresencePanic is returned when handling a message panicked
+var errPresencePanic = errors.New("panic while handling presence message")
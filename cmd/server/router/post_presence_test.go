@@ -0,0 +1,184 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// notifyDB accepts the pg_notify calls used to relay presence to other replicas
+type notifyDB struct {
+	models.DBTX
+}
+
+func (notifyDB) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, nil
+}
+
+func Test_PostPresence_Connect(t *testing.T) {
+	t.Parallel()
+
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	missingUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	slugUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440002")
+	strayUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440003")
+
+	// Given:
+	mockQ := &mocks.Querier{}
+	mockQ.On("GetPost", mock.Anything, mock.Anything, fixedUUID).Return(models.Post{ID: fixedUUID}, nil)
+	mockQ.On("GetPost", mock.Anything, mock.Anything, missingUUID).Return(models.Post{}, pgx.ErrNoRows)
+	mockQ.On("GetPost", mock.Anything, mock.Anything, strayUUID).Return(models.Post{ID: strayUUID}, nil)
+	mockQ.On("GetPostBySlug", mock.Anything, mock.Anything, "post-title").Return(models.Post{ID: slugUUID}, nil)
+	mockQ.On("GetPostBySlug", mock.Anything, mock.Anything, "unknown").Return(models.Post{}, pgx.ErrNoRows)
+
+	pp := router.NewPostPresence(notifyDB{}, mockQ, time.Minute)
+	r := chi.NewRouter()
+	r.Get("/api/v1/posts/{id}/ws", httphandler.Handle(pp.Connect))
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(srv.URL, "http") + "/api/v1/posts/"
+
+	dial := func(t *testing.T, user string) *websocket.Conn {
+		t.Helper()
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+fixedUUID.String()+"/ws?user="+user, nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		return conn
+	}
+	read := func(t *testing.T, conn *websocket.Conn) router.PresenceMessage {
+		t.Helper()
+		var msg router.PresenceMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		return msg
+	}
+
+	t.Run("post not found", func(t *testing.T) {
+		for _, id := range []string{missingUUID.String(), "unknown"} {
+			_, resp, err := websocket.DefaultDialer.Dial(wsURL+id+"/ws", nil)
+			require.ErrorIs(t, err, websocket.ErrBadHandshake)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		}
+	})
+
+	t.Run("post slug", func(t *testing.T) {
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+"post-title/ws?user=carol", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		resp.Body.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		snapshot := read(t, conn)
+		assert.Equal(t, router.PresenceSnapshot, snapshot.Type)
+	})
+
+	t.Run("remote lock of a client never seen joining", func(t *testing.T) {
+		// Given: a lock relayed by another replica for a post without a room
+		pp.Notify(context.Background(), &pgconn.Notification{
+			Channel: router.PostPresenceChannel,
+			Payload: `{"origin":"other","post_id":"` + strayUUID.String() + `","message":{"type":"lock","client_id":"dave-1","field":"title"}}`,
+		})
+
+		// When:
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL+strayUUID.String()+"/ws?user=erin", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+		resp.Body.Close()
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+		// Then: no room was left holding the lock
+		snapshot := read(t, conn)
+		assert.Empty(t, snapshot.Clients)
+		assert.Empty(t, snapshot.Locks)
+	})
+
+	t.Run("presence, cursor and locks", func(t *testing.T) {
+		// When: alice joins an empty room
+		alice := dial(t, "alice")
+		defer alice.Close()
+		snapshot := read(t, alice)
+
+		// Then:
+		assert.Equal(t, router.PresenceSnapshot, snapshot.Type)
+		assert.Empty(t, snapshot.Clients)
+		aliceID := snapshot.ClientID
+
+		// When: bob joins
+		bob := dial(t, "bob")
+		defer bob.Close()
+		snapshot = read(t, bob)
+
+		// Then: bob sees alice, alice is told about bob
+		assert.Equal(t, []router.PresenceClient{{ID: aliceID, User: "alice"}}, snapshot.Clients)
+		bobID := snapshot.ClientID
+		joined := read(t, alice)
+		assert.Equal(t, router.PresenceJoin, joined.Type)
+		assert.Equal(t, bobID, joined.ClientID)
+		assert.Equal(t, "bob", joined.User)
+
+		// When: alice locks the title
+		require.NoError(t, alice.WriteJSON(router.PresenceMessage{Type: router.PresenceLock, Field: "title"}))
+
+		// Then: alice is confirmed and bob is told
+		assert.Equal(t, router.PresenceLock, read(t, alice).Type)
+		locked := read(t, bob)
+		assert.Equal(t, router.PresenceLock, locked.Type)
+		assert.Equal(t, aliceID, locked.ClientID)
+		assert.Equal(t, "title", locked.Field)
+
+		// When: bob tries to lock the title as well
+		require.NoError(t, bob.WriteJSON(router.PresenceMessage{Type: router.PresenceLock, Field: "title"}))
+
+		// Then:
+		denied := read(t, bob)
+		assert.Equal(t, router.PresenceLockDenied, denied.Type)
+		assert.Equal(t, aliceID, denied.ClientID)
+
+		// When: alice moves her cursor
+		require.NoError(t, alice.WriteJSON(router.PresenceMessage{Type: router.PresenceCursor, Data: []byte(`{"pos":42}`)}))
+
+		// Then:
+		cursor := read(t, bob)
+		assert.Equal(t, router.PresenceCursor, cursor.Type)
+		assert.Equal(t, aliceID, cursor.ClientID)
+		assert.JSONEq(t, `{"pos":42}`, string(cursor.Data))
+
+		// When: someone joins through another replica
+		pp.Notify(context.Background(), &pgconn.Notification{
+			Channel: router.PostPresenceChannel,
+			Payload: `{"origin":"other","post_id":"` + fixedUUID.String() + `","message":{"type":"join","client_id":"carol-1","user":"carol"}}`,
+		})
+
+		// Then:
+		remote := read(t, bob)
+		assert.Equal(t, router.PresenceJoin, remote.Type)
+		assert.Equal(t, "carol", remote.User)
+
+		// When: alice leaves
+		require.NoError(t, alice.Close())
+
+		// Then: bob is told
+		left := read(t, bob)
+		assert.Equal(t, router.PresenceLeave, left.Type)
+		assert.Equal(t, aliceID, left.ClientID)
+	})
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/slug"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	postSlugFallback   = "post"          // Slug of titles without any letter or digit
+	postSlugConstraint = "post_slug_key" // Unique constraint of post slugs
+	maxSlugAttempts    = 3               // Saves of a post whose slug was taken concurrently
+	uniqueViolation    = "23505"         // SQLSTATE of unique constraint violations
+)
+
+// postSlugs allocates unique slugs to posts, among the current and former
+// slugs of every post
+type postSlugs struct {
+	owners map[string]uuid.UUID
+}
+
+// loadPostSlugs loads the slugs that the slugs of titles may collide with
+func loadPostSlugs(ctx context.Context, q models.Querier, db models.DBTX, titles []string) (*postSlugs, error) {
+	seen := make(map[string]bool, len(titles))
+	patterns := make([]string, 0, 2*len(titles))
+	for _, title := range titles {
+		base := postSlugBase(title)
+		if seen[base] {
+			continue
+		}
+		seen[base] = true
+		patterns = append(patterns, base, base+"-%")
+	}
+
+	rows, err := q.ListPostSlugs(ctx, db, patterns)
+	if err != nil {
+		return nil, fmt.Errorf("q.ListPostSlugs: %w", err)
+	}
+	owners := make(map[string]uuid.UUID, len(rows))
+	for _, row := range rows {
+		owners[row.Slug] = row.PostID
+	}
+	return &postSlugs{owners: owners}, nil
+}
+
+// allocate returns the slug of the post id titled title and reserves it. A
+// post keeps its current slug, and takes back its former ones.
+func (s *postSlugs) allocate(id uuid.UUID, title string) string {
+	v := slug.Unique(postSlugBase(title), func(candidate string) bool {
+		owner, ok := s.owners[candidate]
+		return !ok || owner == id
+	})
+	s.owners[v] = id
+	return v
+}
+
+// PostSlug returns a free slug for the post id titled title, e.g.
+// "hello-world", or "hello-world-2" when another post has or had it
+func PostSlug(ctx context.Context, q models.Querier, db models.DBTX, id uuid.UUID, title string) (string, error) {
+	slugs, err := loadPostSlugs(ctx, q, db, []string{title})
+	if err != nil {
+		return "", err
+	}
+	return slugs.allocate(id, title), nil
+}
+
+// savePostWithSlug saves the post id titled title with save, given a free
+// slug. A slug taken by a concurrent save in the meantime is allocated again.
+func savePostWithSlug(
+	ctx context.Context, q models.Querier, db models.DBTX, id uuid.UUID, title string,
+	save func(slug string) (models.Post, error),
+) (models.Post, error) {
+	for attempt := 1; ; attempt++ {
+		s, err := PostSlug(ctx, q, db, id, title)
+		if err != nil {
+			return models.Post{}, err
+		}
+		post, err := save(s)
+		if isSlugConflict(err) && attempt < maxSlugAttempts {
+			continue
+		}
+		return post, err
+	}
+}
+
+// resolvePostID returns the ID of the post identified by the {id} route
+// param, an ID or a current slug, without checking that a post has the ID
+func resolvePostID(ctx context.Context, q models.Querier, db models.DBTX, param string) (uuid.UUID, error) {
+	if id, err := uuid.Parse(param); err == nil {
+		return id, nil
+	}
+	post, err := q.GetPostBySlug(ctx, db, param)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("q.GetPostBySlug: %w", err)
+	}
+	return post.ID, nil
+}
+
+// isPostID reports whether the {id} route param is an ID rather than a slug
+func isPostID(param string) bool {
+	_, err := uuid.Parse(param)
+	return err == nil
+}
+
+// reservedPostSlugs are the static routes matched before {id}, e.g. /api/v1/posts/stream
+var reservedPostSlugs = map[string]bool{"stream": true}
+
+// postSlugBase returns the slug of title before collision suffixes. Slugs
+// that are UUIDs or reserved are prefixed, as {id} route params are IDs
+// first and static routes are matched before them.
+func postSlugBase(title string) string {
+	base := slug.Make(title)
+	if base == "" {
+		return postSlugFallback
+	}
+	if _, err := uuid.Parse(base); err == nil || reservedPostSlugs[base] {
+		return postSlugFallback + "-" + base
+	}
+	return base
+}
+
+// isSlugConflict reports whether err is a violation of the uniqueness of post slugs
+func isSlugConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation && pgErr.ConstraintName == postSlugConstraint
+}
@@ -0,0 +1,54 @@
+package router_test
+
+import (
+	"context"
+	"testing"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_PostSlug(t *testing.T) {
+	t.Parallel()
+
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	otherUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+
+	testCases := []struct {
+		desc  string
+		title string
+		taken []models.ListPostSlugsRow
+		want  string
+	}{
+		{desc: "title", title: "Hello, World!", want: "hello-world"},
+		{desc: "no letter or digit", title: "?!", want: "post"},
+		{desc: "taken by another post", title: "Hello World", taken: []models.ListPostSlugsRow{{Slug: "hello-world", PostID: otherUUID}}, want: "hello-world-2"},
+		{desc: "kept by the post", title: "Hello World", taken: []models.ListPostSlugsRow{{Slug: "hello-world", PostID: fixedUUID}}, want: "hello-world"},
+		{desc: "uuid", title: otherUUID.String(), want: "post-" + otherUUID.String()},
+		{desc: "static route", title: "Stream", want: "post-stream"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			mockQ := &mocks.Querier{}
+			mockQ.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+				Return(append([]models.ListPostSlugsRow{}, tc.taken...), nil)
+
+			// When:
+			got, err := router.PostSlug(context.Background(), mockQ, nil, fixedUUID, tc.title)
+
+			// Then:
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
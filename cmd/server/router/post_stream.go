@@ -0,0 +1,357 @@
+package router
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// PostChangesChannel is the Postgres NOTIFY channel fired by the post_notify_change trigger
+const PostChangesChannel = "post_changes"
+
+// Post event types sent as the SSE "event" field
+const (
+	PostEventCreated = "created"
+	PostEventUpdated = "updated"
+	PostEventDeleted = "deleted"
+
+	// postEventReset tells the client that events were missed and it should refetch
+	postEventReset = "reset"
+)
+
+// postOpEvents maps the trigger operation to the SSE event type
+var postOpEvents = map[string]string{
+	"insert": PostEventCreated,
+	"update": PostEventUpdated,
+	"delete": PostEventDeleted,
+}
+
+// postEvent is a single change to a post
+type postEvent struct {
+	id   int64
+	kind string
+	data []byte
+}
+
+// postLoadQueueSize bounds the changes waiting for their post to be loaded
+const postLoadQueueSize = 256
+
+// postChangePayload is the JSON payload sent by the post_notify_change trigger.
+// Post is the created or updated post, unless too large for the payload.
+type postChangePayload struct {
+	ID     int64        `json:"id"`
+	Op     string       `json:"op"`
+	PostID uuid.UUID    `json:"post_id"`
+	Post   *models.Post `json:"post"`
+}
+
+// postStream fans out post changes received through LISTEN/NOTIFY to SSE clients.
+// The last events are kept in a bounded buffer so clients can resume with Last-Event-ID.
+type postStream struct {
+	db        models.DBTX
+	querier   models.Querier
+	heartbeat time.Duration
+	loads     chan postChangePayload // Changes published by Run, in order, while a post is loaded
+	pending   atomic.Int64           // Changes sent to loads and not yet published
+
+	mu     sync.Mutex
+	buffer []postEvent // oldest first, at most bufferSize events
+	size   int
+	subs   map[chan postEvent]struct{}
+}
+
+// NewPostStream creates a post stream that keeps the last bufferSize events
+// and sends a heartbeat comment to idle clients every heartbeat interval
+func NewPostStream(db models.DBTX, q models.Querier, bufferSize int, heartbeat time.Duration) *postStream {
+	return &postStream{
+		db:        db,
+		querier:   q,
+		heartbeat: heartbeat,
+		loads:     make(chan postChangePayload, postLoadQueueSize),
+		pending:   atomic.Int64{},
+		mu:        sync.Mutex{},
+		buffer:    make([]postEvent, 0, bufferSize),
+		size:      bufferSize,
+		subs:      map[chan postEvent]struct{}{},
+	}
+}
+
+// Run publishes the changes queued by Notify, loading the posts of the
+// changes sent without them, until ctx is done
+func (s *postStream) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-s.loads:
+			s.load(ctx, payload)
+			s.pending.Add(-1)
+		}
+	}
+}
+
+// Notify handles a notification from the post_changes channel, on the
+// listener goroutine shared with presence, so it never queries the database.
+// Changes sent without their post are handed to Run, and so are the changes
+// that follow them until they are published, to keep events in order. Clients
+// are reset when too many changes are waiting.
+func (s *postStream) Notify(ctx context.Context, n *pgconn.Notification) {
+	logger := slogr.FromContext(ctx)
+
+	var payload postChangePayload
+	if err := json.Unmarshal([]byte(n.Payload), &payload); err != nil {
+		logger.Error("[post-stream] invalid payload", slog.String("payload", n.Payload), slog.Any("err", err))
+		return
+	}
+
+	kind, ok := postOpEvents[payload.Op]
+	if !ok {
+		logger.Error("[post-stream] unknown operation", slog.String("op", payload.Op))
+		return
+	}
+
+	if (kind != PostEventDeleted && payload.Post == nil) || s.pending.Load() > 0 {
+		s.pending.Add(1)
+		select {
+		case s.loads <- payload:
+		default:
+			s.pending.Add(-1)
+			logger.Warn("[post-stream] load queue full, resetting clients", slog.Int64("event-id", payload.ID))
+			s.Reset(ctx)
+		}
+		return
+	}
+
+	s.publishChange(ctx, payload)
+}
+
+// load publishes a change queued by Notify, once its post is loaded. Clients
+// are reset when the post cannot be loaded, as they would miss the change.
+func (s *postStream) load(ctx context.Context, payload postChangePayload) {
+	if payload.Post == nil && postOpEvents[payload.Op] != PostEventDeleted {
+		post, err := s.querier.GetPost(ctx, s.db, payload.PostID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// deleted in the meantime, the delete event follows
+			return
+		}
+		if err != nil {
+			slogr.FromContext(ctx).Error("[post-stream] failed to load post, resetting clients",
+				slog.Int64("event-id", payload.ID), slog.Any("err", err))
+			s.Reset(ctx)
+			return
+		}
+		payload.Post = &post
+	}
+
+	s.publishChange(ctx, payload)
+}
+
+// publishChange publishes a change: the post when created or updated, its ID when deleted
+func (s *postStream) publishChange(ctx context.Context, payload postChangePayload) {
+	kind := postOpEvents[payload.Op]
+	var data any = map[string]uuid.UUID{"id": payload.PostID}
+	if kind != PostEventDeleted {
+		data = payload.Post
+	}
+
+	s.publishData(ctx, payload.ID, kind, data)
+}
+
+// publishData encodes the data of an event and publishes it
+func (s *postStream) publishData(ctx context.Context, id int64, kind string, data any) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		slogr.FromContext(ctx).Error("[post-stream] failed to encode event", slog.Any("err", err))
+		return
+	}
+
+	s.publish(postEvent{id: id, kind: kind, data: b})
+}
+
+// Reset tells every connected client that events may have been missed,
+// e.g. after the LISTEN connection was re-established
+func (s *postStream) Reset(_ context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buffer = s.buffer[:0]
+	for ch := range s.subs {
+		s.send(ch, postEvent{id: 0, kind: postEventReset, data: []byte("{}")})
+	}
+}
+
+// publish appends the event to the buffer and sends it to every subscriber
+func (s *postStream) publish(e postEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buffer) == s.size {
+		copy(s.buffer, s.buffer[1:])
+		s.buffer = s.buffer[:len(s.buffer)-1]
+	}
+	s.buffer = append(s.buffer, e)
+
+	for ch := range s.subs {
+		s.send(ch, e)
+	}
+}
+
+// send delivers an event without blocking. Slow subscribers are disconnected
+// and are expected to reconnect with Last-Event-ID. Must hold s.mu.
+func (s *postStream) send(ch chan postEvent, e postEvent) {
+	select {
+	case ch <- e:
+	default:
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// subscribe registers a new client and returns the events it missed since lastEventID.
+// reset is true when lastEventID is no longer in the buffer.
+func (s *postStream) subscribe(lastEventID string) (ch chan postEvent, backlog []postEvent, reset bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ch = make(chan postEvent, 64)
+	s.subs[ch] = struct{}{}
+
+	if lastEventID == "" {
+		return ch, nil, false
+	}
+
+	id, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil {
+		return ch, nil, true
+	}
+
+	// match by position rather than by value since concurrent transactions
+	// may commit, and therefore notify, out of sequence order
+	for i, e := range s.buffer {
+		if e.id == id {
+			return ch, append([]postEvent(nil), s.buffer[i+1:]...), false
+		}
+	}
+
+	return ch, nil, true
+}
+
+// unsubscribe removes the client if it was not already disconnected
+func (s *postStream) unsubscribe(ch chan postEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.subs[ch]; ok {
+		delete(s.subs, ch)
+		close(ch)
+	}
+}
+
+// Stream sends post changes as Server-Sent Events until the client disconnects
+func (s *postStream) Stream(r *http.Request) httphandler.Responder {
+	ch, backlog, reset := s.subscribe(r.Header.Get("Last-Event-ID"))
+	if reset {
+		backlog = []postEvent{{id: 0, kind: postEventReset, data: []byte("{}")}}
+	}
+
+	return &sseResponder{
+		stream:    s,
+		ch:        ch,
+		backlog:   backlog,
+		heartbeat: s.heartbeat,
+	}
+}
+
+// sseResponder writes a text/event-stream response
+type sseResponder struct {
+	stream    *postStream
+	ch        chan postEvent
+	backlog   []postEvent
+	heartbeat time.Duration
+}
+
+// Respond streams events until the client goes away or the subscription is dropped
+func (res *sseResponder) Respond(w http.ResponseWriter, r *http.Request) {
+	defer res.stream.unsubscribe(res.ch)
+
+	ctx := r.Context()
+	logger := slogr.FromContext(ctx)
+
+	// The server read and write timeouts are meant for regular requests,
+	// lift them for the lifetime of the stream
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", 3000); err != nil {
+		return
+	}
+	for _, e := range res.backlog {
+		if err := writeSSE(w, e); err != nil {
+			return
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		logger.Error("[post-stream] streaming unsupported", slog.Any("err", err))
+		return
+	}
+
+	ticker := time.NewTicker(res.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case e, ok := <-res.ch:
+			if !ok {
+				// dropped for being too slow, the client reconnects and resumes
+				return
+			}
+			if err := writeSSE(w, e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeSSE writes a single event in the text/event-stream format
+func writeSSE(w http.ResponseWriter, e postEvent) error {
+	if e.id != 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", e.id); err != nil {
+			return err //nolint:wrapcheck // client went away
+		}
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.kind, e.data); err != nil {
+		return err //nolint:wrapcheck // client went away
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func Test_PostStream_Stream(t *testing.T) {
+	t.Parallel()
+
+	post := `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description",` +
+		`"created_at":"2025-01-18T00:13:02+00:00","updated_at":"2025-01-18T00:13:02.000000+00:00","slug":"post-title"}`
+	notifications := []string{
+		`{"id":1,"op":"insert","post_id":"550e8400-e29b-41d4-a716-446655440000","post":` + post + `}`,
+		`{"id":2,"op":"update","post_id":"550e8400-e29b-41d4-a716-446655440000","post":` + post + `}`,
+		`{"id":3,"op":"delete","post_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+	}
+
+	testCases := []struct {
+		desc        string
+		lastEventID string
+		live        bool
+		wantBody    string
+	}{
+		{
+			desc:        "new client receives nothing from the buffer",
+			lastEventID: "",
+			wantBody:    "retry: 3000\n\n",
+		},
+		{
+			desc:        "resume from buffer",
+			lastEventID: "2",
+			wantBody: "retry: 3000\n\n" +
+				"id: 3\nevent: deleted\ndata: {\"id\":\"550e8400-e29b-41d4-a716-446655440000\"}\n\n",
+		},
+		{
+			desc:        "resume from an event no longer buffered",
+			lastEventID: "1",
+			wantBody:    "retry: 3000\n\nevent: reset\ndata: {}\n\n",
+		},
+		{
+			desc:        "live event",
+			lastEventID: "",
+			live:        true,
+			wantBody: "retry: 3000\n\n" +
+				"id: 4\nevent: updated\ndata: {\"id\":\"550e8400-e29b-41d4-a716-446655440000\",\"title\":\"Post title\",\"description\":\"Post description\",\"created_at\":\"2025-01-18T00:13:02Z\",\"updated_at\":\"2025-01-18T00:13:02Z\",\"slug\":\"post-title\"}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given: posts sent in the payloads, no query is expected
+			mockQ := &mocks.Querier{}
+			ps := router.NewPostStream(nil, mockQ, 2, time.Hour)
+			for _, payload := range notifications {
+				ps.Notify(context.Background(), &pgconn.Notification{Channel: router.PostChangesChannel, Payload: payload})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/posts/stream", nil)
+			if tc.lastEventID != "" {
+				r.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+			w := httptest.NewRecorder()
+
+			// When:
+			resp := ps.Stream(r)
+			if tc.live {
+				ps.Notify(context.Background(), &pgconn.Notification{
+					Channel: router.PostChangesChannel,
+					Payload: `{"id":4,"op":"update","post_id":"550e8400-e29b-41d4-a716-446655440000","post":` + post + `}`,
+				})
+			}
+			resp.Respond(w, r)
+
+			// Then:
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+			assert.Equal(t, tc.wantBody, w.Body.String())
+			mockQ.AssertExpectations(t)
+		})
+	}
+}
+
+func Test_PostStream_LoadLargePost(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	post := models.Post{
+		ID:          fixedUUID,
+		Title:       "Post title",
+		Description: ptr.Ref("Post description"),
+		CreatedAt:   fixedTime,
+		UpdatedAt:   fixedTime,
+		Slug:        "post-title",
+	}
+
+	testCases := []struct {
+		desc     string
+		mockFunc func(*mocks.Querier)
+		wantBody string
+	}{
+		{
+			desc: "loaded",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPost", mock.Anything, mock.Anything, fixedUUID).After(20*time.Millisecond).Return(post, nil)
+			},
+			wantBody: "retry: 3000\n\n" +
+				"id: 1\nevent: created\ndata: {\"id\":\"550e8400-e29b-41d4-a716-446655440000\",\"title\":\"Post title\",\"description\":\"Post description\",\"created_at\":\"2025-01-18T00:13:02Z\",\"updated_at\":\"2025-01-18T00:13:02Z\",\"slug\":\"post-title\"}\n\n" +
+				"id: 2\nevent: deleted\ndata: {\"id\":\"550e8400-e29b-41d4-a716-446655440000\"}\n\n",
+		},
+		{
+			desc: "failed to load",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPost", mock.Anything, mock.Anything, fixedUUID).Return(models.Post{}, errors.New("db error"))
+			},
+			wantBody: "retry: 3000\n\n" +
+				"event: reset\ndata: {}\n\n" +
+				"id: 2\nevent: deleted\ndata: {\"id\":\"550e8400-e29b-41d4-a716-446655440000\"}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given: a change sent without its post, too large for the payload
+			mockQ := &mocks.Querier{}
+			tc.mockFunc(mockQ)
+			ps := router.NewPostStream(nil, mockQ, 2, time.Hour)
+			go ps.Run(t.Context())
+
+			ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+			defer cancel()
+			r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/api/v1/posts/stream", nil)
+			w := httptest.NewRecorder()
+			resp := ps.Stream(r)
+
+			// When: followed by a change sent in full
+			ps.Notify(t.Context(), &pgconn.Notification{
+				Channel: router.PostChangesChannel,
+				Payload: `{"id":1,"op":"insert","post_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			})
+			ps.Notify(t.Context(), &pgconn.Notification{
+				Channel: router.PostChangesChannel,
+				Payload: `{"id":2,"op":"delete","post_id":"550e8400-e29b-41d4-a716-446655440000"}`,
+			})
+			resp.Respond(w, r)
+
+			// Then: the post is loaded off the listener goroutine, and events keep their order
+			assert.Equal(t, tc.wantBody, w.Body.String())
+			mockQ.AssertExpectations(t)
+		})
+	}
+}
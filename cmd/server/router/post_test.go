@@ -18,6 +18,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,16 @@ func Test_PostHandler_Create(t *testing.T) {
 
 	fixedTime := time.Date(2025, 1, 17, 23, 51, 43, 0, time.UTC)
 	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	otherUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	post := models.Post{
+		ID:          fixedUUID,
+		Title:       "Post title",
+		Description: ptr.Ref("Post description"),
+		CreatedAt:   fixedTime,
+		UpdatedAt:   fixedTime,
+		Slug:        "post-title-2",
+	}
+	postJSON := `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-17T23:51:43Z","updated_at":"2025-01-17T23:51:43Z","slug":"post-title-2"}`
 
 	testCases := []struct {
 		desc       string
@@ -38,23 +49,41 @@ func Test_PostHandler_Create(t *testing.T) {
 		{
 			desc: "success",
 			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, []string{"post-title", "post-title-%"}).
+					Return([]models.ListPostSlugsRow{{Slug: "post-title", PostID: otherUUID}}, nil)
 				m.On("CreatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.CreatePostParams) bool {
-					return p.Title == "Post title" && ptr.SameValue(p.Description, ptr.Ref("Post description"))
+					return p.Title == "Post title" && ptr.SameValue(p.Description, ptr.Ref("Post description")) &&
+						p.Slug == "post-title-2"
 				})).
-					Return(models.Post{
-						ID:          fixedUUID,
-						Title:       "Post title",
-						Description: ptr.Ref("Post description"),
-						CreatedAt:   fixedTime,
-						UpdatedAt:   fixedTime,
-					}, nil)
+					Return(post, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   postJSON,
+		},
+		{
+			desc: "slug taken concurrently",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{}, nil).Once()
+				m.On("CreatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.CreatePostParams) bool {
+					return p.Slug == "post-title"
+				})).
+					Return(models.Post{}, &pgconn.PgError{Code: "23505", ConstraintName: "post_slug_key"}).Once()
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{{Slug: "post-title", PostID: otherUUID}}, nil).Once()
+				m.On("CreatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.CreatePostParams) bool {
+					return p.Slug == "post-title-2"
+				})).
+					Return(post, nil).Once()
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-17T23:51:43Z","updated_at":"2025-01-17T23:51:43Z"}`,
+			wantBody:   postJSON,
 		},
 		{
 			desc: "fail",
 			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{}, nil)
 				m.On("CreatePost", mock.Anything, mock.Anything, mock.Anything).
 					Return(models.Post{}, errors.New("db error"))
 			},
@@ -88,6 +117,7 @@ func Test_PostHandler_Create(t *testing.T) {
 			// Then:
 			assert.Equal(t, tc.wantStatus, got.StatusCode)
 			assert.JSONEq(t, tc.wantBody, string(gotBodyBytes))
+			mockQ.AssertExpectations(t)
 		})
 	}
 }
@@ -114,10 +144,11 @@ func Test_PostHandler_List(t *testing.T) {
 						Description: ptr.Ref("Post description"),
 						CreatedAt:   fixedTime,
 						UpdatedAt:   fixedTime,
+						Slug:        "post-title",
 					}}, nil)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   `[{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z"}]`,
+			wantBody:   `[{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"post-title"}]`,
 		},
 		{
 			desc: "success | no results",
@@ -170,6 +201,15 @@ func Test_PostHandler_Get(t *testing.T) {
 
 	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
 	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	post := models.Post{
+		ID:          fixedUUID,
+		Title:       "Post title",
+		Description: ptr.Ref("Post description"),
+		CreatedAt:   fixedTime,
+		UpdatedAt:   fixedTime,
+		Slug:        "post-title",
+	}
+	postJSON := `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"post-title"}`
 
 	testCases := []struct {
 		desc       string
@@ -182,17 +222,19 @@ func Test_PostHandler_Get(t *testing.T) {
 			desc:  "success",
 			given: fixedUUID.String(),
 			mockFunc: func(m *mocks.Querier) {
-				m.On("GetPost", mock.Anything, mock.Anything, fixedUUID).
-					Return(models.Post{
-						ID:          fixedUUID,
-						Title:       "Post title",
-						Description: ptr.Ref("Post description"),
-						CreatedAt:   fixedTime,
-						UpdatedAt:   fixedTime,
-					}, nil)
+				m.On("GetPost", mock.Anything, mock.Anything, fixedUUID).Return(post, nil)
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Post title","description":"Post description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z"}`,
+			wantBody:   postJSON,
+		},
+		{
+			desc:  "success | slug",
+			given: "post-title",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "post-title").Return(post, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   postJSON,
 		},
 		{
 			desc: "not found",
@@ -205,11 +247,16 @@ func Test_PostHandler_Get(t *testing.T) {
 			wantBody:   `{"error":"Post not found"}`,
 		},
 		{
-			desc:       "invalid uuid",
-			mockFunc:   func(m *mocks.Querier) {},
-			given:      "invalid-uuid",
-			wantStatus: http.StatusBadRequest,
-			wantBody:   `{"error":"Invalid ID format"}`,
+			desc: "slug not found",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "unknown").
+					Return(models.Post{}, pgx.ErrNoRows)
+				m.On("GetCurrentPostSlug", mock.Anything, mock.Anything, "unknown").
+					Return("", pgx.ErrNoRows)
+			},
+			given:      "unknown",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"Post not found"}`,
 		},
 		{
 			desc: "db error",
@@ -258,6 +305,16 @@ func Test_PostHandler_Update(t *testing.T) {
 
 	fixedTime := time.Date(2025, 1, 18, 0, 13, 2, 0, time.UTC)
 	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+	otherUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	updated := models.Post{
+		ID:          fixedUUID,
+		Title:       "Updated title",
+		Description: ptr.Ref("Updated description"),
+		CreatedAt:   fixedTime,
+		UpdatedAt:   fixedTime,
+		Slug:        "updated-title-2",
+	}
+	updatedJSON := `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Updated title","description":"Updated description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z","slug":"updated-title-2"}`
 
 	testCases := []struct {
 		desc       string
@@ -271,37 +328,61 @@ func Test_PostHandler_Update(t *testing.T) {
 			desc:  "success",
 			given: fixedUUID.String(),
 			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, []string{"updated-title", "updated-title-%"}).
+					Return([]models.ListPostSlugsRow{{Slug: "updated-title", PostID: otherUUID}}, nil)
 				m.On("UpdatePost", mock.Anything, mock.Anything, models.UpdatePostParams{
 					ID:          fixedUUID,
 					Title:       "Updated title",
 					Description: ptr.Ref("Updated description"),
-				}).Return(models.Post{
-					ID:          fixedUUID,
-					Title:       "Updated title",
-					Description: ptr.Ref("Updated description"),
-					CreatedAt:   fixedTime,
-					UpdatedAt:   fixedTime,
-				}, nil)
+					Slug:        "updated-title-2",
+				}).Return(updated, nil)
+			},
+			input: router.UpdatePostParams{
+				Title:       "Updated title",
+				Description: ptr.Ref("Updated description"),
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   updatedJSON,
+		},
+		{
+			desc:  "success | slug",
+			given: "post-title",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "post-title").
+					Return(models.Post{ID: fixedUUID}, nil) //nolint:exhaustruct // Only the ID is read
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{
+						{Slug: "updated-title", PostID: otherUUID},
+						{Slug: "updated-title-2", PostID: fixedUUID}, // Kept by the post
+					}, nil)
+				m.On("UpdatePost", mock.Anything, mock.Anything, mock.MatchedBy(func(p models.UpdatePostParams) bool {
+					return p.ID == fixedUUID && p.Slug == "updated-title-2"
+				})).Return(updated, nil)
 			},
 			input: router.UpdatePostParams{
 				Title:       "Updated title",
 				Description: ptr.Ref("Updated description"),
 			},
 			wantStatus: http.StatusOK,
-			wantBody:   `{"id":"550e8400-e29b-41d4-a716-446655440000","title":"Updated title","description":"Updated description","created_at":"2025-01-18T00:13:02Z","updated_at":"2025-01-18T00:13:02Z"}`,
+			wantBody:   updatedJSON,
 		},
 		{
-			desc:       "invalid uuid",
-			given:      "invalid-uuid",
-			mockFunc:   func(m *mocks.Querier) {},
+			desc:  "slug not found",
+			given: "unknown",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "unknown").
+					Return(models.Post{}, pgx.ErrNoRows)
+			},
 			input:      router.UpdatePostParams{},
-			wantStatus: http.StatusBadRequest,
-			wantBody:   `{"error":"Invalid ID format"}`,
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"Post not found"}`,
 		},
 		{
 			desc:  "db error",
 			given: fixedUUID.String(),
 			mockFunc: func(m *mocks.Querier) {
+				m.On("ListPostSlugs", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.ListPostSlugsRow{}, nil)
 				m.On("UpdatePost", mock.Anything, mock.Anything, mock.Anything).
 					Return(models.Post{}, errors.New("db error"))
 			},
@@ -374,11 +455,26 @@ func Test_PostHandler_Delete(t *testing.T) {
 			wantBody:   `{"error":"Post not found"}`,
 		},
 		{
-			desc:       "invalid uuid",
-			given:      "invalid-uuid",
-			mockFunc:   func(m *mocks.Querier) {},
-			wantStatus: http.StatusBadRequest,
-			wantBody:   `{"error":"Invalid ID format"}`,
+			desc:  "success | slug",
+			given: "post-title",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "post-title").
+					Return(models.Post{ID: fixedUUID}, nil) //nolint:exhaustruct // Only the ID is read
+				m.On("DeletePost", mock.Anything, mock.Anything, fixedUUID).
+					Return(int64(1), nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   "",
+		},
+		{
+			desc:  "slug not found",
+			given: "unknown",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("GetPostBySlug", mock.Anything, mock.Anything, "unknown").
+					Return(models.Post{}, pgx.ErrNoRows)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":"Post not found"}`,
 		},
 		{
 			desc: "fail",
@@ -424,3 +520,29 @@ func Test_PostHandler_Delete(t *testing.T) {
 		})
 	}
 }
+
+func Test_PostHandler_Get_FormerSlug(t *testing.T) {
+	t.Parallel()
+
+	// Given: a post renamed from "old-title" to "new-title"
+	mockQ := &mocks.Querier{}
+	mockQ.On("GetPostBySlug", mock.Anything, mock.Anything, "old-title").
+		Return(models.Post{}, pgx.ErrNoRows)
+	mockQ.On("GetCurrentPostSlug", mock.Anything, mock.Anything, "old-title").
+		Return("new-title", nil)
+	h := router.NewPostHandler(nil, mockQ)
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/posts/old-title?format=csv", nil)
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "old-title")
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+
+	// When:
+	h.Get(r).Respond(w, r)
+
+	// Then: the former slug redirects permanently to the current one
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/api/v1/posts/new-title?format=csv", w.Header().Get("Location"))
+	mockQ.AssertExpectations(t)
+}
@@ -0,0 +1,45 @@
+package router
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"go-starter/internal/pkg/slogr"
+)
+
+// Principal identifies a service that called with a verified client certificate
+type Principal struct {
+	Name    string // Common name of the certificate, else its first DNS name
+	Subject string // Distinguished name, e.g. "CN=billing,O=Example"
+}
+
+type principalCtxKey struct{}
+
+// PrincipalFromContext returns the principal of a mutual TLS request
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalCtxKey{}).(Principal)
+	return p, ok
+}
+
+// clientPrincipal adds the principal of a verified client certificate to the
+// request context and to its log attributes. Unverified certificates, which
+// are only presented when verification is disabled, are ignored.
+func clientPrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		p := Principal{Name: cert.Subject.CommonName, Subject: cert.Subject.String()}
+		if p.Name == "" && len(cert.DNSNames) > 0 {
+			p.Name = cert.DNSNames[0]
+		}
+
+		ctx := context.WithValue(r.Context(), principalCtxKey{}, p)
+		ctx = slogr.WithAttrs(ctx, slog.String("principal", p.Name))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
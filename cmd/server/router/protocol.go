@@ -0,0 +1,47 @@
+package router
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Protocol is an HTTP protocol the server accepts
+type Protocol string
+
+// Supported protocols
+const (
+	ProtocolHTTP1 Protocol = "http1" // HTTP/1.1, with or without TLS
+	ProtocolHTTP2 Protocol = "http2" // HTTP/2 over TLS, negotiated with ALPN
+	ProtocolH2C   Protocol = "h2c"   // Unencrypted HTTP/2 with prior knowledge, for internal clients
+)
+
+// errUnknownProtocol is returned when parsing an unsupported protocol
+var errUnknownProtocol = errors.New("unknown protocol")
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *Protocol) UnmarshalText(text []byte) error {
+	v := Protocol(strings.ToLower(string(text)))
+	if v != ProtocolHTTP1 && v != ProtocolHTTP2 && v != ProtocolH2C {
+		return fmt.Errorf("%w: %q", errUnknownProtocol, text)
+	}
+	*p = v
+	return nil
+}
+
+// Protocols returns the set of protocols of http.Server.Protocols
+func Protocols(protocols []Protocol) *http.Protocols {
+	var set http.Protocols
+	for _, p := range protocols {
+		switch p {
+		case ProtocolHTTP1:
+			set.SetHTTP1(true)
+		case ProtocolHTTP2:
+			set.SetHTTP2(true)
+		case ProtocolH2C:
+			set.SetUnencryptedHTTP2(true)
+		}
+	}
+	return &set
+}
@@ -0,0 +1,155 @@
+package router_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Protocols(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc          string
+		serverProtos  []router.Protocol
+		clientProtos  func(*http.Protocols)
+		wantProto     string
+		wantReachable bool
+	}{
+		{
+			desc:          "h2c",
+			serverProtos:  []router.Protocol{router.ProtocolHTTP1, router.ProtocolH2C},
+			clientProtos:  func(p *http.Protocols) { p.SetUnencryptedHTTP2(true) },
+			wantProto:     "HTTP/2.0",
+			wantReachable: true,
+		},
+		{
+			desc:          "http1 alongside h2c",
+			serverProtos:  []router.Protocol{router.ProtocolHTTP1, router.ProtocolH2C},
+			clientProtos:  func(p *http.Protocols) { p.SetHTTP1(true) },
+			wantProto:     "HTTP/1.1",
+			wantReachable: true,
+		},
+		{
+			desc:          "h2c only",
+			serverProtos:  []router.Protocol{router.ProtocolH2C},
+			clientProtos:  func(p *http.Protocols) { p.SetUnencryptedHTTP2(true) },
+			wantProto:     "HTTP/2.0",
+			wantReachable: true,
+		},
+		{
+			desc:          "h2c disabled",
+			serverProtos:  []router.Protocol{router.ProtocolHTTP1, router.ProtocolHTTP2},
+			clientProtos:  func(p *http.Protocols) { p.SetUnencryptedHTTP2(true) },
+			wantProto:     "",
+			wantReachable: false,
+		},
+	}
+
+	// The pool connects lazily and the listeners stop at once, no database is needed
+	pool, err := pgxpool.New(t.Context(), "postgres://localhost:5432/appdb")
+	require.NoError(t, err)
+	t.Cleanup(pool.Close)
+	stopped, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	handler, err := router.Handler(stopped, pool, router.Config{
+		Timeout:    time.Minute,
+		Settings:   router.NewLiveSettings(router.Settings{CORSOrigins: []string{"*"}}),
+		LogLevel:   new(slog.LevelVar),
+		AdminToken: "",
+	})
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			srv := httptest.NewUnstartedServer(handler)
+			srv.Config.Protocols = router.Protocols(tc.serverProtos)
+			srv.Config.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: 10, MaxReadFrameSize: 1 << 20}
+			srv.Start()
+			t.Cleanup(srv.Close)
+
+			var protos http.Protocols
+			tc.clientProtos(&protos)
+			client := &http.Client{Transport: &http.Transport{Protocols: &protos}}
+
+			// When:
+			got, err := client.Get(srv.URL + "/ping")
+
+			// Then:
+			if !tc.wantReachable {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer got.Body.Close()
+			gotBodyBytes, err := io.ReadAll(got.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, got.StatusCode)
+			assert.Equal(t, tc.wantProto, got.Proto)
+			assert.Equal(t, "pong", strings.TrimSpace(string(gotBodyBytes)))
+		})
+	}
+}
+
+func Test_Protocols_ConcurrentStreams(t *testing.T) {
+	t.Parallel()
+
+	// Given: a handler holding each request until all of them arrived
+	const streams = 5
+	arrived := make(chan struct{}, streams)
+	release := make(chan struct{})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		arrived <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	srv.Config.Protocols = router.Protocols([]router.Protocol{router.ProtocolH2C})
+	srv.Config.HTTP2 = &http.HTTP2Config{MaxConcurrentStreams: streams}
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	var protos http.Protocols
+	protos.SetUnencryptedHTTP2(true)
+	client := &http.Client{Transport: &http.Transport{Protocols: &protos}}
+
+	// When: requests are multiplexed on one connection
+	errs := make(chan error, streams)
+	for range streams {
+		go func() {
+			resp, err := client.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			errs <- err
+		}()
+	}
+
+	// Then:
+	for range streams {
+		select {
+		case <-arrived:
+		case <-time.After(5 * time.Second):
+			t.Fatal("requests were not served concurrently")
+		}
+	}
+	close(release)
+	for range streams {
+		require.NoError(t, <-errs)
+	}
+}
@@ -1,27 +1,179 @@
 package router
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+
+	"go-starter/internal/pkg/breaker"
+	"go-starter/internal/pkg/cache"
+	"go-starter/internal/pkg/retry"
+	"go-starter/internal/pkg/slogr"
 
 	"github.com/alvinchoong/go-httphandler"
 	"github.com/alvinchoong/go-httphandler/jsonresp"
 )
 
-// quoteHandler implements a proxy to an external user API service
+// QuoteSourceFallback is reported in the X-Quote-Source header when no provider could serve the quote
+const QuoteSourceFallback = "fallback"
+
+// errUpstreamStatus is returned when a provider replies with an unexpected status code
+var errUpstreamStatus = errors.New("unexpected upstream status")
+
+// QuoteAdapter decodes a provider response body into a QuoteResponse
+type QuoteAdapter func(body io.Reader) (QuoteResponse, error)
+
+// QuoteProvider is an upstream quote API
+type QuoteProvider struct {
+	Name     string       // Reported in the X-Quote-Source header
+	Endpoint string       // URL returning a single random quote
+	Adapt    QuoteAdapter // Decodes the provider specific response
+}
+
+// quoteAdapters are the response adapters available to ParseQuoteProviders
+var quoteAdapters = map[string]QuoteAdapter{
+	"dummyjson": DummyJSONQuote,
+	"zenquotes": ZenQuotesQuote,
+}
+
+// errInvalidQuoteProvider is returned when a provider spec cannot be parsed
+var errInvalidQuoteProvider = errors.New("invalid quote provider")
+
+// ParseQuoteProviders parses an ordered, comma separated list of "adapter=url"
+// pairs, e.g. "dummyjson=https://dummyjson.com/quotes/random". The adapter
+// name is also used as the provider name.
+func ParseQuoteProviders(s string) ([]QuoteProvider, error) {
+	var providers []QuoteProvider
+	for _, spec := range strings.Split(s, ",") {
+		name, endpoint, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok || endpoint == "" {
+			return nil, fmt.Errorf("%w: %q: expected adapter=url", errInvalidQuoteProvider, spec)
+		}
+		adapt, ok := quoteAdapters[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: unknown adapter %q", errInvalidQuoteProvider, spec, name)
+		}
+		providers = append(providers, QuoteProvider{Name: name, Endpoint: endpoint, Adapt: adapt})
+	}
+	return providers, nil
+}
+
+// QuoteProviders is an ordered list of providers that can be loaded from text,
+// see ParseQuoteProviders for the format
+type QuoteProviders []QuoteProvider
+
+// String formats the providers as accepted by ParseQuoteProviders
+func (p QuoteProviders) String() string {
+	specs := make([]string, 0, len(p))
+	for _, provider := range p {
+		specs = append(specs, provider.Name+"="+provider.Endpoint)
+	}
+	return strings.Join(specs, ",")
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *QuoteProviders) UnmarshalText(text []byte) error {
+	providers, err := ParseQuoteProviders(string(text))
+	if err != nil {
+		return err
+	}
+	*p = providers
+	return nil
+}
+
+// QuoteFallback returns a quote when every provider is unavailable
+type QuoteFallback func(ctx context.Context) (QuoteResponse, error)
+
+// quoteProvider is a provider guarded by its own circuit breaker
+type quoteProvider struct {
+	QuoteProvider
+	breaker *breaker.Breaker
+}
+
+// quoteHandler implements a resilient proxy to external quote APIs.
+// Providers are tried in order, each with retries and a circuit breaker,
+// before falling back to the local quotes.
 type quoteHandler struct {
-	client   *http.Client // HTTP client for external API requests
-	endpoint string       // Base URL of the external API
+	client       *http.Client // HTTP client for external API requests
+	providers    []quoteProvider
+	fallback     QuoteFallback
+	retryOpts    []retry.Option
+	breakerOpts  []breaker.Option
+	attemptLimit time.Duration
+	cache        *cache.Cache[quoteResult]
+}
+
+// quoteResult is a quote along with where it came from
+type quoteResult struct {
+	quote  QuoteResponse
+	source string
+}
+
+// QuoteOption is a function that configures a quoteHandler
+type QuoteOption func(*quoteHandler)
+
+// WithQuoteFallback sets the source of quotes used when every provider is down
+func WithQuoteFallback(fallback QuoteFallback) QuoteOption {
+	return func(h *quoteHandler) {
+		h.fallback = fallback
+	}
+}
+
+// WithQuoteRetry sets the retry policy applied to each provider
+func WithQuoteRetry(opts ...retry.Option) QuoteOption {
+	return func(h *quoteHandler) {
+		h.retryOpts = opts
+	}
+}
+
+// WithQuoteBreaker sets the circuit breaker configuration applied to each provider
+func WithQuoteBreaker(opts ...breaker.Option) QuoteOption {
+	return func(h *quoteHandler) {
+		h.breakerOpts = opts
+	}
 }
 
-// NewQuoteHandler creates a new user handler with configured HTTP client and API endpoint
-func NewQuoteHandler(client *http.Client, endpoint string) *quoteHandler {
-	return &quoteHandler{
-		client:   client,
-		endpoint: endpoint,
+// WithQuoteCache serves quotes from an in-process cache for ttl, and stale for up
+// to stale more while refreshing. Upstream Cache-Control headers may shorten both.
+func WithQuoteCache(ttl, stale time.Duration) QuoteOption {
+	return func(h *quoteHandler) {
+		if ttl > 0 || stale > 0 {
+			h.cache = cache.New[quoteResult](ttl, stale)
+		}
 	}
 }
 
+// NewQuoteHandler creates a new quote handler with configured HTTP client and ordered providers
+func NewQuoteHandler(client *http.Client, providers []QuoteProvider, opts ...QuoteOption) *quoteHandler {
+	h := &quoteHandler{
+		client:       client,
+		providers:    nil,
+		fallback:     nil,
+		retryOpts:    nil,
+		breakerOpts:  nil,
+		attemptLimit: 5 * time.Second,
+		cache:        nil,
+	}
+	for _, o := range opts {
+		o(h)
+	}
+
+	for _, p := range providers {
+		h.providers = append(h.providers, quoteProvider{
+			QuoteProvider: p,
+			breaker:       breaker.New(h.breakerOpts...),
+		})
+	}
+
+	return h
+}
+
 // QuoteResponse represents the structure of quote data from the external API
 type QuoteResponse struct {
 	ID     int    `json:"id"`
@@ -29,29 +181,164 @@ type QuoteResponse struct {
 	Author string `json:"author"`
 }
 
-// Get proxies the request to an external API and returns the quote data
+// Get returns a quote from the cache, the first available provider, or the fallback
 func (h *quoteHandler) Get(r *http.Request) httphandler.Responder {
 	ctx := r.Context()
 
-	request, err := http.NewRequestWithContext(ctx, http.MethodGet, h.endpoint, nil)
+	if h.cache == nil {
+		res, _, err := h.load(ctx)
+		if err != nil {
+			return jsonresp.Error(err, "failed to fetch quote", http.StatusServiceUnavailable)
+		}
+		return jsonresp.Success(&res.quote).WithHeader("X-Quote-Source", res.source)
+	}
+
+	res, err := h.cache.Get(ctx, "random", h.load)
 	if err != nil {
-		return jsonresp.Error(err, "failed to create request", http.StatusInternalServerError)
+		return jsonresp.Error(err, "failed to fetch quote", http.StatusServiceUnavailable)
+	}
+
+	resp := jsonresp.Success(&res.Value.quote).WithHeader("X-Quote-Source", res.Value.source)
+
+	header := http.Header{}
+	res.SetHeaders(header)
+	for k := range header {
+		resp = resp.WithHeader(k, header.Get(k))
+	}
+
+	return resp
+}
+
+// load tries every provider in order before falling back to the local quotes.
+// Fallback quotes are not cached so that the providers are tried again next time.
+func (h *quoteHandler) load(ctx context.Context) (quoteResult, cache.Policy, error) {
+	logger := slogr.FromContext(ctx)
+
+	var errs []error
+	for _, p := range h.providers {
+		quote, policy, err := h.fetch(ctx, p)
+		if err == nil {
+			return quoteResult{quote: quote, source: p.Name}, policy, nil
+		}
+		if ctx.Err() != nil {
+			return quoteResult{}, cache.Policy{}, err
+		}
+
+		logger.Warn("quote provider failed",
+			slog.String("provider", p.Name),
+			slog.String("breaker", p.breaker.State().String()),
+			slog.Any("err", err),
+		)
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+	}
+
+	if h.fallback != nil {
+		quote, err := h.fallback(ctx)
+		if err == nil {
+			return quoteResult{quote: quote, source: QuoteSourceFallback}, cache.Policy{NoStore: true}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", QuoteSourceFallback, err))
 	}
 
+	return quoteResult{}, cache.Policy{}, errors.Join(errs...)
+}
+
+// fetch gets a quote from a provider, retrying transient failures
+func (h *quoteHandler) fetch(ctx context.Context, p quoteProvider) (QuoteResponse, cache.Policy, error) {
+	var (
+		quote  QuoteResponse
+		policy cache.Policy
+	)
+
+	err := p.breaker.Do(func() error {
+		return retry.Do(ctx, func(ctx context.Context) error {
+			q, cp, err := h.fetchOnce(ctx, p)
+			if err != nil {
+				return err
+			}
+			quote, policy = q, cp
+			return nil
+		}, h.retryOpts...)
+	})
+
+	return quote, policy, err //nolint:wrapcheck // wrapped by the caller with the provider name
+}
+
+// fetchOnce makes a single request to the provider.
+// Only transient failures are returned as retryable errors.
+func (h *quoteHandler) fetchOnce(ctx context.Context, p quoteProvider) (QuoteResponse, cache.Policy, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.attemptLimit)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Endpoint, nil)
+	if err != nil {
+		return QuoteResponse{}, cache.Policy{}, retry.Permanent(fmt.Errorf("http.NewRequestWithContext: %w", err))
+	}
+	request.Header.Set("Accept", "application/json")
+
 	resp, err := h.client.Do(request)
 	if err != nil {
-		return jsonresp.Error(err, "failed to make request", http.StatusInternalServerError)
+		return QuoteResponse{}, cache.Policy{}, fmt.Errorf("client.Do: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return jsonresp.Error(nil, "failed to fetch data from external API", resp.StatusCode)
+		err := fmt.Errorf("%w: %d", errUpstreamStatus, resp.StatusCode)
+		if !retryableStatus(resp.StatusCode) {
+			return QuoteResponse{}, cache.Policy{}, retry.Permanent(err)
+		}
+		return QuoteResponse{}, cache.Policy{}, err
 	}
 
+	quote, err := p.Adapt(resp.Body)
+	if err != nil {
+		return QuoteResponse{}, cache.Policy{}, retry.Permanent(fmt.Errorf("adapt: %w", err))
+	}
+
+	return quote, cache.ParsePolicy(resp.Header), nil
+}
+
+// retryableStatus reports whether a status code signals a transient upstream failure
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// errEmptyQuote is returned by adapters when the provider returned no quote
+var errEmptyQuote = errors.New("empty quote")
+
+// DummyJSONQuote adapts https://dummyjson.com/quotes/random responses
+func DummyJSONQuote(body io.Reader) (QuoteResponse, error) {
 	var quote QuoteResponse
-	if err := json.NewDecoder(resp.Body).Decode(&quote); err != nil {
-		return jsonresp.Error(err, "failed to decode response", http.StatusInternalServerError)
+	if err := json.NewDecoder(body).Decode(&quote); err != nil {
+		return QuoteResponse{}, fmt.Errorf("json.Decode: %w", err)
+	}
+	if quote.Quote == "" {
+		return QuoteResponse{}, errEmptyQuote
 	}
+	return quote, nil
+}
 
-	return jsonresp.Success(&quote)
+// ZenQuotesQuote adapts https://zenquotes.io/api/random responses
+func ZenQuotesQuote(body io.Reader) (QuoteResponse, error) {
+	var quotes []struct {
+		Quote  string `json:"q"`
+		Author string `json:"a"`
+	}
+	if err := json.NewDecoder(body).Decode(&quotes); err != nil {
+		return QuoteResponse{}, fmt.Errorf("json.Decode: %w", err)
+	}
+	if len(quotes) == 0 || quotes[0].Quote == "" {
+		return QuoteResponse{}, errEmptyQuote
+	}
+	return QuoteResponse{ID: 0, Quote: quotes[0].Quote, Author: quotes[0].Author}, nil
 }
@@ -1,12 +1,18 @@
 package router_test
 
 import (
+	"context"
+	"errors"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"go-starter/cmd/server/router"
+	"go-starter/internal/pkg/breaker"
+	"go-starter/internal/pkg/retry"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -15,48 +21,109 @@ import (
 func Test_QuoteHandler_Get(t *testing.T) {
 	t.Parallel()
 
+	okResponse := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write([]byte(body))
+			assert.NoError(t, err)
+		}
+	}
+	errorResponse := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			_, err := w.Write([]byte(`{"error": "origin server error"}`))
+			assert.NoError(t, err)
+		}
+	}
+	// failOnce fails the first request with a transient error, then delegates
+	failOnce := func(next http.HandlerFunc) http.HandlerFunc {
+		var calls atomic.Int32
+		return func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				errorResponse(http.StatusServiceUnavailable)(w, r)
+				return
+			}
+			next(w, r)
+		}
+	}
+
+	dummyJSONBody := `{
+		"id": 1,
+		"quote": "Life is what happens when you're busy making other plans",
+		"author": "John Lennon"
+	}`
+	zenQuotesBody := `[{"q":"Clear is better than clever.","a":"Rob Pike","h":"<blockquote>...</blockquote>"}]`
+
+	fallback := func(context.Context) (router.QuoteResponse, error) {
+		return router.QuoteResponse{ID: 7, Quote: "Make it work, make it right, make it fast.", Author: "Kent Beck"}, nil
+	}
+	fallbackErr := func(context.Context) (router.QuoteResponse, error) {
+		return router.QuoteResponse{}, errors.New("db error")
+	}
+
 	testCases := []struct {
-		desc         string
-		mockResponse http.HandlerFunc
-		wantStatus   int
-		wantBody     string
+		desc       string
+		primary    http.HandlerFunc
+		secondary  http.HandlerFunc
+		fallback   router.QuoteFallback
+		wantStatus int
+		wantSource string
+		wantBody   string
 	}{
 		{
-			desc: "success",
-			mockResponse: func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				_, err := w.Write([]byte(`{
-					"id": 1,
-					"quote": "Life is what happens when you're busy making other plans",
-					"author": "John Lennon"
-				}`))
-				assert.NoError(t, err)
-			},
+			desc:       "success",
+			primary:    okResponse(dummyJSONBody),
+			secondary:  okResponse(zenQuotesBody),
+			fallback:   fallback,
 			wantStatus: http.StatusOK,
+			wantSource: "primary",
 			wantBody:   `{"id":1,"quote":"Life is what happens when you're busy making other plans","author":"John Lennon"}`,
 		},
 		{
-			desc: "external api returns error",
-			mockResponse: func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusInternalServerError)
-				_, err := w.Write([]byte(`{"error": "origin server error"}`))
-				assert.NoError(t, err)
-			},
-			wantStatus: http.StatusInternalServerError,
-			wantBody:   `{"error":"failed to fetch data from external API"}`,
+			desc:       "transient error is retried",
+			primary:    failOnce(okResponse(dummyJSONBody)),
+			secondary:  errorResponse(http.StatusInternalServerError),
+			fallback:   fallback,
+			wantStatus: http.StatusOK,
+			wantSource: "primary",
+			wantBody:   `{"id":1,"quote":"Life is what happens when you're busy making other plans","author":"John Lennon"}`,
 		},
 		{
-			desc: "external api returns invalid json",
-			mockResponse: func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Content-Type", "application/json")
-				w.WriteHeader(http.StatusOK)
-				_, err := w.Write([]byte(`invalid json`))
-				assert.NoError(t, err)
-			},
-			wantStatus: http.StatusInternalServerError,
-			wantBody:   `{"error":"failed to decode response"}`,
+			desc:       "external api returns error, next provider is used",
+			primary:    errorResponse(http.StatusInternalServerError),
+			secondary:  okResponse(zenQuotesBody),
+			fallback:   fallback,
+			wantStatus: http.StatusOK,
+			wantSource: "secondary",
+			wantBody:   `{"id":0,"quote":"Clear is better than clever.","author":"Rob Pike"}`,
+		},
+		{
+			desc:       "external api returns invalid json, next provider is used",
+			primary:    okResponse(`invalid json`),
+			secondary:  okResponse(zenQuotesBody),
+			fallback:   fallback,
+			wantStatus: http.StatusOK,
+			wantSource: "secondary",
+			wantBody:   `{"id":0,"quote":"Clear is better than clever.","author":"Rob Pike"}`,
+		},
+		{
+			desc:       "every provider is down, fallback is used",
+			primary:    errorResponse(http.StatusBadGateway),
+			secondary:  errorResponse(http.StatusNotFound),
+			fallback:   fallback,
+			wantStatus: http.StatusOK,
+			wantSource: router.QuoteSourceFallback,
+			wantBody:   `{"id":7,"quote":"Make it work, make it right, make it fast.","author":"Kent Beck"}`,
+		},
+		{
+			desc:       "every provider and the fallback are down",
+			primary:    errorResponse(http.StatusBadGateway),
+			secondary:  okResponse(`[]`),
+			fallback:   fallbackErr,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody:   `{"error":"failed to fetch quote"}`,
 		},
 	}
 
@@ -65,11 +132,19 @@ func Test_QuoteHandler_Get(t *testing.T) {
 			t.Parallel()
 
 			// Given:
-			// Create a mock external API server
-			mockServer := httptest.NewServer(tc.mockResponse)
-			defer mockServer.Close()
+			// Create mock external API servers
+			primary := httptest.NewServer(tc.primary)
+			defer primary.Close()
+			secondary := httptest.NewServer(tc.secondary)
+			defer secondary.Close()
 
-			h := router.NewQuoteHandler(&http.Client{}, mockServer.URL)
+			h := router.NewQuoteHandler(&http.Client{},
+				[]router.QuoteProvider{
+					{Name: "primary", Endpoint: primary.URL, Adapt: router.DummyJSONQuote},
+					{Name: "secondary", Endpoint: secondary.URL, Adapt: router.ZenQuotesQuote},
+				},
+				router.WithQuoteFallback(tc.fallback),
+				router.WithQuoteRetry(retry.WithMaxAttempts(2), retry.WithBackoff(time.Millisecond, time.Millisecond)))
 			r := httptest.NewRequest(http.MethodGet, "/api/v1/quotes", nil)
 			w := httptest.NewRecorder()
 
@@ -83,7 +158,159 @@ func Test_QuoteHandler_Get(t *testing.T) {
 
 			// Then:
 			assert.Equal(t, tc.wantStatus, w.Code)
+			assert.Equal(t, tc.wantSource, got.Header.Get("X-Quote-Source"))
 			require.JSONEq(t, tc.wantBody, string(gotBodyBytes))
 		})
 	}
 }
+
+func Test_QuoteHandler_Get_CircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	// Given: a provider that always fails
+	var calls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer upstream.Close()
+
+	h := router.NewQuoteHandler(&http.Client{},
+		[]router.QuoteProvider{{Name: "primary", Endpoint: upstream.URL, Adapt: router.DummyJSONQuote}},
+		router.WithQuoteRetry(retry.WithMaxAttempts(1)),
+		router.WithQuoteBreaker(breaker.WithFailureThreshold(2), breaker.WithOpenTimeout(time.Hour)))
+
+	// When: more requests are made than the failure threshold
+	for range 5 {
+		r := httptest.NewRequest(http.MethodGet, "/api/v1/quotes", nil)
+		w := httptest.NewRecorder()
+		h.Get(r).Respond(w, r)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	}
+
+	// Then: the breaker stops calling the provider once open
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func Test_QuoteHandler_Get_Cache(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc             string
+		upstreamCache    string
+		wantMaxCalls     int32
+		wantCacheControl string
+	}{
+		{
+			desc:             "cached for the configured ttl",
+			wantMaxCalls:     1,
+			wantCacheControl: "public, max-age=60, stale-while-revalidate=300",
+		},
+		{
+			desc:             "upstream max-age shortens the ttl",
+			upstreamCache:    "max-age=10",
+			wantMaxCalls:     1,
+			wantCacheControl: "public, max-age=10, stale-while-revalidate=300",
+		},
+		{
+			desc:             "upstream no-store is respected",
+			upstreamCache:    "no-store",
+			wantMaxCalls:     5,
+			wantCacheControl: "no-store",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given: a slow upstream, so that concurrent misses overlap
+			var calls atomic.Int32
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				time.Sleep(50 * time.Millisecond)
+				if tc.upstreamCache != "" {
+					w.Header().Set("Cache-Control", tc.upstreamCache)
+				}
+				_, err := w.Write([]byte(`{"id":1,"quote":"Less is more.","author":"Mies"}`))
+				assert.NoError(t, err)
+			}))
+			defer upstream.Close()
+
+			h := router.NewQuoteHandler(&http.Client{},
+				[]router.QuoteProvider{{Name: "primary", Endpoint: upstream.URL, Adapt: router.DummyJSONQuote}},
+				router.WithQuoteCache(time.Minute, 5*time.Minute))
+
+			// When: several requests arrive at once
+			recorders := make([]*httptest.ResponseRecorder, 5)
+			done := make(chan struct{})
+			for i := range recorders {
+				recorders[i] = httptest.NewRecorder()
+				go func() {
+					defer func() { done <- struct{}{} }()
+					r := httptest.NewRequest(http.MethodGet, "/api/v1/quotes", nil)
+					h.Get(r).Respond(recorders[i], r)
+				}()
+			}
+			for range recorders {
+				<-done
+			}
+
+			// Then: they share upstream calls as allowed by the cache policy
+			assert.LessOrEqual(t, calls.Load(), tc.wantMaxCalls)
+			for _, w := range recorders {
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, "primary", w.Header().Get("X-Quote-Source"))
+				assert.Equal(t, tc.wantCacheControl, w.Header().Get("Cache-Control"))
+			}
+		})
+	}
+}
+
+func Test_ParseQuoteProviders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc      string
+		input     string
+		wantNames []string
+		wantErr   bool
+	}{
+		{
+			desc:      "ordered providers",
+			input:     "zenquotes=https://zenquotes.io/api/random, dummyjson=https://dummyjson.com/quotes/random",
+			wantNames: []string{"zenquotes", "dummyjson"},
+		},
+		{
+			desc:    "missing endpoint",
+			input:   "dummyjson",
+			wantErr: true,
+		},
+		{
+			desc:    "unknown adapter",
+			input:   "foo=https://example.com",
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// When:
+			got, err := router.ParseQuoteProviders(tc.input)
+
+			// Then:
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			names := make([]string, 0, len(got))
+			for _, p := range got {
+				names = append(names, p.Name)
+			}
+			assert.Equal(t, tc.wantNames, names)
+		})
+	}
+}
@@ -2,53 +2,171 @@ package router
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"time"
 
 	"go-starter/internal/models"
+	"go-starter/internal/pkg/buildinfo"
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/pgnotify"
+	"go-starter/internal/pkg/slogr"
 
 	"github.com/alvinchoong/go-httphandler"
 	"github.com/alvinchoong/go-httphandler/plainresp"
 	"github.com/go-chi/chi/middleware"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// Config holds the settings of the HTTP handlers
+type Config struct {
+	Timeout         time.Duration   // Maximum duration of regular, non-streaming requests
+	QuoteProviders  []QuoteProvider // Upstream quote APIs, tried in order
+	QuoteCacheTTL   time.Duration   // How long quotes are served from cache
+	QuoteCacheStale time.Duration   // How long stale quotes are served while revalidating
+	Settings        *LiveSettings   // Settings that may be reloaded while running
+	LogLevel        *slog.LevelVar  // Global log level, changed by the admin API
+	AdminToken      envvar.Secret   // Bearer token of the admin API, which is disabled when empty
+	CompressMinSize int             // Smallest response body compressed, in bytes
+	CompressTypes   []string        // Content types compressed, e.g. "application/json" or "text/*", none when empty
+	ImportMaxSize   int64           // Largest post import file accepted, in bytes
+	ImportAsyncSize int64           // Post import files at least this large are imported in the background, in bytes
+}
+
 // Handler returns the http handler that handles all requests.
-// It sets up the router with middleware, database connection, and routes.
+// It sets up the router with middleware, database connection, and routes,
+// and starts the background loops of the handlers until ctx is done.
 func Handler(
 	ctx context.Context,
 	db *pgxpool.Pool,
-	timeout time.Duration,
+	cfg Config,
 ) (*chi.Mux, error) {
+	// Initialize database query interface
+	q := models.New()
+
+	h := &handlers{
+		stream:   NewPostStream(db, q, 1000, 15*time.Second),
+		presence: NewPostPresence(db, q, 15*time.Second),
+		posts:    NewPostHandler(db, q),
+		importer: NewPostImporter(db, q, cfg.ImportMaxSize, cfg.ImportAsyncSize),
+		batch:    NewPostBatchHandler(db, q),
+		quotes: NewQuoteHandler(newHTTPClient(), cfg.QuoteProviders,
+			WithQuoteCache(cfg.QuoteCacheTTL, cfg.QuoteCacheStale),
+			WithQuoteFallback(func(ctx context.Context) (QuoteResponse, error) {
+				quote, err := q.GetRandomQuote(ctx, db)
+				if err != nil {
+					return QuoteResponse{}, fmt.Errorf("q.GetRandomQuote: %w", err)
+				}
+				return QuoteResponse{ID: int(quote.ID), Quote: quote.Quote, Author: quote.Author}, nil
+			})),
+		scheduler: NewSchedulerHandler(db, q),
+	}
+
+	go h.stream.Run(ctx)
+	go h.presence.Run(ctx)
+	go h.importer.Run(ctx)
+
+	listener := pgnotify.NewListener(db.Config().ConnConfig,
+		[]string{PostChangesChannel, PostPresenceChannel},
+		pgnotify.WithOnConnect(h.stream.Reset))
+	go func() {
+		err := listener.Listen(ctx, func(ctx context.Context, n *pgconn.Notification) {
+			switch n.Channel {
+			case PostChangesChannel:
+				h.stream.Notify(ctx, n)
+			case PostPresenceChannel:
+				h.presence.Notify(ctx, n)
+			}
+		})
+		if err != nil {
+			slogr.FromContext(ctx).Error("[pgnotify] listener stopped", slog.Any("err", err))
+		}
+	}()
+
+	return newRouter(cfg, h), nil
+}
+
+// Routes returns the routes of Handler without any handler behind them, to
+// list them without a database and without starting anything
+func Routes(cfg Config) *chi.Mux {
+	return newRouter(cfg, new(handlers))
+}
+
+// handlers holds the handlers depending on the database or upstream APIs
+type handlers struct {
+	stream    *postStream
+	presence  *postPresence
+	posts     *postHandler
+	importer  *postImporter
+	batch     *postBatchHandler
+	quotes    *quoteHandler
+	scheduler *schedulerHandler
+}
+
+// newRouter sets up the middlewares and routes of every handler
+func newRouter(cfg Config, h *handlers) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Top-level middlewares
 	r.Use(middleware.RequestID)
-	r.Use(requestLogger)
+	r.Use(clientPrincipal)
+	r.Use(requestLogger(cfg.AdminToken))
 	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(timeout))
-	r.Use(corsMiddleware([]string{"*"}))
+	r.Use(liveCORSMiddleware(cfg.Settings))
+	r.Use(rateLimitMiddleware(cfg.Settings))
+	if len(cfg.CompressTypes) > 0 {
+		r.Use(Compress(cfg.CompressMinSize, cfg.CompressTypes))
+	}
 
-	// Initialize database query interface
-	q := models.New()
+	// Live post changes via Server-Sent Events and editing presence via WebSocket.
+	// Registered outside of the timeout group as the connections are long-lived.
+	r.With(requireFeature(cfg.Settings, FeaturePostStream)).
+		Get("/api/v1/posts/stream", httphandler.Handle(h.stream.Stream))
+	r.With(requireFeature(cfg.Settings, FeaturePostPresence)).
+		Get("/api/v1/posts/{id}/ws", httphandler.Handle(h.presence.Connect))
+
+	// Bulk import and export of posts, outside of the timeout group as the
+	// files may be large. Large imports run in the background, one at a time.
+	r.Post("/api/v1/posts:import", httphandler.Handle(h.importer.Import))
+	r.Get("/api/v1/posts:import/{id}", httphandler.Handle(h.importer.Job))
+	r.Get("/api/v1/posts:export", httphandler.Handle(h.posts.Export))
+
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(cfg.Timeout))
+
+		// Post CRUD API
+		r.Post("/api/v1/posts", httphandler.HandleWithInput(h.posts.Create))
+		r.Get("/api/v1/posts", httphandler.Handle(h.posts.List))
+		r.Get("/api/v1/posts/{id}", httphandler.Handle(h.posts.Get))
+		r.Put("/api/v1/posts/{id}", httphandler.HandleWithInput(h.posts.Update))
+		r.Delete("/api/v1/posts/{id}", httphandler.Handle(h.posts.Delete))
+
+		// Several post operations in one transaction
+		r.Post("/api/v1/posts:batch", httphandler.HandleWithInput(h.batch.Batch))
+
+		// Quotes API proxy
+		r.Get("/api/v1/quotes", httphandler.Handle(h.quotes.Get))
+
+		// Scheduler admin API, restricted to the admin token
+		r.With(requireAdmin(cfg.AdminToken)).Get("/api/v1/admin/scheduler/runs", httphandler.Handle(h.scheduler.ListRuns))
 
-	// Post CRUD API
-	ph := NewPostHandler(db, q)
-	r.Post("/api/v1/posts", httphandler.HandleWithInput(ph.Create))
-	r.Get("/api/v1/posts", httphandler.Handle(ph.List))
-	r.Get("/api/v1/posts/{id}", httphandler.Handle(ph.Get))
-	r.Put("/api/v1/posts/{id}", httphandler.HandleWithInput(ph.Update))
-	r.Delete("/api/v1/posts/{id}", httphandler.Handle(ph.Delete))
+		// Log level admin API, restricted to the admin token
+		lh := NewLogLevelHandler(cfg.LogLevel)
+		r.With(requireAdmin(cfg.AdminToken)).Get("/api/v1/admin/log/level", httphandler.Handle(lh.Get))
+		r.With(requireAdmin(cfg.AdminToken)).Put("/api/v1/admin/log/level", httphandler.HandleWithInput(lh.Update))
 
-	// Quotes API proxy
-	qh := NewQuoteHandler(newHTTPClient(), "https://dummyjson.com/quotes/random")
-	r.Get("/api/v1/quotes", httphandler.Handle(qh.Get))
+		// Health check endpoint
+		r.Get("/ping", httphandler.Handle(pingHandler))
 
-	// Health check endpoint
-	r.Get("/ping", httphandler.Handle(pingHandler))
+		// Build information
+		r.Get("/version", httphandler.Handle(versionHandler(buildinfo.Get(), cfg.AdminToken)))
+		r.Get("/metrics", metricsHandler(buildinfo.Get()))
+	})
 
-	return r, nil
+	return r
 }
 
 // pingHandler responds to health check requests with "pong"
@@ -0,0 +1,56 @@
+package router
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+)
+
+// Bounds for the number of scheduler runs returned per request
+const (
+	defaultSchedulerRunsLimit = 50
+	maxSchedulerRunsLimit     = 500
+)
+
+// schedulerHandler exposes the run history of scheduled jobs
+type schedulerHandler struct {
+	db      models.DBTX
+	querier models.Querier
+}
+
+// NewSchedulerHandler creates a new scheduler handler with database connection and query interface
+func NewSchedulerHandler(db models.DBTX, q models.Querier) *schedulerHandler {
+	return &schedulerHandler{
+		db:      db,
+		querier: q,
+	}
+}
+
+// ListRuns retrieves the most recent job runs, optionally filtered by ?job= and capped by ?limit=
+func (h *schedulerHandler) ListRuns(r *http.Request) httphandler.Responder {
+	ctx := r.Context()
+
+	limit := defaultSchedulerRunsLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSchedulerRunsLimit {
+			return jsonresp.Error(err, "Invalid limit", http.StatusBadRequest)
+		}
+		limit = n
+	}
+
+	runs, err := h.querier.ListSchedulerRuns(ctx, h.db, models.ListSchedulerRunsParams{
+		JobName: ptr.NilIfZero(r.URL.Query().Get("job")),
+		Limit:   int32(limit), //nolint:gosec // bounded by maxSchedulerRunsLimit
+	})
+	if err != nil {
+		return jsonresp.InternalServerError(err)
+	}
+
+	return jsonresp.Success(&runs)
+}
@@ -0,0 +1,112 @@
+package router_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+	"go-starter/internal/mocks"
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_SchedulerHandler_ListRuns(t *testing.T) {
+	t.Parallel()
+
+	fixedTime := time.Date(2025, 1, 18, 3, 0, 0, 0, time.UTC)
+	fixedUUID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	testCases := []struct {
+		desc       string
+		query      string
+		mockFunc   func(*mocks.Querier)
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			desc:  "success | defaults",
+			query: "",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListSchedulerRuns", mock.Anything, mock.Anything, models.ListSchedulerRunsParams{
+					JobName: nil,
+					Limit:   50,
+				}).Return([]models.SchedulerRun{{
+					ID:          fixedUUID,
+					JobName:     "purge-scheduler-runs",
+					Instance:    "host-1",
+					ScheduledAt: fixedTime,
+					StartedAt:   fixedTime,
+					FinishedAt:  ptr.Ref(fixedTime.Add(1500 * time.Millisecond)),
+					DurationMs:  ptr.Ref(int64(1500)),
+					Status:      "succeeded",
+					Error:       nil,
+				}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":"550e8400-e29b-41d4-a716-446655440000","job_name":"purge-scheduler-runs","instance":"host-1","scheduled_at":"2025-01-18T03:00:00Z","started_at":"2025-01-18T03:00:00Z","finished_at":"2025-01-18T03:00:01.5Z","duration_ms":1500,"status":"succeeded","error":null}]`,
+		},
+		{
+			desc:  "success | filtered",
+			query: "?job=purge-scheduler-runs&limit=10",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListSchedulerRuns", mock.Anything, mock.Anything, models.ListSchedulerRunsParams{
+					JobName: ptr.Ref("purge-scheduler-runs"),
+					Limit:   10,
+				}).Return([]models.SchedulerRun{}, nil)
+			},
+			wantStatus: http.StatusOK,
+			wantBody:   `[]`,
+		},
+		{
+			desc:       "invalid limit",
+			query:      "?limit=1000",
+			mockFunc:   func(m *mocks.Querier) {},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":"Invalid limit"}`,
+		},
+		{
+			desc:  "db error",
+			query: "",
+			mockFunc: func(m *mocks.Querier) {
+				m.On("ListSchedulerRuns", mock.Anything, mock.Anything, mock.Anything).
+					Return([]models.SchedulerRun{}, errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":"Internal Server Error"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			mockQ := &mocks.Querier{}
+			tc.mockFunc(mockQ)
+			h := router.NewSchedulerHandler(nil, mockQ)
+			r := httptest.NewRequest(http.MethodGet, "/api/v1/admin/scheduler/runs"+tc.query, nil)
+			w := httptest.NewRecorder()
+
+			// When:
+			h.ListRuns(r).Respond(w, r)
+
+			got := w.Result()
+			defer got.Body.Close()
+			gotBodyBytes, err := io.ReadAll(got.Body)
+			require.NoError(t, err)
+
+			// Then:
+			assert.Equal(t, tc.wantStatus, got.StatusCode)
+			assert.JSONEq(t, tc.wantBody, string(gotBodyBytes))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package router
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+	"golang.org/x/time/rate"
+)
+
+// Feature flags gating optional routes
+const (
+	FeaturePostStream   = "post_stream"
+	FeaturePostPresence = "post_presence"
+)
+
+// Settings are the handler settings that may change while the server is running
+type Settings struct {
+	CORSOrigins []string        // Allowed CORS origins, "*" allows any
+	RateLimit   float64         // Requests per second allowed per client IP, 0 disables rate limiting
+	RateBurst   int             // Requests a client IP may make at once
+	Features    map[string]bool // Enabled feature flags, absent flags are disabled
+}
+
+// LiveSettings holds the current Settings, which may be replaced at any time
+type LiveSettings struct {
+	v atomic.Pointer[Settings]
+}
+
+// NewLiveSettings creates a LiveSettings holding s
+func NewLiveSettings(s Settings) *LiveSettings {
+	l := &LiveSettings{v: atomic.Pointer[Settings]{}}
+	l.Store(s)
+	return l
+}
+
+// Load returns the current settings
+func (l *LiveSettings) Load() *Settings {
+	return l.v.Load()
+}
+
+// Store replaces the current settings, affecting requests received from now on
+func (l *LiveSettings) Store(s Settings) {
+	l.v.Store(&s)
+}
+
+// liveCORSMiddleware applies corsMiddleware with the current CORS origins,
+// rebuilding it whenever the settings are replaced
+func liveCORSMiddleware(settings *LiveSettings) func(next http.Handler) http.Handler {
+	type current struct {
+		settings *Settings
+		handler  http.Handler
+	}
+
+	return func(next http.Handler) http.Handler {
+		var cur atomic.Pointer[current]
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := settings.Load()
+			c := cur.Load()
+			if c == nil || c.settings != s {
+				c = &current{settings: s, handler: corsMiddleware(s.CORSOrigins)(next)}
+				cur.Store(c)
+			}
+			c.handler.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireFeature responds with 404 Not Found unless the feature flag is enabled
+func requireFeature(settings *LiveSettings, feature string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !settings.Load().Features[feature] {
+				http.NotFound(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimiter limits requests per client IP with a token bucket following the current settings
+type rateLimiter struct {
+	settings *LiveSettings
+	idle     time.Duration // Clients idle for longer are forgotten
+
+	mu        sync.Mutex
+	clients   map[string]*rateClient
+	lastSweep time.Time
+}
+
+// rateClient is the bucket of a client IP
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimitMiddleware responds with 429 Too Many Requests to clients exceeding the rate limit
+func rateLimitMiddleware(settings *LiveSettings) func(next http.Handler) http.Handler {
+	rl := &rateLimiter{
+		settings:  settings,
+		idle:      3 * time.Minute,
+		mu:        sync.Mutex{},
+		clients:   map[string]*rateClient{},
+		lastSweep: time.Now(),
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s := rl.settings.Load()
+			if s.RateLimit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !rl.allow(clientIP(r), rate.Limit(s.RateLimit), s.RateBurst) {
+				retryAfter := int(math.Ceil(1 / s.RateLimit))
+				jsonresp.Error(nil, "Too many requests", http.StatusTooManyRequests).
+					WithHeader("Retry-After", strconv.Itoa(retryAfter)).
+					Respond(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// allow reports whether the client may make a request now, applying limit
+// changes to its bucket and forgetting idle clients once a minute
+func (rl *rateLimiter) allow(ip string, limit rate.Limit, burst int) bool {
+	now := time.Now()
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > time.Minute {
+		for k, c := range rl.clients {
+			if now.Sub(c.lastSeen) > rl.idle {
+				delete(rl.clients, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	c, ok := rl.clients[ip]
+	if !ok {
+		c = &rateClient{limiter: rate.NewLimiter(limit, burst), lastSeen: now}
+		rl.clients[ip] = c
+	}
+	if c.limiter.Limit() != limit {
+		c.limiter.SetLimitAt(now, limit)
+	}
+	if c.limiter.Burst() != burst {
+		c.limiter.SetBurstAt(now, burst)
+	}
+	c.lastSeen = now
+
+	return c.limiter.AllowN(now, 1)
+}
+
+// clientIP returns the IP address of the client, without the port. Unix
+// sockets have no client address and are only reachable by local processes,
+// such as a reverse proxy, so the address it appended to X-Forwarded-For is
+// trusted there, or else X-Real-IP.
+func clientIP(r *http.Request) string {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok && addr.Network() == "unix" {
+		if ip := forwardedIP(r); ip != "" {
+			return ip
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedIP returns the last address of X-Forwarded-For, or X-Real-IP
+func forwardedIP(r *http.Request) string {
+	if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+		hops := strings.Split(values[len(values)-1], ",")
+		if ip := strings.TrimSpace(hops[len(hops)-1]); ip != "" {
+			return ip
+		}
+	}
+	return strings.TrimSpace(r.Header.Get("X-Real-IP"))
+}
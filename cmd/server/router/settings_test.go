@@ -0,0 +1,79 @@
+package router_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-starter/cmd/server/router"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RateLimit_UnixSocket(t *testing.T) {
+	t.Parallel()
+
+	unix := &net.UnixAddr{Name: "/run/server.sock", Net: "unix"}
+	tcp := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 8080}
+
+	testCases := []struct {
+		desc       string
+		localAddr  net.Addr
+		remoteAddr string
+		forwarded  []string // X-Forwarded-For of each request
+		wantStatus []int
+	}{
+		{
+			desc:       "unix socket | clients behind the proxy",
+			localAddr:  unix,
+			remoteAddr: "@",
+			forwarded:  []string{"203.0.113.1", "203.0.113.2", "198.51.100.9, 203.0.113.1"},
+			wantStatus: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc:       "unix socket | without forwarded address",
+			localAddr:  unix,
+			remoteAddr: "@",
+			forwarded:  []string{"", ""},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			desc:       "tcp | forwarded address not trusted",
+			localAddr:  tcp,
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  []string{"203.0.113.1", "203.0.113.2"},
+			wantStatus: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given: one request per second allowed per client
+			h := router.Routes(router.Config{
+				Timeout:  time.Minute,
+				Settings: router.NewLiveSettings(router.Settings{CORSOrigins: []string{"*"}, RateLimit: 1, RateBurst: 1}),
+			})
+
+			for i, forwarded := range tc.forwarded {
+				ctx := context.WithValue(context.Background(), http.LocalAddrContextKey, tc.localAddr)
+				r := httptest.NewRequestWithContext(ctx, http.MethodGet, "/ping", nil)
+				r.RemoteAddr = tc.remoteAddr
+				if forwarded != "" {
+					r.Header.Set("X-Forwarded-For", forwarded)
+				}
+				w := httptest.NewRecorder()
+
+				// When:
+				h.ServeHTTP(w, r)
+
+				// Then:
+				assert.Equal(t, tc.wantStatus[i], w.Code, "request %d", i)
+			}
+		})
+	}
+}
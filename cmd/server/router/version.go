@@ -0,0 +1,34 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+
+	"go-starter/internal/pkg/buildinfo"
+	"go-starter/internal/pkg/envvar"
+
+	"github.com/alvinchoong/go-httphandler"
+	"github.com/alvinchoong/go-httphandler/jsonresp"
+)
+
+// versionHandler returns the build information. Module dependencies are only
+// listed for admins, so that their versions are not advertised publicly.
+func versionHandler(info buildinfo.Info, adminToken envvar.Secret) func(*http.Request) httphandler.Responder {
+	return func(r *http.Request) httphandler.Responder {
+		if !isAdmin(r, adminToken) {
+			info.Deps = nil
+		}
+		return jsonresp.Success(&info)
+	}
+}
+
+// metricsHandler exposes the build_info gauge in the Prometheus text format
+func metricsHandler(info buildinfo.Info) http.HandlerFunc {
+	var body bytes.Buffer
+	_ = info.WriteMetric(&body)
+
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write(body.Bytes())
+	}
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// errLockNotHeld is returned when checking a lock that was never acquired
+var errLockNotHeld = errors.New("advisory lock not held")
+
+// advisoryLock holds a session-level advisory lock on a dedicated connection.
+// The connection is kept outside of the pool so that leadership does not
+// take a slot away from request handlers.
+type advisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+	conn *pgx.Conn
+}
+
+func newAdvisoryLock(pool *pgxpool.Pool, key int64) *advisoryLock {
+	return &advisoryLock{
+		pool: pool,
+		key:  key,
+		conn: nil,
+	}
+}
+
+// tryAcquire attempts to take the lock without blocking
+func (l *advisoryLock) tryAcquire(ctx context.Context) (bool, error) {
+	if l.conn == nil {
+		conn, err := pgx.ConnectConfig(ctx, l.pool.Config().ConnConfig)
+		if err != nil {
+			return false, fmt.Errorf("pgx.ConnectConfig: %w", err)
+		}
+		l.conn = conn
+	}
+
+	var acquired bool
+	if err := l.conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		// the connection may be broken, start afresh next time
+		l.release(ctx)
+		return false, fmt.Errorf("pg_try_advisory_lock: %w", err)
+	}
+
+	return acquired, nil
+}
+
+// check verifies that the session holding the lock is still alive
+func (l *advisoryLock) check(ctx context.Context) error {
+	if l.conn == nil {
+		return errLockNotHeld
+	}
+	if err := l.conn.Ping(ctx); err != nil {
+		return fmt.Errorf("conn.Ping: %w", err)
+	}
+	return nil
+}
+
+// release drops the lock by closing the session that holds it
+func (l *advisoryLock) release(ctx context.Context) {
+	if l.conn == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_ = l.conn.Close(ctx)
+	l.conn = nil
+}
@@ -0,0 +1,304 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"go-starter/internal/models"
+	"go-starter/internal/pkg/cron"
+	"go-starter/internal/pkg/ptr"
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultLockKey is the advisory lock key used for leader election.
+// Replicas sharing the same database and key elect a single leader.
+const DefaultLockKey int64 = 0x676f2d7363686564 // "go-sched"
+
+// Run statuses recorded in the scheduler_run table
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// JobFunc is the work performed on every tick of a job
+type JobFunc func(ctx context.Context) error
+
+// job is a registered periodic task
+type job struct {
+	name     string
+	schedule cron.Schedule
+	fn       JobFunc
+}
+
+// Scheduler runs periodic jobs on the elected leader among all replicas.
+//
+// Leadership is held through a session-level pg_try_advisory_lock on a
+// dedicated connection. If the leader dies its session ends, the lock is
+// released and another replica takes over on its next election attempt.
+type Scheduler struct {
+	pool             *pgxpool.Pool
+	querier          models.Querier
+	instance         string
+	lockKey          int64
+	electionInterval time.Duration
+	location         *time.Location
+	jobs             []job
+	leader           atomic.Bool
+}
+
+// Option is a function that configures a Scheduler
+type Option func(*Scheduler)
+
+// WithInstance sets the instance name recorded in the run history
+func WithInstance(instance string) Option {
+	return func(s *Scheduler) {
+		s.instance = instance
+	}
+}
+
+// WithLockKey sets the advisory lock key used for leader election
+func WithLockKey(key int64) Option {
+	return func(s *Scheduler) {
+		s.lockKey = key
+	}
+}
+
+// WithElectionInterval sets how often followers try to become leader
+// and how often the leader verifies it still holds the lock
+func WithElectionInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.electionInterval = d
+	}
+}
+
+// WithLocation sets the time zone cron expressions are evaluated in
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) {
+		s.location = loc
+	}
+}
+
+// New creates a scheduler backed by the given pool.
+// Defaults: instance is "<hostname>-<pid>", election every 10s, UTC schedules.
+func New(pool *pgxpool.Pool, q models.Querier, opts ...Option) *Scheduler {
+	hostname, _ := os.Hostname()
+
+	s := &Scheduler{
+		pool:             pool,
+		querier:          q,
+		instance:         hostname + "-" + strconv.Itoa(os.Getpid()),
+		lockKey:          DefaultLockKey,
+		electionInterval: 10 * time.Second,
+		location:         time.UTC,
+		jobs:             nil,
+		leader:           atomic.Bool{},
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return s
+}
+
+// Register adds a job that runs according to the cron spec.
+// It must be called before Run.
+func (s *Scheduler) Register(name string, spec string, fn JobFunc) error {
+	schedule, err := cron.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("cron.Parse: %w", err)
+	}
+
+	for _, j := range s.jobs {
+		if j.name == name {
+			return fmt.Errorf("job %q already registered", name)
+		}
+	}
+
+	s.jobs = append(s.jobs, job{name: name, schedule: schedule, fn: fn})
+	return nil
+}
+
+// IsLeader reports whether this instance currently runs the jobs
+func (s *Scheduler) IsLeader() bool {
+	return s.leader.Load()
+}
+
+// Run takes part in leader election and fires jobs while leader.
+// It blocks until the context is canceled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	logger := slogr.FromContext(ctx).With(slog.String("instance", s.instance))
+	ctx = slogr.ToContext(ctx, logger)
+
+	logger.Info("[scheduler] starting", slog.Int("jobs", len(s.jobs)))
+
+	g, gctx := errgroup.WithContext(ctx)
+
+	g.Go(func() error {
+		return s.elect(gctx)
+	})
+
+	for _, j := range s.jobs {
+		g.Go(func() error {
+			s.loop(gctx, j)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("errgroup.Wait: %w", err)
+	}
+
+	logger.Info("[scheduler] stopped")
+	return nil
+}
+
+// elect periodically campaigns for leadership until the context is canceled
+func (s *Scheduler) elect(ctx context.Context) error {
+	lock := newAdvisoryLock(s.pool, s.lockKey)
+	defer func() {
+		s.leader.Store(false)
+		lock.release(context.WithoutCancel(ctx))
+	}()
+
+	ticker := time.NewTicker(s.electionInterval)
+	defer ticker.Stop()
+
+	for {
+		s.campaign(ctx, lock)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign tries to become leader, or verifies that leadership is still held
+func (s *Scheduler) campaign(ctx context.Context, lock *advisoryLock) {
+	logger := slogr.FromContext(ctx)
+
+	if s.leader.Load() {
+		if err := lock.check(ctx); err != nil {
+			s.leader.Store(false)
+			lock.release(ctx)
+			logger.Warn("[scheduler] lost leadership", slog.Any("err", err))
+		}
+		return
+	}
+
+	acquired, err := lock.tryAcquire(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Warn("[scheduler] election failed", slog.Any("err", err))
+		}
+		return
+	}
+	if acquired {
+		s.leader.Store(true)
+		logger.Info("[scheduler] elected leader")
+	}
+}
+
+// loop waits for each tick of the job and runs it if this instance is leader
+func (s *Scheduler) loop(ctx context.Context, j job) {
+	logger := slogr.FromContext(ctx).With(slog.String("job", j.name))
+	ctx = slogr.ToContext(ctx, logger)
+
+	for {
+		next := j.schedule.Next(time.Now().In(s.location))
+		if next.IsZero() {
+			logger.Warn("[scheduler] job will never run again")
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if !s.leader.Load() {
+			continue
+		}
+
+		s.execute(ctx, j, next)
+	}
+}
+
+// execute runs a single tick of the job and records it in the run history.
+//
+// The tick is claimed first by inserting its run, unique per job and
+// scheduled time: leadership is only verified every election interval, so a
+// leader that lost its session may still fire the tick the new leader fires.
+func (s *Scheduler) execute(ctx context.Context, j job, scheduledAt time.Time) {
+	logger := slogr.FromContext(ctx)
+
+	run, err := s.querier.CreateSchedulerRun(ctx, s.pool, models.CreateSchedulerRunParams{
+		ID:          uuid.New(),
+		JobName:     j.name,
+		Instance:    s.instance,
+		ScheduledAt: scheduledAt,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		logger.Info("[scheduler] run already claimed by another instance", slog.Time("scheduled-at", scheduledAt))
+		return
+	}
+	if err != nil {
+		logger.Error("[scheduler] failed to claim run, skipped", slog.Any("err", err))
+		return
+	}
+
+	start := time.Now()
+	err = safeRun(ctx, j.fn)
+	duration := time.Since(start)
+
+	status := StatusSucceeded
+	var errMsg *string
+	if err != nil {
+		status = StatusFailed
+		errMsg = ptr.Ref(err.Error())
+		logger.Error("[scheduler] job failed", slog.Duration("duration", duration), slog.Any("err", err))
+	} else {
+		logger.Info("[scheduler] job succeeded", slog.Duration("duration", duration))
+	}
+
+	// record the result even if the job was interrupted by shutdown
+	finishCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+
+	durationMs := duration.Milliseconds()
+	if _, err := s.querier.FinishSchedulerRun(finishCtx, s.pool, models.FinishSchedulerRunParams{
+		ID:         run.ID,
+		DurationMs: &durationMs,
+		Status:     status,
+		Error:      errMsg,
+	}); err != nil {
+		logger.Error("[scheduler] failed to record run result", slog.Any("err", err))
+	}
+}
+
+// safeRun calls fn and converts a panic into an error
+func safeRun(ctx context.Context, fn JobFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return fn(ctx)
+}
@@ -0,0 +1,9 @@
+// Package database embeds the SQL migrations, so that the server binary can apply them
+package database
+
+import "embed"
+
+// Migrations holds the migrations/*.sql files, see the migrate package
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
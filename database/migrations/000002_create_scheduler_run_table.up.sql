@@ -0,0 +1,14 @@
+CREATE TABLE scheduler_run (
+  id uuid PRIMARY KEY,
+  job_name TEXT NOT NULL,
+  instance TEXT NOT NULL,
+  scheduled_at timestamptz NOT NULL,
+  started_at timestamptz NOT NULL DEFAULT NOW(),
+  finished_at timestamptz,
+  duration_ms BIGINT,
+  status TEXT NOT NULL,
+  error TEXT
+);
+
+CREATE INDEX scheduler_run_job_name_started_at_idx ON scheduler_run (job_name, started_at DESC);
+CREATE INDEX scheduler_run_started_at_idx ON scheduler_run (started_at DESC);
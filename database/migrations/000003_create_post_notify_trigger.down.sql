@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS post_notify_change ON post;
+DROP FUNCTION IF EXISTS notify_post_change();
+DROP SEQUENCE IF EXISTS post_event_seq;
@@ -0,0 +1,27 @@
+-- post_event_seq gives every change a global, monotonically increasing id
+-- so that SSE clients can resume from any replica via Last-Event-ID
+CREATE SEQUENCE post_event_seq;
+
+CREATE FUNCTION notify_post_change() RETURNS trigger AS $$
+DECLARE
+  changed_id uuid;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed_id := OLD.id;
+  ELSE
+    changed_id := NEW.id;
+  END IF;
+
+  PERFORM pg_notify('post_changes', json_build_object(
+    'id', nextval('post_event_seq'),
+    'op', lower(TG_OP),
+    'post_id', changed_id
+  )::text);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_notify_change
+AFTER INSERT OR UPDATE OR DELETE ON post
+FOR EACH ROW EXECUTE FUNCTION notify_post_change();
@@ -0,0 +1,16 @@
+-- quote is served by the quote proxy when every upstream provider is down
+CREATE TABLE quote (
+  id SERIAL PRIMARY KEY,
+  quote TEXT NOT NULL,
+  author TEXT NOT NULL
+);
+
+INSERT INTO quote (quote, author) VALUES
+  ('Life is what happens when you''re busy making other plans.', 'John Lennon'),
+  ('Simplicity is prerequisite for reliability.', 'Edsger W. Dijkstra'),
+  ('The best way to predict the future is to invent it.', 'Alan Kay'),
+  ('Premature optimization is the root of all evil.', 'Donald Knuth'),
+  ('Talk is cheap. Show me the code.', 'Linus Torvalds'),
+  ('Clear is better than clever.', 'Rob Pike'),
+  ('Make it work, make it right, make it fast.', 'Kent Beck'),
+  ('Any fool can write code that a computer can understand. Good programmers write code that humans can understand.', 'Martin Fowler');
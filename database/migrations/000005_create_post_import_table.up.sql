@@ -0,0 +1,12 @@
+-- post_import stages the rows of an import, copied with COPY and then upserted
+-- into post in the same transaction, which deletes them before committing
+CREATE UNLOGGED TABLE post_import (
+  import_id uuid NOT NULL,
+  id uuid NOT NULL,
+  title TEXT NOT NULL,
+  description TEXT,
+  created_at timestamptz,
+  updated_at timestamptz
+);
+
+CREATE INDEX post_import_import_id_idx ON post_import (import_id);
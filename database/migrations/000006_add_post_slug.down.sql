@@ -0,0 +1,5 @@
+ALTER TABLE post_import DROP COLUMN IF EXISTS slug;
+DROP TRIGGER IF EXISTS post_slug_change ON post;
+DROP FUNCTION IF EXISTS record_post_slug_change;
+DROP TABLE IF EXISTS post_slug_history;
+ALTER TABLE post DROP COLUMN IF EXISTS slug;
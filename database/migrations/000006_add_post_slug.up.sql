@@ -0,0 +1,61 @@
+-- Existing posts get an ASCII slug of their title, the application
+-- transliterates the titles of new and renamed posts. Duplicates are
+-- suffixed with the start of their id. Like in the application, slugs that
+-- parse as UUIDs or name a static route such as /api/v1/posts/stream are
+-- prefixed with "post-".
+ALTER TABLE post ADD COLUMN slug TEXT;
+
+WITH made AS (
+  SELECT
+    id,
+    created_at,
+    COALESCE(NULLIF(trim(BOTH '-' FROM left(regexp_replace(lower(title), '[^a-z0-9]+', '-', 'g'), 80)), ''), 'post') AS slug
+  FROM post
+), base AS (
+  SELECT
+    id,
+    created_at,
+    CASE
+      WHEN slug ~ '^([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}|[0-9a-f]{32})$'
+        OR slug IN ('stream') THEN 'post-' || slug
+      ELSE slug
+    END AS slug
+  FROM made
+), numbered AS (
+  SELECT id, slug, row_number() OVER (PARTITION BY slug ORDER BY created_at, id) AS n
+  FROM base
+)
+UPDATE post SET slug = CASE WHEN numbered.n = 1 THEN numbered.slug ELSE numbered.slug || '-' || left(post.id::text, 8) END
+FROM numbered
+WHERE post.id = numbered.id;
+
+ALTER TABLE post ALTER COLUMN slug SET NOT NULL;
+ALTER TABLE post ADD CONSTRAINT post_slug_key UNIQUE (slug);
+
+-- post_slug_history keeps the former slugs of renamed posts, which redirect
+-- to their current slug
+CREATE TABLE post_slug_history (
+  slug TEXT PRIMARY KEY,
+  post_id uuid NOT NULL REFERENCES post (id) ON DELETE CASCADE,
+  created_at timestamptz NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX post_slug_history_post_id_idx ON post_slug_history (post_id);
+
+-- A post taking one of its former slugs back removes it from the history
+CREATE FUNCTION record_post_slug_change() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM post_slug_history WHERE slug = NEW.slug;
+  INSERT INTO post_slug_history (slug, post_id) VALUES (OLD.slug, OLD.id)
+  ON CONFLICT (slug) DO UPDATE SET post_id = EXCLUDED.post_id, created_at = NOW();
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER post_slug_change
+AFTER UPDATE OF slug ON post
+FOR EACH ROW WHEN (OLD.slug IS DISTINCT FROM NEW.slug)
+EXECUTE FUNCTION record_post_slug_change();
+
+-- Imported posts are staged with the slug given by the application
+ALTER TABLE post_import ADD COLUMN slug TEXT NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE scheduler_run DROP CONSTRAINT IF EXISTS scheduler_run_job_name_scheduled_at_key;
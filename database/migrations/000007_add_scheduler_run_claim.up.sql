@@ -0,0 +1,10 @@
+-- A run is claimed by inserting it, so that a single replica runs each tick
+-- of a job, even when two of them believe they are leader
+DELETE FROM scheduler_run a
+USING scheduler_run b
+WHERE a.job_name = b.job_name
+  AND a.scheduled_at = b.scheduled_at
+  AND (a.started_at, a.id) > (b.started_at, b.id);
+
+ALTER TABLE scheduler_run
+  ADD CONSTRAINT scheduler_run_job_name_scheduled_at_key UNIQUE (job_name, scheduled_at);
@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION notify_post_change() RETURNS trigger AS $$
+DECLARE
+  changed_id uuid;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed_id := OLD.id;
+  ELSE
+    changed_id := NEW.id;
+  END IF;
+
+  PERFORM pg_notify('post_changes', json_build_object(
+    'id', nextval('post_event_seq'),
+    'op', lower(TG_OP),
+    'post_id', changed_id
+  )::text);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
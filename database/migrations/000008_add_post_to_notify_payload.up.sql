@@ -0,0 +1,35 @@
+-- The changed post is sent in the payload, so that listeners do not load it
+-- on every notification. Posts too large for a NOTIFY payload, limited to
+-- 8000 bytes, are sent without it and loaded by the listeners.
+CREATE OR REPLACE FUNCTION notify_post_change() RETURNS trigger AS $$
+DECLARE
+  changed_id uuid;
+  payload text;
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    changed_id := OLD.id;
+  ELSE
+    changed_id := NEW.id;
+  END IF;
+
+  payload := json_build_object(
+    'id', nextval('post_event_seq'),
+    'op', lower(TG_OP),
+    'post_id', changed_id
+  )::text;
+
+  IF TG_OP <> 'DELETE' THEN
+    DECLARE
+      with_post text := (payload::jsonb || jsonb_build_object('post', row_to_json(NEW)::jsonb))::text;
+    BEGIN
+      IF octet_length(with_post) < 8000 THEN
+        payload := with_post;
+      END IF;
+    END;
+  END IF;
+
+  PERFORM pg_notify('post_changes', payload);
+
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
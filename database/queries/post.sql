@@ -1,15 +1,20 @@
 -- name: CreatePost :one
-INSERT INTO post (id, title, description)
-VALUES ($1, $2, $3)
-RETURNING id, title, description, created_at, updated_at;
+INSERT INTO post (id, title, description, slug)
+VALUES ($1, $2, $3, $4)
+RETURNING id, title, description, created_at, updated_at, slug;
 
 -- name: GetPost :one
-SELECT id, title, description, created_at, updated_at
+SELECT id, title, description, created_at, updated_at, slug
 FROM post
 WHERE id = $1;
 
+-- name: GetPostBySlug :one
+SELECT id, title, description, created_at, updated_at, slug
+FROM post
+WHERE slug = $1;
+
 -- name: ListPosts :many
-SELECT id, title, description, created_at, updated_at
+SELECT id, title, description, created_at, updated_at, slug
 FROM post
 ORDER BY created_at DESC;
 
@@ -17,9 +22,10 @@ ORDER BY created_at DESC;
 UPDATE post SET
   title = $2,
   description = $3,
+  slug = $4,
   updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, description, created_at, updated_at;
+RETURNING id, title, description, created_at, updated_at, slug;
 
 -- name: DeletePost :execrows
 DELETE FROM post WHERE id = $1;
@@ -0,0 +1,31 @@
+-- name: CopyPostImport :copyfrom
+INSERT INTO post_import (import_id, id, title, description, created_at, updated_at, slug)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: UpsertPostImport :one
+WITH upserted AS (
+  INSERT INTO post (id, title, description, created_at, updated_at, slug)
+  SELECT id, title, description, COALESCE(created_at, NOW()), COALESCE(updated_at, NOW()), slug
+  FROM post_import
+  WHERE import_id = $1
+  ON CONFLICT (id) DO UPDATE SET
+    title = EXCLUDED.title,
+    description = EXCLUDED.description,
+    slug = EXCLUDED.slug,
+    updated_at = EXCLUDED.updated_at
+  RETURNING (xmax = 0) AS inserted
+)
+SELECT
+  COUNT(*) FILTER (WHERE inserted) AS inserted,
+  COUNT(*) FILTER (WHERE NOT inserted) AS updated
+FROM upserted;
+
+-- name: InsertPostImport :execrows
+INSERT INTO post (id, title, description, created_at, updated_at, slug)
+SELECT id, title, description, COALESCE(created_at, NOW()), COALESCE(updated_at, NOW()), slug
+FROM post_import
+WHERE import_id = $1
+ON CONFLICT (id) DO NOTHING;
+
+-- name: DeletePostImport :execrows
+DELETE FROM post_import WHERE import_id = $1;
@@ -0,0 +1,14 @@
+-- name: GetCurrentPostSlug :one
+SELECT post.slug
+FROM post_slug_history
+JOIN post ON post.id = post_slug_history.post_id
+WHERE post_slug_history.slug = @former_slug;
+
+-- name: ListPostSlugs :many
+SELECT slug, id AS post_id
+FROM post
+WHERE slug LIKE ANY(@patterns::text[])
+UNION ALL
+SELECT slug, post_id
+FROM post_slug_history
+WHERE slug LIKE ANY(@patterns::text[]);
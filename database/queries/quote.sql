@@ -0,0 +1,5 @@
+-- name: GetRandomQuote :one
+SELECT id, quote, author
+FROM quote
+ORDER BY random()
+LIMIT 1;
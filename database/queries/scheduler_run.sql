@@ -0,0 +1,24 @@
+-- name: CreateSchedulerRun :one
+INSERT INTO scheduler_run (id, job_name, instance, scheduled_at, status)
+VALUES ($1, $2, $3, $4, 'running')
+ON CONFLICT (job_name, scheduled_at) DO NOTHING
+RETURNING id, job_name, instance, scheduled_at, started_at, finished_at, duration_ms, status, error;
+
+-- name: FinishSchedulerRun :one
+UPDATE scheduler_run SET
+  finished_at = NOW(),
+  duration_ms = $2,
+  status = $3,
+  error = $4
+WHERE id = $1
+RETURNING id, job_name, instance, scheduled_at, started_at, finished_at, duration_ms, status, error;
+
+-- name: ListSchedulerRuns :many
+SELECT id, job_name, instance, scheduled_at, started_at, finished_at, duration_ms, status, error
+FROM scheduler_run
+WHERE (sqlc.narg('job_name')::text IS NULL OR job_name = sqlc.narg('job_name')::text)
+ORDER BY started_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: DeleteSchedulerRunsBefore :execrows
+DELETE FROM scheduler_run WHERE started_at < $1;
@@ -2,10 +2,12 @@ package mocks
 
 import (
 	"context"
+	"time"
 
 	"go-starter/internal/models"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -24,6 +26,12 @@ func (m *Querier) ListPosts(ctx context.Context, db models.DBTX) ([]models.Post,
 	return args.Get(0).([]models.Post), args.Error(1)
 }
 
+func (m *Querier) ListPostsRows(ctx context.Context, db models.DBTX) (pgx.Rows, error) {
+	args := m.Called(ctx, db)
+	rows, _ := args.Get(0).(pgx.Rows)
+	return rows, args.Error(1)
+}
+
 func (m *Querier) GetPost(ctx context.Context, db models.DBTX, id uuid.UUID) (models.Post, error) {
 	args := m.Called(ctx, db, id)
 	return args.Get(0).(models.Post), args.Error(1)
@@ -38,3 +46,63 @@ func (m *Querier) DeletePost(ctx context.Context, db models.DBTX, id uuid.UUID)
 	args := m.Called(ctx, db, id)
 	return args.Get(0).(int64), args.Error(1)
 }
+
+func (m *Querier) CreateSchedulerRun(ctx context.Context, db models.DBTX, params models.CreateSchedulerRunParams) (models.SchedulerRun, error) {
+	args := m.Called(ctx, db, params)
+	return args.Get(0).(models.SchedulerRun), args.Error(1)
+}
+
+func (m *Querier) FinishSchedulerRun(ctx context.Context, db models.DBTX, params models.FinishSchedulerRunParams) (models.SchedulerRun, error) {
+	args := m.Called(ctx, db, params)
+	return args.Get(0).(models.SchedulerRun), args.Error(1)
+}
+
+func (m *Querier) ListSchedulerRuns(ctx context.Context, db models.DBTX, params models.ListSchedulerRunsParams) ([]models.SchedulerRun, error) {
+	args := m.Called(ctx, db, params)
+	return args.Get(0).([]models.SchedulerRun), args.Error(1)
+}
+
+func (m *Querier) DeleteSchedulerRunsBefore(ctx context.Context, db models.DBTX, startedAt time.Time) (int64, error) {
+	args := m.Called(ctx, db, startedAt)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *Querier) GetRandomQuote(ctx context.Context, db models.DBTX) (models.Quote, error) {
+	args := m.Called(ctx, db)
+	return args.Get(0).(models.Quote), args.Error(1)
+}
+
+func (m *Querier) CopyPostImport(ctx context.Context, db models.DBTX, rows []models.CopyPostImportParams) (int64, error) {
+	args := m.Called(ctx, db, rows)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *Querier) UpsertPostImport(ctx context.Context, db models.DBTX, importID uuid.UUID) (models.UpsertPostImportRow, error) {
+	args := m.Called(ctx, db, importID)
+	return args.Get(0).(models.UpsertPostImportRow), args.Error(1)
+}
+
+func (m *Querier) InsertPostImport(ctx context.Context, db models.DBTX, importID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, db, importID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *Querier) DeletePostImport(ctx context.Context, db models.DBTX, importID uuid.UUID) (int64, error) {
+	args := m.Called(ctx, db, importID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *Querier) GetPostBySlug(ctx context.Context, db models.DBTX, slug string) (models.Post, error) {
+	args := m.Called(ctx, db, slug)
+	return args.Get(0).(models.Post), args.Error(1)
+}
+
+func (m *Querier) GetCurrentPostSlug(ctx context.Context, db models.DBTX, formerSlug string) (string, error) {
+	args := m.Called(ctx, db, formerSlug)
+	return args.String(0), args.Error(1)
+}
+
+func (m *Querier) ListPostSlugs(ctx context.Context, db models.DBTX, patterns []string) ([]models.ListPostSlugsRow, error) {
+	args := m.Called(ctx, db, patterns)
+	return args.Get(0).([]models.ListPostSlugsRow), args.Error(1)
+}
@@ -16,4 +16,39 @@ type Post struct {
 	Description *string   `json:"description"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Slug        string    `json:"slug"`
+}
+
+type PostImport struct {
+	ImportID    uuid.UUID  `json:"import_id"`
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Description *string    `json:"description"`
+	CreatedAt   *time.Time `json:"created_at"`
+	UpdatedAt   *time.Time `json:"updated_at"`
+	Slug        string     `json:"slug"`
+}
+
+type PostSlugHistory struct {
+	Slug      string    `json:"slug"`
+	PostID    uuid.UUID `json:"post_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Quote struct {
+	ID     int32  `json:"id"`
+	Quote  string `json:"quote"`
+	Author string `json:"author"`
+}
+
+type SchedulerRun struct {
+	ID          uuid.UUID  `json:"id"`
+	JobName     string     `json:"job_name"`
+	Instance    string     `json:"instance"`
+	ScheduledAt time.Time  `json:"scheduled_at"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at"`
+	DurationMs  *int64     `json:"duration_ms"`
+	Status      string     `json:"status"`
+	Error       *string    `json:"error"`
 }
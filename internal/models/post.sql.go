@@ -12,19 +12,25 @@ import (
 )
 
 const CreatePost = `-- name: CreatePost :one
-INSERT INTO post (id, title, description)
-VALUES ($1, $2, $3)
-RETURNING id, title, description, created_at, updated_at
+INSERT INTO post (id, title, description, slug)
+VALUES ($1, $2, $3, $4)
+RETURNING id, title, description, created_at, updated_at, slug
 `
 
 type CreatePostParams struct {
 	ID          uuid.UUID `json:"id"`
 	Title       string    `json:"title"`
 	Description *string   `json:"description"`
+	Slug        string    `json:"slug"`
 }
 
 func (q *Queries) CreatePost(ctx context.Context, db DBTX, arg CreatePostParams) (Post, error) {
-	row := db.QueryRow(ctx, CreatePost, arg.ID, arg.Title, arg.Description)
+	row := db.QueryRow(ctx, CreatePost,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.Slug,
+	)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -32,6 +38,7 @@ func (q *Queries) CreatePost(ctx context.Context, db DBTX, arg CreatePostParams)
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
@@ -49,7 +56,7 @@ func (q *Queries) DeletePost(ctx context.Context, db DBTX, id uuid.UUID) (int64,
 }
 
 const GetPost = `-- name: GetPost :one
-SELECT id, title, description, created_at, updated_at
+SELECT id, title, description, created_at, updated_at, slug
 FROM post
 WHERE id = $1
 `
@@ -63,12 +70,33 @@ func (q *Queries) GetPost(ctx context.Context, db DBTX, id uuid.UUID) (Post, err
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
+	)
+	return i, err
+}
+
+const GetPostBySlug = `-- name: GetPostBySlug :one
+SELECT id, title, description, created_at, updated_at, slug
+FROM post
+WHERE slug = $1
+`
+
+func (q *Queries) GetPostBySlug(ctx context.Context, db DBTX, slug string) (Post, error) {
+	row := db.QueryRow(ctx, GetPostBySlug, slug)
+	var i Post
+	err := row.Scan(
+		&i.ID,
+		&i.Title,
+		&i.Description,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
 
 const ListPosts = `-- name: ListPosts :many
-SELECT id, title, description, created_at, updated_at
+SELECT id, title, description, created_at, updated_at, slug
 FROM post
 ORDER BY created_at DESC
 `
@@ -88,6 +116,7 @@ func (q *Queries) ListPosts(ctx context.Context, db DBTX) ([]Post, error) {
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Slug,
 		); err != nil {
 			return nil, err
 		}
@@ -103,19 +132,26 @@ const UpdatePost = `-- name: UpdatePost :one
 UPDATE post SET
   title = $2,
   description = $3,
+  slug = $4,
   updated_at = NOW()
 WHERE id = $1
-RETURNING id, title, description, created_at, updated_at
+RETURNING id, title, description, created_at, updated_at, slug
 `
 
 type UpdatePostParams struct {
 	ID          uuid.UUID `json:"id"`
 	Title       string    `json:"title"`
 	Description *string   `json:"description"`
+	Slug        string    `json:"slug"`
 }
 
 func (q *Queries) UpdatePost(ctx context.Context, db DBTX, arg UpdatePostParams) (Post, error) {
-	row := db.QueryRow(ctx, UpdatePost, arg.ID, arg.Title, arg.Description)
+	row := db.QueryRow(ctx, UpdatePost,
+		arg.ID,
+		arg.Title,
+		arg.Description,
+		arg.Slug,
+	)
 	var i Post
 	err := row.Scan(
 		&i.ID,
@@ -123,6 +159,7 @@ func (q *Queries) UpdatePost(ctx context.Context, db DBTX, arg UpdatePostParams)
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Slug,
 	)
 	return i, err
 }
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RowsQuerier extends Querier with queries whose rows are read one at a time,
+// which sqlc does not generate
+type RowsQuerier interface {
+	Querier
+	ListPostsRows(ctx context.Context, db DBTX) (pgx.Rows, error)
+}
+
+var _ RowsQuerier = (*Queries)(nil)
+
+// ListPostsRows runs ListPosts and returns its rows unread, for streaming
+// every post without loading them all. The caller closes the rows.
+func (q *Queries) ListPostsRows(ctx context.Context, db DBTX) (pgx.Rows, error) {
+	rows, err := db.Query(ctx, ListPosts)
+	if err != nil {
+		return nil, fmt.Errorf("db.Query: %w", err)
+	}
+	return rows, nil
+}
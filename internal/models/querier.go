@@ -6,16 +6,29 @@ package models
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Querier interface {
+	CopyPostImport(ctx context.Context, db DBTX, arg []CopyPostImportParams) (int64, error)
 	CreatePost(ctx context.Context, db DBTX, arg CreatePostParams) (Post, error)
+	CreateSchedulerRun(ctx context.Context, db DBTX, arg CreateSchedulerRunParams) (SchedulerRun, error)
 	DeletePost(ctx context.Context, db DBTX, id uuid.UUID) (int64, error)
+	DeletePostImport(ctx context.Context, db DBTX, importID uuid.UUID) (int64, error)
+	DeleteSchedulerRunsBefore(ctx context.Context, db DBTX, startedAt time.Time) (int64, error)
+	FinishSchedulerRun(ctx context.Context, db DBTX, arg FinishSchedulerRunParams) (SchedulerRun, error)
+	GetCurrentPostSlug(ctx context.Context, db DBTX, formerSlug string) (string, error)
 	GetPost(ctx context.Context, db DBTX, id uuid.UUID) (Post, error)
+	GetPostBySlug(ctx context.Context, db DBTX, slug string) (Post, error)
+	GetRandomQuote(ctx context.Context, db DBTX) (Quote, error)
+	InsertPostImport(ctx context.Context, db DBTX, importID uuid.UUID) (int64, error)
+	ListPostSlugs(ctx context.Context, db DBTX, patterns []string) ([]ListPostSlugsRow, error)
 	ListPosts(ctx context.Context, db DBTX) ([]Post, error)
+	ListSchedulerRuns(ctx context.Context, db DBTX, arg ListSchedulerRunsParams) ([]SchedulerRun, error)
 	UpdatePost(ctx context.Context, db DBTX, arg UpdatePostParams) (Post, error)
+	UpsertPostImport(ctx context.Context, db DBTX, importID uuid.UUID) (UpsertPostImportRow, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -0,0 +1,203 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned when the breaker rejects a call without attempting it
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// Closed lets every call through and counts consecutive failures
+	Closed State = iota
+	// Open rejects every call until the open timeout elapses
+	Open
+	// HalfOpen lets a limited number of probes through to test recovery
+	HalfOpen
+)
+
+// String implements fmt.Stringer
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Breaker is a circuit breaker that opens after consecutive failures and
+// probes the dependency again after a cool-down period.
+type Breaker struct {
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	now              func() time.Time
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	inFlight int    // probes in flight while half-open
+	gen      uint64 // incremented on every state change, calls are recorded in the state they were allowed in
+}
+
+// Option is a function that configures a Breaker
+type Option func(*Breaker)
+
+// WithFailureThreshold sets the number of consecutive failures that opens the breaker
+func WithFailureThreshold(n int) Option {
+	return func(b *Breaker) {
+		b.failureThreshold = n
+	}
+}
+
+// WithOpenTimeout sets how long the breaker stays open before probing
+func WithOpenTimeout(d time.Duration) Option {
+	return func(b *Breaker) {
+		b.openTimeout = d
+	}
+}
+
+// WithHalfOpenProbes sets how many concurrent probes are allowed while half-open
+func WithHalfOpenProbes(n int) Option {
+	return func(b *Breaker) {
+		b.halfOpenProbes = n
+	}
+}
+
+// WithClock sets the time source, useful for tests
+func WithClock(now func() time.Time) Option {
+	return func(b *Breaker) {
+		b.now = now
+	}
+}
+
+// New creates a closed breaker.
+// Defaults: opens after 5 consecutive failures, probes after 30s with 1 probe.
+func New(opts ...Option) *Breaker {
+	b := &Breaker{
+		failureThreshold: 5,
+		openTimeout:      30 * time.Second,
+		halfOpenProbes:   1,
+		now:              time.Now,
+		mu:               sync.Mutex{},
+		state:            Closed,
+		failures:         0,
+		openedAt:         time.Time{},
+		inFlight:         0,
+		gen:              0,
+	}
+	for _, o := range opts {
+		o(b)
+	}
+
+	return b
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.currentState()
+}
+
+// currentState moves an open breaker to half-open once the timeout elapsed.
+// Must hold b.mu.
+func (b *Breaker) currentState() State {
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.openTimeout {
+		b.state = HalfOpen
+		b.inFlight = 0
+		b.gen++
+	}
+	return b.state
+}
+
+// Do calls fn if the breaker allows it and records the outcome.
+// Errors caused by the caller giving up (context.Canceled) are not counted as failures.
+func (b *Breaker) Do(fn func() error) error {
+	gen, err := b.allow()
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+	b.record(gen, err == nil || errors.Is(err, context.Canceled))
+
+	return err
+}
+
+// allow reports whether a call may proceed, and returns the generation of
+// the state it is allowed in
+func (b *Breaker) allow() (uint64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case Open:
+		return 0, ErrOpen
+	case HalfOpen:
+		if b.inFlight >= b.halfOpenProbes {
+			return 0, ErrOpen
+		}
+		b.inFlight++
+	case Closed:
+	}
+
+	return b.gen, nil
+}
+
+// record updates the state with the outcome of a call allowed in generation
+// gen. Outcomes of calls allowed before the state changed are ignored, e.g. a
+// slow call allowed while closed is no probe of a half-open breaker.
+func (b *Breaker) record(gen uint64, success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if gen != b.gen {
+		return
+	}
+
+	switch b.state {
+	case HalfOpen:
+		b.inFlight--
+		if success {
+			b.state = Closed
+			b.failures = 0
+			b.gen++
+			return
+		}
+		b.trip()
+	case Closed:
+		if success {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.trip()
+		}
+	case Open:
+		// unreachable, no call is allowed while open
+	}
+}
+
+// trip opens the breaker. Must hold b.mu.
+func (b *Breaker) trip() {
+	b.state = Open
+	b.openedAt = b.now()
+	b.failures = 0
+	b.inFlight = 0
+	b.gen++
+}
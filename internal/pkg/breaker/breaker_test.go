@@ -0,0 +1,136 @@
+package breaker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/breaker"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	errFail := errors.New("fail")
+	succeed := func() error { return nil }
+	fail := func() error { return errFail }
+
+	// Given:
+	now := time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)
+	b := breaker.New(
+		breaker.WithFailureThreshold(3),
+		breaker.WithOpenTimeout(10*time.Second),
+		breaker.WithClock(func() time.Time { return now }),
+	)
+
+	// When: failures below the threshold
+	require.ErrorIs(t, b.Do(fail), errFail)
+	require.ErrorIs(t, b.Do(fail), errFail)
+	require.NoError(t, b.Do(succeed))
+	require.ErrorIs(t, b.Do(fail), errFail)
+	require.ErrorIs(t, b.Do(fail), errFail)
+
+	// Then: a success in between resets the count
+	assert.Equal(t, breaker.Closed, b.State())
+
+	// When: the threshold is reached
+	require.ErrorIs(t, b.Do(fail), errFail)
+
+	// Then: calls are rejected without being made
+	assert.Equal(t, breaker.Open, b.State())
+	called := false
+	require.ErrorIs(t, b.Do(func() error { called = true; return nil }), breaker.ErrOpen)
+	assert.False(t, called)
+
+	// When: the open timeout elapses and the probe fails
+	now = now.Add(10 * time.Second)
+	assert.Equal(t, breaker.HalfOpen, b.State())
+	require.ErrorIs(t, b.Do(fail), errFail)
+
+	// Then: the breaker opens again
+	assert.Equal(t, breaker.Open, b.State())
+
+	// When: the open timeout elapses and the probe succeeds
+	now = now.Add(10 * time.Second)
+	require.NoError(t, b.Do(succeed))
+
+	// Then:
+	assert.Equal(t, breaker.Closed, b.State())
+}
+
+func TestBreaker_HalfOpenProbes(t *testing.T) {
+	t.Parallel()
+
+	// Given: a half-open breaker allowing a single probe
+	now := time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)
+	b := breaker.New(
+		breaker.WithFailureThreshold(1),
+		breaker.WithOpenTimeout(time.Second),
+		breaker.WithHalfOpenProbes(1),
+		breaker.WithClock(func() time.Time { return now }),
+	)
+	require.Error(t, b.Do(func() error { return errors.New("fail") }))
+	now = now.Add(time.Second)
+
+	// When: a second call arrives while the probe is in flight
+	var concurrent error
+	err := b.Do(func() error {
+		concurrent = b.Do(func() error { return nil })
+		return nil
+	})
+
+	// Then:
+	require.NoError(t, err)
+	require.ErrorIs(t, concurrent, breaker.ErrOpen)
+	assert.Equal(t, breaker.Closed, b.State())
+}
+
+func TestBreaker_IgnoresCanceled(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	b := breaker.New(breaker.WithFailureThreshold(1))
+
+	// When:
+	err := b.Do(func() error { return context.Canceled })
+
+	// Then:
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, breaker.Closed, b.State())
+}
+
+func TestBreaker_IgnoresEarlierState(t *testing.T) {
+	t.Parallel()
+
+	// Given: a slow call allowed while closed
+	now := time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)
+	b := breaker.New(
+		breaker.WithFailureThreshold(1),
+		breaker.WithOpenTimeout(time.Second),
+		breaker.WithHalfOpenProbes(1),
+		breaker.WithClock(func() time.Time { return now }),
+	)
+
+	// When: it succeeds once another call tripped the breaker and it went half-open
+	err := b.Do(func() error {
+		_ = b.Do(func() error { return errors.New("fail") })
+		now = now.Add(time.Second)
+		assert.Equal(t, breaker.HalfOpen, b.State())
+		return nil
+	})
+
+	// Then: it is no probe, the breaker still allows a single one
+	require.NoError(t, err)
+	assert.Equal(t, breaker.HalfOpen, b.State())
+	var concurrent error
+	require.NoError(t, b.Do(func() error {
+		concurrent = b.Do(func() error { return nil })
+		return nil
+	}))
+	require.ErrorIs(t, concurrent, breaker.ErrOpen)
+	assert.Equal(t, breaker.Closed, b.State())
+}
@@ -1,7 +1,125 @@
 package buildinfo
 
+import (
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
 // These variables are set during build time via -ldflags
 var (
 	Version   string
 	BuildTime string
 )
+
+// Info describes the build of the running binary
+type Info struct {
+	Version   string `json:"version"`    // From -ldflags, the module version or the VCS revision
+	BuildTime string `json:"build_time"` // From -ldflags or the time of the VCS revision
+	Revision  string `json:"revision"`   // VCS revision, e.g. the git commit
+	Modified  bool   `json:"modified"`   // Whether the working tree had uncommitted changes
+	GoVersion string `json:"go_version"`
+	Path      string `json:"path"`           // Main package path
+	Deps      []Dep  `json:"deps,omitempty"` // Module dependencies
+}
+
+// Dep is a module dependency
+type Dep struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	Replace string `json:"replace,omitempty"` // Replacement module path and version, if any
+}
+
+// Get returns the build information of the running binary. Values set with
+// -ldflags take precedence over those recorded by the Go toolchain.
+var Get = sync.OnceValue(func() Info {
+	bi, _ := debug.ReadBuildInfo()
+	return New(bi, Version, BuildTime)
+})
+
+// New returns the build information from bi, which may be nil, with version
+// and buildTime, when not empty, taking precedence
+func New(bi *debug.BuildInfo, version, buildTime string) Info {
+	info := Info{
+		Version:   version,
+		BuildTime: buildTime,
+		Revision:  "",
+		Modified:  false,
+		GoVersion: "",
+		Path:      "",
+		Deps:      nil,
+	}
+	if bi == nil {
+		if info.Version == "" {
+			info.Version = "dev"
+		}
+		return info
+	}
+
+	info.GoVersion = bi.GoVersion
+	info.Path = bi.Path
+	var vcsTime string
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			vcsTime = s.Value
+		case "vcs.modified":
+			info.Modified, _ = strconv.ParseBool(s.Value)
+		}
+	}
+	for _, d := range bi.Deps {
+		dep := Dep{Path: d.Path, Version: d.Version, Replace: ""}
+		if d.Replace != nil {
+			dep.Replace = strings.TrimSpace(d.Replace.Path + " " + d.Replace.Version)
+		}
+		info.Deps = append(info.Deps, dep)
+	}
+
+	if info.Version == "" {
+		info.Version = fallbackVersion(bi.Main.Version, info.Revision, info.Modified)
+	}
+	if info.BuildTime == "" {
+		info.BuildTime = vcsTime
+	}
+
+	return info
+}
+
+// fallbackVersion returns the module version, e.g. when installed with go install,
+// or the short VCS revision of development builds
+func fallbackVersion(moduleVersion, revision string, modified bool) string {
+	if moduleVersion != "" && moduleVersion != "(devel)" {
+		return moduleVersion
+	}
+	if revision == "" {
+		return "dev"
+	}
+
+	v := revision[:min(len(revision), 12)]
+	if modified {
+		v += "-dirty"
+	}
+	return v
+}
+
+// WriteMetric writes the build_info gauge in the Prometheus text format
+func (i Info) WriteMetric(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP build_info Build information of the server, the value is always 1.\n"+
+		"# TYPE build_info gauge\n"+
+		"build_info{version=%s,revision=%s,modified=\"%t\",goversion=%s,build_time=%s} 1\n",
+		labelValue(i.Version), labelValue(i.Revision), i.Modified, labelValue(i.GoVersion), labelValue(i.BuildTime))
+	if err != nil {
+		return fmt.Errorf("fmt.Fprintf: %w", err)
+	}
+	return nil
+}
+
+// labelValue quotes a label value, escaping backslashes, double quotes and line feeds
+func labelValue(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,129 @@
+package buildinfo_test
+
+import (
+	"bytes"
+	"runtime/debug"
+	"testing"
+
+	"go-starter/internal/pkg/buildinfo"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	vcs := []debug.BuildSetting{
+		{Key: "vcs.revision", Value: "6ab32a128c7151c025304316d464c716efa91720"},
+		{Key: "vcs.time", Value: "2025-01-02T15:04:05Z"},
+		{Key: "vcs.modified", Value: "true"},
+	}
+
+	tests := []struct {
+		name      string
+		bi        *debug.BuildInfo
+		version   string
+		buildTime string
+		want      buildinfo.Info
+	}{
+		{
+			name:      "no build info",
+			bi:        nil,
+			version:   "",
+			buildTime: "",
+			want:      buildinfo.Info{Version: "dev"},
+		},
+		{
+			name: "ldflags take precedence",
+			bi: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+				Main:      debug.Module{Path: "go-starter", Version: "(devel)"},
+				Settings:  vcs,
+			},
+			version:   "v1.2.3",
+			buildTime: "2025-02-03T00:00:00Z",
+			want: buildinfo.Info{
+				Version:   "v1.2.3",
+				BuildTime: "2025-02-03T00:00:00Z",
+				Revision:  "6ab32a128c7151c025304316d464c716efa91720",
+				Modified:  true,
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+			},
+		},
+		{
+			name: "vcs revision",
+			bi: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+				Main:      debug.Module{Path: "go-starter", Version: "(devel)"},
+				Deps: []*debug.Module{
+					{Path: "github.com/go-chi/chi/v5", Version: "v5.2.1"},
+					{Path: "example.com/a", Version: "v1.0.0", Replace: &debug.Module{Path: "../a"}},
+				},
+				Settings: vcs,
+			},
+			version:   "",
+			buildTime: "",
+			want: buildinfo.Info{
+				Version:   "6ab32a128c71-dirty",
+				BuildTime: "2025-01-02T15:04:05Z",
+				Revision:  "6ab32a128c7151c025304316d464c716efa91720",
+				Modified:  true,
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+				Deps: []buildinfo.Dep{
+					{Path: "github.com/go-chi/chi/v5", Version: "v5.2.1"},
+					{Path: "example.com/a", Version: "v1.0.0", Replace: "../a"},
+				},
+			},
+		},
+		{
+			name: "module version",
+			bi: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+				Main:      debug.Module{Path: "go-starter", Version: "v1.4.0"},
+			},
+			version:   "",
+			buildTime: "",
+			want: buildinfo.Info{
+				Version:   "v1.4.0",
+				GoVersion: "go1.24.0",
+				Path:      "go-starter/cmd/server",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			// When:
+			got := buildinfo.New(tt.bi, tt.version, tt.buildTime)
+
+			// Then:
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestInfo_WriteMetric(t *testing.T) {
+	// Given:
+	info := buildinfo.Info{
+		Version:   `v1.0.0"x`,
+		BuildTime: "2025-01-02T15:04:05Z",
+		Revision:  "abc",
+		Modified:  false,
+		GoVersion: "go1.24.0",
+	}
+	var buf bytes.Buffer
+
+	// When:
+	err := info.WriteMetric(&buf)
+
+	// Then:
+	require.NoError(t, err)
+	assert.Equal(t, "# HELP build_info Build information of the server, the value is always 1.\n"+
+		"# TYPE build_info gauge\n"+
+		`build_info{version="v1.0.0\"x",revision="abc",modified="false",goversion="go1.24.0",build_time="2025-01-02T15:04:05Z"} 1`+"\n",
+		buf.String())
+}
@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Loader loads a value on a cache miss and tells how it may be cached
+type Loader[V any] func(ctx context.Context) (V, Policy, error)
+
+// Result is a value served by the cache
+type Result[V any] struct {
+	Value V
+	Age   time.Duration // Time since the value was loaded
+	TTL   time.Duration // How long the value is fresh for, counted from load time
+	Stale time.Duration // How long a stale value may be served while revalidating
+}
+
+// entry is a cached value
+type entry[V any] struct {
+	value    V
+	storedAt time.Time
+	ttl      time.Duration
+	stale    time.Duration
+}
+
+// Cache is an in-process cache with TTL and stale-while-revalidate.
+// Concurrent misses for the same key are coalesced into a single load.
+type Cache[V any] struct {
+	ttl         time.Duration
+	stale       time.Duration
+	loadTimeout time.Duration
+	now         func() time.Time
+
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]entry[V]
+}
+
+// Option is a function that configures a Cache
+type Option func(*options)
+
+// options holds the optional settings of a Cache
+type options struct {
+	loadTimeout time.Duration
+	now         func() time.Time
+}
+
+// WithLoadTimeout bounds a load that is shared by several callers and
+// therefore not tied to the context of any single one of them
+func WithLoadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.loadTimeout = d
+	}
+}
+
+// WithClock sets the time source, useful for tests
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}
+
+// New creates a cache keeping values fresh for ttl and serving them stale
+// for up to stale more while a single background load refreshes them.
+// Defaults: loads time out after 30s.
+func New[V any](ttl, stale time.Duration, opts ...Option) *Cache[V] {
+	o := options{
+		loadTimeout: 30 * time.Second,
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Cache[V]{
+		ttl:         ttl,
+		stale:       stale,
+		loadTimeout: o.loadTimeout,
+		now:         o.now,
+		group:       singleflight.Group{},
+		mu:          sync.Mutex{},
+		entries:     map[string]entry[V]{},
+	}
+}
+
+// Get returns the cached value for key, loading it on a miss.
+// A stale value is returned immediately while it is refreshed in the background.
+func (c *Cache[V]) Get(ctx context.Context, key string, load Loader[V]) (Result[V], error) {
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.entries[key]
+	c.mu.Unlock()
+
+	if ok {
+		age := now.Sub(e.storedAt)
+		switch {
+		case age < e.ttl:
+			return e.result(age), nil
+		case age < e.ttl+e.stale:
+			// serve stale and revalidate, at most one refresh per key at a time
+			c.group.DoChan(key, func() (any, error) {
+				return c.load(ctx, key, load)
+			})
+			return e.result(age), nil
+		}
+	}
+
+	ch := c.group.DoChan(key, func() (any, error) {
+		return c.load(ctx, key, load)
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero Result[V]
+		return zero, fmt.Errorf("cache.Get: %w", ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			var zero Result[V]
+			return zero, res.Err
+		}
+		return res.Val.(Result[V]), nil //nolint:forcetypeassert // only load stores values
+	}
+}
+
+// load calls the loader detached from the caller, since other callers may share
+// the result, and stores the value according to the policy
+func (c *Cache[V]) load(ctx context.Context, key string, load Loader[V]) (Result[V], error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.loadTimeout)
+	defer cancel()
+
+	v, policy, err := load(ctx)
+	if err != nil {
+		var zero Result[V]
+		return zero, err
+	}
+
+	e := entry[V]{
+		value:    v,
+		storedAt: c.now().Add(-policy.Age),
+		ttl:      0,
+		stale:    0,
+	}
+	if !policy.NoStore {
+		e.ttl = policy.ttl(c.ttl)
+		e.stale = policy.stale(c.stale)
+	}
+
+	c.mu.Lock()
+	if e.ttl <= 0 && e.stale <= 0 {
+		delete(c.entries, key)
+	} else {
+		c.entries[key] = e
+	}
+	c.mu.Unlock()
+
+	return e.result(policy.Age), nil
+}
+
+// result converts the entry into a Result of the given age
+func (e entry[V]) result(age time.Duration) Result[V] {
+	return Result[V]{
+		Value: e.value,
+		Age:   age,
+		TTL:   e.ttl,
+		Stale: e.stale,
+	}
+}
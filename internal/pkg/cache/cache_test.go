@@ -0,0 +1,247 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/cache"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_Get(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	var mu sync.Mutex
+	now := time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	c := cache.New[int](time.Minute, time.Minute, cache.WithClock(clock))
+
+	var loads atomic.Int32
+	refreshed := make(chan struct{}, 1)
+	load := func(context.Context) (int, cache.Policy, error) {
+		n := int(loads.Add(1))
+		if n > 1 {
+			refreshed <- struct{}{}
+		}
+		return n, cache.Policy{}, nil
+	}
+
+	// When: miss
+	got, err := c.Get(context.Background(), "k", load)
+
+	// Then:
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Value)
+	assert.Equal(t, time.Duration(0), got.Age)
+
+	// When: fresh hit
+	advance(30 * time.Second)
+	got, err = c.Get(context.Background(), "k", load)
+
+	// Then:
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Value)
+	assert.Equal(t, 30*time.Second, got.Age)
+
+	// When: stale hit
+	advance(time.Minute)
+	got, err = c.Get(context.Background(), "k", load)
+
+	// Then: the stale value is served and refreshed in the background
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Value)
+	<-refreshed
+	noLoad := func(context.Context) (int, cache.Policy, error) {
+		return 0, cache.Policy{}, errors.New("not expected to load")
+	}
+	require.Eventually(t, func() bool {
+		got, err := c.Get(context.Background(), "k", noLoad)
+		return err == nil && got.Value == 2
+	}, time.Second, time.Millisecond)
+
+	// When: expired
+	advance(3 * time.Minute)
+	got, err = c.Get(context.Background(), "k", load)
+
+	// Then:
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Value)
+	<-refreshed
+}
+
+func TestCache_Get_Coalesced(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	c := cache.New[string](time.Minute, 0)
+	release := make(chan struct{})
+	var loads atomic.Int32
+	load := func(context.Context) (string, cache.Policy, error) {
+		loads.Add(1)
+		<-release
+		return "value", cache.Policy{}, nil
+	}
+
+	// When: many callers miss at the same time
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.Get(context.Background(), "k", load)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", got.Value)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Then:
+	assert.Equal(t, int32(1), loads.Load())
+}
+
+func TestCache_Get_NotStored(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc   string
+		policy cache.Policy
+		err    error
+	}{
+		{desc: "no-store", policy: cache.Policy{NoStore: true}},
+		{desc: "max-age=0", policy: cache.Policy{MaxAge: ptr.Ref(time.Duration(0)), StaleWhileRevalidate: ptr.Ref(time.Duration(0))}},
+		{desc: "error", err: errors.New("upstream error")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			c := cache.New[int](time.Minute, time.Minute)
+			var loads atomic.Int32
+			load := func(context.Context) (int, cache.Policy, error) {
+				return int(loads.Add(1)), tc.policy, tc.err
+			}
+
+			// When:
+			_, _ = c.Get(context.Background(), "k", load)
+			_, _ = c.Get(context.Background(), "k", load)
+
+			// Then:
+			assert.Equal(t, int32(2), loads.Load())
+		})
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc   string
+		header http.Header
+		want   cache.Policy
+	}{
+		{
+			desc:   "no headers",
+			header: http.Header{},
+			want:   cache.Policy{},
+		},
+		{
+			desc:   "max-age with age",
+			header: http.Header{"Cache-Control": {"public, max-age=60"}, "Age": {"10"}},
+			want:   cache.Policy{MaxAge: ptr.Ref(time.Minute), Age: 10 * time.Second},
+		},
+		{
+			desc:   "s-maxage takes precedence",
+			header: http.Header{"Cache-Control": {"max-age=60, s-maxage=30, stale-while-revalidate=120"}},
+			want:   cache.Policy{MaxAge: ptr.Ref(30 * time.Second), StaleWhileRevalidate: ptr.Ref(2 * time.Minute)},
+		},
+		{
+			desc:   "no-cache",
+			header: http.Header{"Cache-Control": {"no-cache"}},
+			want:   cache.Policy{NoStore: true},
+		},
+		{
+			desc:   "private",
+			header: http.Header{"Cache-Control": {"private, max-age=60"}},
+			want:   cache.Policy{NoStore: true, MaxAge: ptr.Ref(time.Minute)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// When:
+			got := cache.ParsePolicy(tc.header)
+
+			// Then:
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestResult_SetHeaders(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		desc    string
+		result  cache.Result[int]
+		wantCC  string
+		wantAge string
+	}{
+		{
+			desc:    "fresh",
+			result:  cache.Result[int]{Age: 15 * time.Second, TTL: time.Minute, Stale: 5 * time.Minute},
+			wantCC:  "public, max-age=60, stale-while-revalidate=300",
+			wantAge: "15",
+		},
+		{
+			desc:    "stale",
+			result:  cache.Result[int]{Age: 90 * time.Second, TTL: time.Minute},
+			wantCC:  "public, max-age=60",
+			wantAge: "90",
+		},
+		{
+			desc:    "not cacheable",
+			result:  cache.Result[int]{},
+			wantCC:  "no-store",
+			wantAge: "",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// When:
+			h := http.Header{}
+			tc.result.SetHeaders(h)
+
+			// Then:
+			assert.Equal(t, tc.wantCC, h.Get("Cache-Control"))
+			assert.Equal(t, tc.wantAge, h.Get("Age"))
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy describes how a loaded value may be cached, typically derived
+// from the Cache-Control and Age headers of an upstream response
+type Policy struct {
+	NoStore              bool           // Must not be cached at all
+	MaxAge               *time.Duration // Upstream freshness lifetime, if any
+	StaleWhileRevalidate *time.Duration // Upstream stale-while-revalidate window, if any
+	Age                  time.Duration  // Time the value already spent in upstream caches
+}
+
+// ttl returns the freshness lifetime, never longer than the upstream allows
+func (p Policy) ttl(limit time.Duration) time.Duration {
+	if p.MaxAge != nil {
+		return min(limit, *p.MaxAge)
+	}
+	return limit
+}
+
+// stale returns the stale window, never longer than the upstream allows
+func (p Policy) stale(limit time.Duration) time.Duration {
+	if p.StaleWhileRevalidate != nil {
+		return min(limit, *p.StaleWhileRevalidate)
+	}
+	return limit
+}
+
+// ParsePolicy reads the caching policy of a response from its headers.
+// As a shared cache, s-maxage takes precedence over max-age, and
+// private, no-cache and no-store responses are not stored.
+func ParsePolicy(h http.Header) Policy {
+	var p Policy
+
+	if age, err := strconv.ParseInt(h.Get("Age"), 10, 64); err == nil && age > 0 {
+		p.Age = seconds(age)
+	}
+
+	var sMaxAge *time.Duration
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		value = strings.Trim(value, `"`)
+
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			p.NoStore = true
+		case "max-age":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+				d := seconds(n)
+				p.MaxAge = &d
+			}
+		case "s-maxage":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+				d := seconds(n)
+				sMaxAge = &d
+			}
+		case "stale-while-revalidate":
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil && n >= 0 {
+				d := seconds(n)
+				p.StaleWhileRevalidate = &d
+			}
+		}
+	}
+	if sMaxAge != nil {
+		p.MaxAge = sMaxAge
+	}
+
+	return p
+}
+
+// seconds converts a header value in seconds to a duration without overflowing
+func seconds(n int64) time.Duration {
+	if n > math.MaxInt64/int64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(n) * time.Second
+}
+
+// SetHeaders sets the Cache-Control and Age headers describing the result.
+// max-age is the whole TTL, as caches downstream subtract Age from it.
+func (r Result[V]) SetHeaders(h http.Header) {
+	if r.TTL <= 0 && r.Stale <= 0 {
+		h.Set("Cache-Control", "no-store")
+		return
+	}
+
+	cc := "public, max-age=" + strconv.Itoa(int(max(0, r.TTL)/time.Second))
+	if r.Stale > 0 {
+		cc += ", stale-while-revalidate=" + strconv.Itoa(int(r.Stale/time.Second))
+	}
+	h.Set("Cache-Control", cc)
+	h.Set("Age", strconv.Itoa(int(r.Age/time.Second)))
+}
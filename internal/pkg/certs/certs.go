@@ -0,0 +1,189 @@
+// Package certs serves TLS certificates that are reloaded when their files
+// change, so rotated certificates are picked up without a restart.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrNoCertificates is returned when a CA bundle holds no PEM certificate
+var ErrNoCertificates = errors.New("no certificates found")
+
+// Reloader holds a certificate and key pair, and optionally a bundle of
+// client CAs, and reloads them when one of the files changes
+type Reloader struct {
+	certFile     string
+	keyFile      string
+	clientCAFile string
+	debounce     time.Duration
+
+	mu        sync.Mutex // serializes reloads
+	cert      atomic.Pointer[tls.Certificate]
+	clientCAs atomic.Pointer[x509.CertPool]
+}
+
+// Option is a function that configures a Reloader
+type Option func(*Reloader)
+
+// WithClientCA sets the PEM bundle of the CAs that client certificates are verified against
+func WithClientCA(path string) Option {
+	return func(r *Reloader) {
+		r.clientCAFile = path
+	}
+}
+
+// WithDebounce sets how long file changes are coalesced before reloading,
+// since the certificate and key are usually written one after the other
+func WithDebounce(d time.Duration) Option {
+	return func(r *Reloader) {
+		r.debounce = d
+	}
+}
+
+// New loads the certificate and key from PEM files.
+// Defaults: no client CAs, changes are debounced for 100ms.
+func New(certFile, keyFile string, opts ...Option) (*Reloader, error) {
+	r := &Reloader{
+		certFile:     certFile,
+		keyFile:      keyFile,
+		clientCAFile: "",
+		debounce:     100 * time.Millisecond,
+		mu:           sync.Mutex{},
+		cert:         atomic.Pointer[tls.Certificate]{},
+		clientCAs:    atomic.Pointer[x509.CertPool]{},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+
+	if err := r.Reload(); err != nil {
+		return nil, fmt.Errorf("certs.New: %w", err)
+	}
+
+	return r, nil
+}
+
+// Reload reads the files again. The current certificate is kept when any of
+// them is invalid, e.g. when the key no longer matches a half written certificate.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls.LoadX509KeyPair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.clientCAFile != "" {
+		pem, err := os.ReadFile(r.clientCAFile)
+		if err != nil {
+			return fmt.Errorf("os.ReadFile: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%w in %s", ErrNoCertificates, r.clientCAFile)
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.clientCAs.Store(pool)
+
+	return nil
+}
+
+// Certificate returns the current certificate, for tls.Config.GetCertificate
+func (r *Reloader) Certificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return r.cert.Load(), nil
+}
+
+// ClientCAs returns the current pool of client CAs, nil when not configured
+func (r *Reloader) ClientCAs() *x509.CertPool {
+	return r.clientCAs.Load()
+}
+
+// Config returns a copy of base serving the current certificate. When client
+// CAs are configured, each handshake verifies client certificates against the
+// current pool according to base.ClientAuth. Those handshakes use a copy of
+// base, not of the config http.Server derives from it, so base.NextProtos
+// must list the ALPN protocols, e.g. "h2" and "http/1.1".
+func (r *Reloader) Config(base *tls.Config) *tls.Config {
+	cfg := base.Clone()
+	cfg.GetCertificate = r.Certificate
+	if r.clientCAFile != "" {
+		cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c := cfg.Clone()
+			c.GetConfigForClient = nil
+			c.ClientCAs = r.ClientCAs()
+			return c, nil
+		}
+	}
+	return cfg
+}
+
+// Run watches the files until ctx is canceled and reloads them on change.
+// Failed reloads are logged and the previous certificate stays in use.
+func (r *Reloader) Run(ctx context.Context) error {
+	logger := slogr.FromContext(ctx)
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("fsnotify.NewWatcher: %w", err)
+	}
+	defer w.Close()
+
+	// Directories are watched rather than files so that atomic replacements
+	// are seen, as done by cert-manager and Kubernetes Secret volumes
+	files := map[string]bool{}
+	for _, f := range []string{r.certFile, r.keyFile, r.clientCAFile} {
+		if f == "" {
+			continue
+		}
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return fmt.Errorf("filepath.Abs: %w", err)
+		}
+		files[abs] = true
+		if err := w.Add(filepath.Dir(abs)); err != nil {
+			return fmt.Errorf("watcher.Add: %w", err)
+		}
+	}
+
+	timer := time.NewTimer(0)
+	<-timer.C
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-w.Events:
+			// Kubernetes swaps a ..data symlink, so any change in the directory counts then
+			if files[filepath.Clean(ev.Name)] || filepath.Base(ev.Name) == "..data" {
+				timer.Reset(r.debounce)
+			}
+		case err := <-w.Errors:
+			logger.Error("[certs] watcher error", slog.Any("err", err))
+		case <-timer.C:
+			if err := r.Reload(); err != nil {
+				logger.Error("[certs] reload failed", slog.Any("err", err))
+				continue
+			}
+			logger.Info("[certs] certificates reloaded", slog.String("cert-file", r.certFile))
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package certs_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/certs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// issue creates a certificate for cn signed by parent, self-signed when nil
+func issue(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, isCA bool) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn, Organization: []string{"Example"}},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv6loopback, net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return cert, key
+}
+
+// writePair writes the certificate and key as PEM files
+func writePair(t *testing.T, certFile, keyFile string, cert *x509.Certificate, key *ecdsa.PrivateKey) {
+	t.Helper()
+
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+}
+
+func TestReloader_Run(t *testing.T) {
+	// Given:
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert, key := issue(t, "first", nil, nil, false)
+	writePair(t, certFile, keyFile, cert, key)
+
+	r, err := certs.New(certFile, keyFile, certs.WithDebounce(10*time.Millisecond))
+	require.NoError(t, err)
+	go func() { _ = r.Run(t.Context()) }()
+	time.Sleep(50 * time.Millisecond) // Let the watcher start
+
+	// When:
+	rotated, rotatedKey := issue(t, "second", nil, nil, false)
+	writePair(t, certFile, keyFile, rotated, rotatedKey)
+
+	// Then:
+	assert.Eventually(t, func() bool {
+		got, err := r.Certificate(nil)
+		return err == nil && got.Leaf.Subject.CommonName == "second"
+	}, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestReloader_Reload(t *testing.T) {
+	// Given:
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	cert, key := issue(t, "first", nil, nil, false)
+	writePair(t, certFile, keyFile, cert, key)
+
+	r, err := certs.New(certFile, keyFile)
+	require.NoError(t, err)
+
+	// When: the key no longer matches the certificate
+	_, otherKey := issue(t, "other", nil, nil, false)
+	writePair(t, filepath.Join(dir, "unused.crt"), keyFile, cert, otherKey)
+	err = r.Reload()
+
+	// Then:
+	require.Error(t, err)
+	got, err := r.Certificate(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "first", got.Leaf.Subject.CommonName)
+}
+
+func TestReloader_Config(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := issue(t, "ca", nil, nil, true)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0o600))
+	serverCert, serverKey := issue(t, "server", ca, caKey, false)
+	writePair(t, filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), serverCert, serverKey)
+
+	clientCert, clientKey := issue(t, "billing", ca, caKey, false)
+	strangerCert, strangerKey := issue(t, "stranger", nil, nil, false)
+
+	tests := []struct {
+		name       string
+		clientAuth certs.ClientAuth
+		client     *x509.Certificate
+		clientKey  *ecdsa.PrivateKey
+		wantErr    bool
+		wantCN     string
+	}{
+		{name: "optional without certificate", clientAuth: certs.ClientAuthOptional, wantCN: ""},
+		{name: "optional with certificate", clientAuth: certs.ClientAuthOptional, client: clientCert, clientKey: clientKey, wantCN: "billing"},
+		{name: "required without certificate", clientAuth: certs.ClientAuthRequire, wantErr: true},
+		{name: "unknown CA", clientAuth: certs.ClientAuthOptional, client: strangerCert, clientKey: strangerKey, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			r, err := certs.New(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"),
+				certs.WithClientCA(filepath.Join(dir, "ca.crt")))
+			require.NoError(t, err)
+
+			srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if len(r.TLS.VerifiedChains) > 0 {
+					_, _ = w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+				}
+			}))
+			srv.TLS = r.Config(&tls.Config{MinVersion: tls.VersionTLS12, ClientAuth: tt.clientAuth.Type()})
+			srv.StartTLS()
+			t.Cleanup(srv.Close)
+
+			roots := x509.NewCertPool()
+			roots.AddCert(ca)
+			clientTLS := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+			if tt.client != nil {
+				// Sent even when not issued by one of the CAs requested by the server
+				clientTLS.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					return &tls.Certificate{Certificate: [][]byte{tt.client.Raw}, PrivateKey: tt.clientKey}, nil
+				}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+
+			// When:
+			resp, err := client.Get(srv.URL)
+
+			// Then:
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCN, string(body))
+		})
+	}
+}
+
+func TestReloader_Config_HTTP2(t *testing.T) {
+	// Given: a server with client CAs, negotiating HTTP/2
+	dir := t.TempDir()
+	ca, caKey := issue(t, "ca", nil, nil, true)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ca.crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0o600))
+	serverCert, serverKey := issue(t, "server", ca, caKey, false)
+	writePair(t, filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"), serverCert, serverKey)
+
+	r, err := certs.New(filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key"),
+		certs.WithClientCA(filepath.Join(dir, "ca.crt")))
+	require.NoError(t, err)
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	srv.EnableHTTP2 = true
+	srv.TLS = r.Config(&tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: certs.ClientAuthOptional.Type(),
+		NextProtos: []string{"h2", "http/1.1"},
+	})
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	// When:
+	conn, err := tls.Dial("tcp", srv.Listener.Addr().String(),
+		&tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12, NextProtos: []string{"h2"}, ServerName: "localhost"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Then:
+	assert.Equal(t, "h2", conn.ConnectionState().NegotiatedProtocol)
+}
+
+func TestConfig_UnmarshalText(t *testing.T) {
+	// Given:
+	var v certs.Version
+	var c certs.CipherSuite
+	var a certs.ClientAuth
+
+	// When:
+	// Then:
+	require.NoError(t, v.UnmarshalText([]byte("1.3")))
+	assert.Equal(t, certs.Version(tls.VersionTLS13), v)
+	assert.Equal(t, "1.3", v.String())
+	require.ErrorIs(t, v.UnmarshalText([]byte("1.0")), certs.ErrUnknownVersion)
+
+	require.NoError(t, c.UnmarshalText([]byte("TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256")))
+	assert.Equal(t, certs.CipherSuite(tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256), c)
+	require.ErrorIs(t, c.UnmarshalText([]byte("TLS_RSA_WITH_RC4_128_SHA")), certs.ErrUnknownCipherSuite)
+
+	require.NoError(t, a.UnmarshalText([]byte("REQUIRE")))
+	assert.Equal(t, tls.RequireAndVerifyClientCert, a.Type())
+	require.ErrorIs(t, a.UnmarshalText([]byte("none")), certs.ErrUnknownClientAuth)
+}
@@ -0,0 +1,97 @@
+package certs
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors returned when parsing TLS settings
+var (
+	ErrUnknownVersion     = errors.New("unknown TLS version")
+	ErrUnknownCipherSuite = errors.New("unknown or insecure cipher suite")
+	ErrUnknownClientAuth  = errors.New("unknown client authentication mode")
+)
+
+// Version is a TLS protocol version, written as "1.2" or "1.3"
+type Version uint16
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (v *Version) UnmarshalText(text []byte) error {
+	switch strings.TrimPrefix(strings.ToUpper(string(text)), "TLS") {
+	case "1.2":
+		*v = tls.VersionTLS12
+	case "1.3":
+		*v = tls.VersionTLS13
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownVersion, text)
+	}
+	return nil
+}
+
+// String returns the version as accepted by UnmarshalText
+func (v Version) String() string {
+	return strings.TrimPrefix(tls.VersionName(uint16(v)), "TLS ")
+}
+
+// CipherSuite is a TLS 1.2 cipher suite, written by its IANA name, e.g.
+// "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256". TLS 1.3 suites are not configurable.
+type CipherSuite uint16
+
+// UnmarshalText implements encoding.TextUnmarshaler. Only the suites
+// considered secure by crypto/tls are accepted.
+func (c *CipherSuite) UnmarshalText(text []byte) error {
+	for _, s := range tls.CipherSuites() {
+		if strings.EqualFold(s.Name, string(text)) {
+			*c = CipherSuite(s.ID)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrUnknownCipherSuite, text)
+}
+
+// String returns the IANA name of the suite
+func (c CipherSuite) String() string {
+	return tls.CipherSuiteName(uint16(c))
+}
+
+// ClientAuth is the policy for client certificates
+type ClientAuth string
+
+// Supported client authentication modes
+const (
+	ClientAuthOptional ClientAuth = "optional" // Certificates are verified when presented
+	ClientAuthRequire  ClientAuth = "require"  // A valid certificate is required
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (a *ClientAuth) UnmarshalText(text []byte) error {
+	v := ClientAuth(strings.ToLower(string(text)))
+	if v != ClientAuthOptional && v != ClientAuthRequire {
+		return fmt.Errorf("%w: %q", ErrUnknownClientAuth, text)
+	}
+	*a = v
+	return nil
+}
+
+// Type returns the crypto/tls equivalent of the mode
+func (a ClientAuth) Type() tls.ClientAuthType {
+	if a == ClientAuthRequire {
+		return tls.RequireAndVerifyClientCert
+	}
+	return tls.VerifyClientCertIfGiven
+}
+
+// Ciphers converts cipher suites to the IDs of tls.Config.CipherSuites,
+// nil when empty to keep the crypto/tls defaults
+func Ciphers(suites []CipherSuite) []uint16 {
+	if len(suites) == 0 {
+		return nil
+	}
+	ids := make([]uint16, len(suites))
+	for i, s := range suites {
+		ids[i] = uint16(s)
+	}
+	return ids
+}
@@ -0,0 +1,257 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidSpec is returned when a cron expression cannot be parsed
+var ErrInvalidSpec = errors.New("invalid cron spec")
+
+// Schedule describes when a job should run
+type Schedule interface {
+	// Next returns the next activation time strictly after t,
+	// or the zero time if the schedule can never be satisfied.
+	Next(t time.Time) time.Time
+}
+
+// field describes the bounds and aliases of a single cron field
+type field struct {
+	name     string
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day of month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{name: "day of week", min: 0, max: 7, names: map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// descriptors maps the predefined schedules to their 5-field equivalent
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses a standard 5-field cron expression (minute, hour, day of month,
+// month, day of week) or one of the predefined descriptors such as "@daily"
+// and "@every 5m". Times are evaluated in the location of the time passed to Next.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if d, ok := strings.CutPrefix(spec, "@every "); ok {
+		interval, err := time.ParseDuration(strings.TrimSpace(d))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("%w: %q: interval must be at least 1s", ErrInvalidSpec, spec)
+		}
+		return everySchedule{interval: interval}, nil
+	}
+
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("%w: %q: expected 5 fields, got %d", ErrInvalidSpec, spec, len(fields))
+	}
+
+	var (
+		s   specSchedule
+		err error
+	)
+	if s.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+	}
+	if s.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+	}
+	if s.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+	}
+	if s.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+	}
+	if s.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, fmt.Errorf("%w: %q: %w", ErrInvalidSpec, spec, err)
+	}
+
+	// 7 is an alias for Sunday
+	if s.dow&(1<<7) != 0 {
+		s.dow = s.dow&^(1<<7) | 1
+	}
+
+	s.domStar = fields[2] == "*" || fields[2] == "?"
+	s.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return s, nil
+}
+
+// MustParse is like Parse but panics if the spec cannot be parsed
+func MustParse(spec string) Schedule {
+	s, err := Parse(spec)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// parseField parses a comma separated list of ranges into a bitset
+func parseField(expr string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(expr, ",") {
+		b, err := parseRange(part, f)
+		if err != nil {
+			return 0, err
+		}
+		bits |= b
+	}
+	return bits, nil
+}
+
+// parseRange parses a single "*", "a", "a-b" term with an optional "/step"
+func parseRange(expr string, f field) (uint64, error) {
+	rangeExpr, stepExpr, hasStep := strings.Cut(expr, "/")
+
+	var start, end uint
+	switch rangeExpr {
+	case "*", "?":
+		start, end = f.min, f.max
+		if f.max == 7 {
+			// avoid matching Sunday twice via the 7 alias
+			end = 6
+		}
+	default:
+		lo, hi, isRange := strings.Cut(rangeExpr, "-")
+
+		var err error
+		if start, err = parseValue(lo, f); err != nil {
+			return 0, err
+		}
+		end = start
+		if isRange {
+			if end, err = parseValue(hi, f); err != nil {
+				return 0, err
+			}
+		} else if hasStep {
+			// "a/n" is shorthand for "a-max/n"
+			end = f.max
+		}
+	}
+
+	step := uint(1)
+	if hasStep {
+		n, err := strconv.ParseUint(stepExpr, 10, 8)
+		if err != nil || n == 0 {
+			return 0, fmt.Errorf("invalid step %q in %s field", stepExpr, f.name)
+		}
+		step = uint(n)
+	}
+
+	if start > end {
+		return 0, fmt.Errorf("invalid range %q in %s field", rangeExpr, f.name)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+// parseValue parses a number or a named alias within the field bounds
+func parseValue(s string, f field) (uint, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q in %s field", s, f.name)
+	}
+	if uint(n) < f.min || uint(n) > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d-%d] in %s field", n, f.min, f.max, f.name)
+	}
+	return uint(n), nil
+}
+
+// specSchedule is a schedule parsed from a 5-field cron expression
+type specSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record whether the day fields were unrestricted,
+	// which changes how the two are combined (see dayMatches)
+	domStar, dowStar bool
+}
+
+// Next implements Schedule
+func (s specSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Give up if no match is found within five years, e.g. "0 0 30 2 *"
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+
+	return time.Time{}
+}
+
+// dayMatches follows the classic cron rule: when both day of month and day of
+// week are restricted, a day matches if either field matches.
+func (s specSchedule) dayMatches(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// everySchedule runs at a fixed interval, aligned to the interval boundary
+type everySchedule struct {
+	interval time.Duration
+}
+
+// Next implements Schedule
+func (s everySchedule) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
@@ -0,0 +1,87 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/cron"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse_Next(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2025, 1, 17, 23, 51, 43, 0, time.UTC) // Friday
+
+	testCases := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2025, 1, 17, 23, 52, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 3 * * *", want: time.Date(2025, 1, 18, 3, 0, 0, 0, time.UTC)},
+		{spec: "30 9 * * mon-fri", want: time.Date(2025, 1, 20, 9, 30, 0, 0, time.UTC)},
+		{spec: "0 0 1 feb *", want: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 * * 7", want: time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 15 * 1", want: time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)},
+		{spec: "5,10 0 * * *", want: time.Date(2025, 1, 18, 0, 5, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{spec: "@daily", want: time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", want: time.Date(2025, 1, 19, 0, 0, 0, 0, time.UTC)},
+		{spec: "@monthly", want: time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{spec: "@every 10m", want: time.Date(2025, 1, 18, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			s, err := cron.Parse(tc.spec)
+			require.NoError(t, err)
+
+			// When:
+			got := s.Next(from)
+
+			// Then:
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	t.Parallel()
+
+	testCases := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"abc * * * *",
+		"@every",
+		"@every 500ms",
+		"@fortnightly",
+	}
+
+	for _, spec := range testCases {
+		t.Run(spec, func(t *testing.T) {
+			t.Parallel()
+
+			// When:
+			_, err := cron.Parse(spec)
+
+			// Then:
+			require.ErrorIs(t, err, cron.ErrInvalidSpec)
+		})
+	}
+}
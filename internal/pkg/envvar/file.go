@@ -0,0 +1,202 @@
+package envvar
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidFile is returned when a configuration file cannot be parsed
+var ErrInvalidFile = errors.New("invalid configuration file")
+
+// ReadFile returns a Source serving the variables defined in a file, named after its path.
+// Files ending in .yaml, .yml or .toml are read as YAML or TOML, where nested
+// keys are joined with underscores and upper-cased, e.g. database: {conns: 4}
+// defines DATABASE_CONNS, and lists are joined with commas. Any other file is
+// read as a .env file of KEY=VALUE lines.
+func ReadFile(path string) (Source, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return Source{}, fmt.Errorf("os.ReadFile: %w", err)
+	}
+
+	var vars map[string]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		vars, err = parseYAML(b)
+	case ".toml":
+		vars, err = parseTOML(b)
+	default:
+		vars, err = parseDotenv(b)
+	}
+	if err != nil {
+		return Source{}, fmt.Errorf("envvar.ReadFile %s: %w", path, err)
+	}
+
+	return Source{
+		Name: path,
+		Lookup: func(key string) (string, bool) {
+			v, ok := vars[key]
+			return v, ok
+		},
+	}, nil
+}
+
+// parseDotenv parses KEY=VALUE lines, ignoring blank lines, # comments and a
+// leading "export". Values may be single quoted (verbatim) or double quoted
+// (with \n, \t, \" and \\ escapes).
+func parseDotenv(b []byte) (map[string]string, error) {
+	vars := map[string]string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%w: line %d: expected KEY=VALUE", ErrInvalidFile, n)
+		}
+
+		value, err := unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidFile, n, err)
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("scanner.Scan: %w", err)
+	}
+
+	return vars, nil
+}
+
+// unquote removes the quotes around a .env value, or a trailing comment from an unquoted one
+func unquote(s string) (string, error) {
+	switch {
+	case len(s) >= 2 && s[0] == '\'' && s[len(s)-1] == '\'':
+		return s[1 : len(s)-1], nil
+	case len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"':
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return "", fmt.Errorf("strconv.Unquote: %w", err)
+		}
+		return v, nil
+	default:
+		if i := strings.Index(s, " #"); i >= 0 {
+			s = strings.TrimSpace(s[:i])
+		}
+		return s, nil
+	}
+}
+
+// parseYAML flattens a YAML document into variables
+func parseYAML(b []byte) (map[string]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	vars := map[string]string{}
+	if len(doc.Content) == 0 {
+		return vars, nil
+	}
+	if err := flattenYAML(doc.Content[0], "", vars); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// flattenYAML adds the scalars under node to vars, named after their path
+func flattenYAML(node *yaml.Node, key string, vars map[string]string) error {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name := strings.ToUpper(strings.ReplaceAll(node.Content[i].Value, "-", "_"))
+			if key != "" {
+				name = key + "_" + name
+			}
+			if err := flattenYAML(node.Content[i+1], name, vars); err != nil {
+				return err
+			}
+		}
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%w: line %d: %s: lists may only hold scalars", ErrInvalidFile, item.Line, key)
+			}
+			items = append(items, item.Value)
+		}
+		vars[key] = strings.Join(items, ",")
+	case yaml.ScalarNode:
+		if key == "" {
+			return fmt.Errorf("%w: line %d: expected a mapping at the top level", ErrInvalidFile, node.Line)
+		}
+		vars[key] = node.Value
+	case yaml.AliasNode:
+		return flattenYAML(node.Alias, key, vars)
+	case yaml.DocumentNode:
+		return fmt.Errorf("%w: line %d: unexpected document", ErrInvalidFile, node.Line)
+	}
+
+	return nil
+}
+
+// parseTOML flattens a TOML document into variables
+func parseTOML(b []byte) (map[string]string, error) {
+	var doc map[string]any
+	if err := toml.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	vars := map[string]string{}
+	if err := flattenTOML(doc, "", vars); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// flattenTOML adds the values of table to vars, named after their path
+func flattenTOML(table map[string]any, prefix string, vars map[string]string) error {
+	for k, v := range table {
+		key := strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		if prefix != "" {
+			key = prefix + "_" + key
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			if err := flattenTOML(v, key, vars); err != nil {
+				return err
+			}
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				if _, ok := item.(map[string]any); ok {
+					return fmt.Errorf("%w: %s: lists may only hold scalars", ErrInvalidFile, key)
+				}
+				items = append(items, fmt.Sprint(item))
+			}
+			vars[key] = strings.Join(items, ",")
+		default:
+			vars[key] = fmt.Sprint(v)
+		}
+	}
+
+	return nil
+}
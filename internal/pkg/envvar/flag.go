@@ -0,0 +1,35 @@
+package envvar
+
+import (
+	"flag"
+	"strings"
+)
+
+// SourceFlag is the name of the Source returned by Flags
+const SourceFlag = "flag"
+
+// Flags defines a flag in fs for every variable of the struct pointed to by v,
+// named after the variable in lower kebab case (DATABASE_URL is --database-url).
+// The returned Source serves the flags set once fs is parsed.
+func Flags(fs *flag.FlagSet, v any) Source {
+	vars := map[string]string{}
+	for _, key := range Keys(v) {
+		fs.Func(FlagName(key), "overrides "+key, func(s string) error {
+			vars[key] = s
+			return nil
+		})
+	}
+
+	return Source{
+		Name: SourceFlag,
+		Lookup: func(key string) (string, bool) {
+			s, ok := vars[key]
+			return s, ok
+		},
+	}
+}
+
+// FlagName returns the flag overriding the variable named key
+func FlagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
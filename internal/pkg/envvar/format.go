@@ -0,0 +1,50 @@
+package envvar
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// formatValue formats a field in the syntax accepted by Load.
+// Values implementing fmt.Stringer, such as Secret, format themselves.
+func formatValue(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if v.CanAddr() {
+		if s, ok := v.Addr().Interface().(fmt.Stringer); ok {
+			return s.String()
+		}
+	}
+	if s, ok := v.Interface().(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	switch {
+	case v.Type() == bytesType:
+		return hex.EncodeToString(v.Bytes())
+	case v.Kind() == reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = formatValue(v.Index(i))
+		}
+		return strings.Join(items, ",")
+	case v.Kind() == reflect.Map:
+		items := make([]string, 0, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			items = append(items, formatValue(iter.Key())+"="+formatValue(iter.Value()))
+		}
+		slices.Sort(items)
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,324 @@
+package envvar
+
+import (
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRequired is reported for a required variable that is neither set nor defaulted
+var ErrRequired = errors.New("required variable is not set")
+
+// ErrUnsupportedType is reported for a field whose type cannot be parsed from a string
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// errInvalidMapEntry is reported for a map entry that is not a key=value pair
+var errInvalidMapEntry = errors.New("invalid map entry")
+
+var (
+	durationType        = reflect.TypeFor[time.Duration]()
+	urlType             = reflect.TypeFor[url.URL]()
+	addressType         = reflect.TypeFor[mail.Address]()
+	bytesType           = reflect.TypeFor[[]byte]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// SourceDefault is reported as the source of values taken from default tags
+const SourceDefault = "default"
+
+// Source is a layer of configuration, such as the process environment or a file
+type Source struct {
+	Name   string                          // Reported as the origin of the values it provides
+	Lookup func(key string) (string, bool) // Returns the value of a variable, if defined
+}
+
+// Env is the process environment
+var Env = Source{Name: "env", Lookup: os.LookupEnv}
+
+// Setting is the effective value of a variable after Load
+type Setting struct {
+	Key    string // Variable name
+	Value  string // Formatted value, with secrets redacted
+	Source string // Name of the source of the value, SourceDefault, or empty when unset
+}
+
+// loadOptions holds the optional settings of Load
+type loadOptions struct {
+	sources []Source
+	report  *[]Setting
+}
+
+// LoadOption is a function that configures Load
+type LoadOption func(*loadOptions)
+
+// WithSources sets where variables are looked up, in increasing order of precedence.
+// Defaults to the process environment alone.
+func WithSources(sources ...Source) LoadOption {
+	return func(o *loadOptions) {
+		o.sources = sources
+	}
+}
+
+// WithReport appends every variable of the struct to report, in field order,
+// along with its effective value and where it came from
+func WithReport(report *[]Setting) LoadOption {
+	return func(o *loadOptions) {
+		o.report = report
+	}
+}
+
+// Load populates the struct pointed to by v from environment variables.
+// Fields are described with tags:
+//
+//	env:"NAME"       variable holding the value of the field
+//	default:"value"  used when the variable is unset or empty
+//	required:"true"  the variable must be set, unless it has a default
+//	prefix:"DB_"     on a nested struct, prepended to the names of its fields
+//
+// Values are resolved like Lookup does, so they may also be read from files.
+// Supported types are strings, bools, integers, floats, time.Duration, url.URL,
+// mail.Address, hex encoded []byte, encoding.TextUnmarshaler and pointers to
+// those, as well as comma separated slices ("a,b") and maps ("k1=v1,k2=v2").
+// Every invalid field is reported, not only the first one.
+func Load(v any, opts ...LoadOption) error {
+	o := loadOptions{
+		sources: []Source{Env},
+		report:  nil,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envvar.Load: %w: expected a pointer to a struct, got %T", ErrUnsupportedType, v)
+	}
+
+	var errs []error
+	walk(rv.Elem(), "", func(key string, f reflect.StructField, v reflect.Value) {
+		origin, err := loadField(key, f, v, o.sources)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+		if o.report != nil {
+			value := ""
+			if origin != "" || !v.IsZero() {
+				value = formatValue(v)
+			}
+			*o.report = append(*o.report, Setting{Key: key, Value: value, Source: origin})
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("envvar.Load: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Keys returns the variable names of the struct pointed to by v, in field order
+func Keys(v any) []string {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	walk(rv.Elem(), "", func(key string, _ reflect.StructField, _ reflect.Value) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// walk calls fn for every field of v tagged with env, recursing into nested structs
+func walk(v reflect.Value, prefix string, fn func(key string, f reflect.StructField, v reflect.Value)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, ok := f.Tag.Lookup("env")
+		if !ok {
+			if isNested(f.Type) {
+				walk(v.Field(i), prefix+f.Tag.Get("prefix"), fn)
+			}
+			continue
+		}
+
+		fn(prefix+name, f, v.Field(i))
+	}
+}
+
+// loadField sets v from the variable named key and returns the source of its value
+func loadField(key string, f reflect.StructField, v reflect.Value, sources []Source) (string, error) {
+	s, origin, ok, err := lookup(sources, key)
+	if err != nil {
+		return origin, err
+	}
+	if !ok || s == "" {
+		s, ok = f.Tag.Lookup("default")
+		origin = SourceDefault
+	}
+	if !ok {
+		origin = ""
+		if f.Tag.Get("required") == "true" {
+			return "", ErrRequired
+		}
+		return "", nil
+	}
+
+	return origin, setValue(v, s)
+}
+
+// isNested reports whether t is a struct holding fields of its own,
+// rather than a value parsed from a single variable
+func isNested(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct &&
+		t != urlType &&
+		t != addressType &&
+		!reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// setValue parses s into v according to its type
+func setValue(v reflect.Value, s string) error {
+	if v.Kind() == reflect.Pointer {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return fmt.Errorf("cannot unmarshal text: %w", err)
+		}
+		return nil
+	}
+
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("invalid duration: %w", err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	case urlType:
+		u, err := url.Parse(s)
+		if err != nil {
+			return fmt.Errorf("invalid url: %w", err)
+		}
+		v.Set(reflect.ValueOf(*u))
+		return nil
+	case addressType:
+		a, err := mail.ParseAddress(s)
+		if err != nil {
+			return fmt.Errorf("invalid email address: %w", err)
+		}
+		v.Set(reflect.ValueOf(*a))
+		return nil
+	case bytesType:
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("not a hex string: %w", err)
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	switch v.Kind() { //nolint:exhaustive // remaining kinds are unsupported
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("not a valid bool: %w", err)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer: %w", err)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer: %w", err)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid float: %w", err)
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		return setSlice(v, s)
+	case reflect.Map:
+		return setMap(v, s)
+	default:
+		return fmt.Errorf("%w: %s", ErrUnsupportedType, v.Type())
+	}
+
+	return nil
+}
+
+// setSlice parses comma separated elements into v
+func setSlice(v reflect.Value, s string) error {
+	items := splitList(s)
+	sl := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := setValue(sl.Index(i), item); err != nil {
+			return fmt.Errorf("element %d: %w", i, err)
+		}
+	}
+	v.Set(sl)
+	return nil
+}
+
+// setMap parses comma separated key=value pairs into v
+func setMap(v reflect.Value, s string) error {
+	items := splitList(s)
+	m := reflect.MakeMapWithSize(v.Type(), len(items))
+	for _, item := range items {
+		ks, vs, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("%w %q: expected key=value", errInvalidMapEntry, item)
+		}
+		key := reflect.New(v.Type().Key()).Elem()
+		if err := setValue(key, strings.TrimSpace(ks)); err != nil {
+			return fmt.Errorf("key %q: %w", ks, err)
+		}
+		val := reflect.New(v.Type().Elem()).Elem()
+		if err := setValue(val, strings.TrimSpace(vs)); err != nil {
+			return fmt.Errorf("value of %q: %w", ks, err)
+		}
+		m.SetMapIndex(key, val)
+	}
+	v.Set(m)
+	return nil
+}
+
+// splitList splits a comma separated list, ignoring surrounding spaces
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	items := strings.Split(s, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
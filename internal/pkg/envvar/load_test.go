@@ -0,0 +1,145 @@
+package envvar_test
+
+import (
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/ptr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testDatabaseConfig struct {
+	URL   url.URL `env:"URL"   required:"true"`
+	Conns int32   `env:"CONNS" default:"4"`
+}
+
+type testConfig struct {
+	LogLevel slog.Level         `env:"LOG_LEVEL" default:"INFO"`
+	Timeout  time.Duration      `env:"TIMEOUT"   default:"30s"`
+	Admin    mail.Address       `env:"ADMIN"`
+	Key      []byte             `env:"KEY"`
+	Debug    *bool              `env:"DEBUG"`
+	Origins  []string           `env:"ORIGINS"`
+	Limits   map[string]int     `env:"LIMITS"`
+	Database testDatabaseConfig `prefix:"DB_"`
+
+	unexported string //nolint:unused // must be skipped by Load
+}
+
+func TestLoad(t *testing.T) {
+	testCases := []struct {
+		desc    string
+		env     map[string]string
+		exp     testConfig
+		expErrs []string
+	}{
+		{
+			desc: "defaults",
+			env:  map[string]string{"DB_URL": "postgres://localhost/db"},
+			exp: testConfig{
+				LogLevel: slog.LevelInfo,
+				Timeout:  30 * time.Second,
+				Database: testDatabaseConfig{
+					URL:   url.URL{Scheme: "postgres", Host: "localhost", Path: "/db"},
+					Conns: 4,
+				},
+			},
+		},
+		{
+			desc: "every type",
+			env: map[string]string{
+				"LOG_LEVEL": "DEBUG",
+				"TIMEOUT":   "2m",
+				"ADMIN":     "Admin <admin@example.com>",
+				"KEY":       "414243",
+				"DEBUG":     "true",
+				"ORIGINS":   "https://a.example.com, https://b.example.com",
+				"LIMITS":    "read=100,write=10",
+				"DB_URL":    "postgres://localhost/db",
+				"DB_CONNS":  "8",
+			},
+			exp: testConfig{
+				LogLevel: slog.LevelDebug,
+				Timeout:  2 * time.Minute,
+				Admin:    mail.Address{Name: "Admin", Address: "admin@example.com"},
+				Key:      []byte("ABC"),
+				Debug:    ptr.Ref(true),
+				Origins:  []string{"https://a.example.com", "https://b.example.com"},
+				Limits:   map[string]int{"read": 100, "write": 10},
+				Database: testDatabaseConfig{
+					URL:   url.URL{Scheme: "postgres", Host: "localhost", Path: "/db"},
+					Conns: 8,
+				},
+			},
+		},
+		{
+			desc: "every error is reported",
+			env: map[string]string{
+				"TIMEOUT":  "60",
+				"LIMITS":   "read",
+				"DB_CONNS": "99999999999",
+			},
+			expErrs: []string{
+				"TIMEOUT: invalid duration",
+				"LIMITS: invalid map entry",
+				"DB_URL: required variable is not set",
+				"DB_CONNS: invalid integer",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Given:
+			for _, key := range []string{"LOG_LEVEL", "TIMEOUT", "ADMIN", "KEY", "DEBUG", "ORIGINS", "LIMITS", "DB_URL", "DB_CONNS"} {
+				t.Setenv(key, "")
+			}
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			// When:
+			var act testConfig
+			err := envvar.Load(&act)
+
+			// Then:
+			if len(tc.expErrs) > 0 {
+				require.Error(t, err)
+				for _, exp := range tc.expErrs {
+					assert.Contains(t, err.Error(), exp)
+				}
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestLoad_RequiredError(t *testing.T) {
+	// Given:
+	t.Setenv("DB_URL", "")
+
+	// When:
+	var act testConfig
+	err := envvar.Load(&act)
+
+	// Then:
+	require.ErrorIs(t, err, envvar.ErrRequired)
+}
+
+func TestLoad_NotAStruct(t *testing.T) {
+	// When:
+	var act string
+	err := envvar.Load(&act)
+
+	// Then:
+	require.ErrorIs(t, err, envvar.ErrUnsupportedType)
+}
@@ -0,0 +1,72 @@
+package envvar
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// FileSuffix is appended to a variable name to read its value from a file,
+// following the Docker and Kubernetes secrets convention (e.g. DATABASE_URL_FILE)
+const FileSuffix = "_FILE"
+
+// fileScheme prefixes a value that is a reference to a file holding the actual value
+const fileScheme = "file://"
+
+// ErrConflictingFile is returned when both a variable and its _FILE variant are set in the same source
+var ErrConflictingFile = errors.New("both variable and its _FILE variant are set")
+
+// Lookup returns the value of the variable named by key, like os.LookupEnv.
+// The value is read from a file instead when key_FILE is set, or when the
+// value is a file:// reference. Trailing newlines of file contents are stripped.
+func Lookup(key string) (string, bool, error) {
+	value, _, ok, err := lookup([]Source{Env}, key)
+	if err != nil {
+		return "", false, fmt.Errorf("envvar.Lookup %s: %w", key, err)
+	}
+	return value, ok, nil
+}
+
+// lookup resolves key like Lookup, in sources of increasing precedence, where
+// key_FILE overrides key when set in a source of higher precedence.
+// It also returns the name of the source that provided the value.
+func lookup(sources []Source, key string) (string, string, bool, error) {
+	value, i := lookupSources(sources, key)
+	path, fileIdx := lookupSources(sources, key+FileSuffix)
+
+	switch {
+	case fileIdx >= 0 && path != "" && fileIdx == i && value != "":
+		return "", "", false, ErrConflictingFile
+	case fileIdx >= 0 && path != "" && fileIdx >= i:
+		value, ok, err := readFile(path)
+		return value, sources[fileIdx].Name, ok, err
+	case i < 0:
+		return "", "", false, nil
+	case strings.HasPrefix(value, fileScheme):
+		value, ok, err := readFile(strings.TrimPrefix(value, fileScheme))
+		return value, sources[i].Name, ok, err
+	default:
+		return value, sources[i].Name, true, nil
+	}
+}
+
+// lookupSources returns the value of key in the source of highest precedence
+// defining it, along with the index of that source or -1 if none does
+func lookupSources(sources []Source, key string) (string, int) {
+	for i := len(sources) - 1; i >= 0; i-- {
+		if v, ok := sources[i].Lookup(key); ok {
+			return v, i
+		}
+	}
+	return "", -1
+}
+
+// readFile returns the content of a secret file without its trailing newlines
+func readFile(path string) (string, bool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("os.ReadFile: %w", err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), true, nil
+}
@@ -0,0 +1,84 @@
+package envvar_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-starter/internal/pkg/envvar"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte("s3cr3t\n"), 0o600))
+
+	testCases := []struct {
+		desc   string
+		env    map[string]string
+		exp    string
+		expOK  bool
+		expErr error
+	}{
+		{
+			desc:  "plain value",
+			env:   map[string]string{"TEST_SECRET": "plain"},
+			exp:   "plain",
+			expOK: true,
+		},
+		{
+			desc: "unset",
+			env:  map[string]string{},
+		},
+		{
+			desc:  "_FILE variable",
+			env:   map[string]string{"TEST_SECRET_FILE": path},
+			exp:   "s3cr3t",
+			expOK: true,
+		},
+		{
+			desc:  "file:// reference",
+			env:   map[string]string{"TEST_SECRET": "file://" + path},
+			exp:   "s3cr3t",
+			expOK: true,
+		},
+		{
+			desc:   "both set",
+			env:    map[string]string{"TEST_SECRET": "plain", "TEST_SECRET_FILE": path},
+			expErr: envvar.ErrConflictingFile,
+		},
+		{
+			desc:   "missing file",
+			env:    map[string]string{"TEST_SECRET_FILE": path + ".missing"},
+			expErr: os.ErrNotExist,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// Given:
+			t.Setenv("TEST_SECRET", "")
+			t.Setenv("TEST_SECRET_FILE", "")
+			os.Unsetenv("TEST_SECRET")
+			os.Unsetenv("TEST_SECRET_FILE")
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+
+			// When:
+			act, ok, err := envvar.Lookup("TEST_SECRET")
+
+			// Then:
+			if tc.expErr != nil {
+				require.ErrorIs(t, err, tc.expErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.expOK, ok)
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package envvar
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// redacted replaces the value of a Secret wherever it is printed
+const redacted = "[REDACTED]"
+
+// Secret is a sensitive value, such as a password or a connection string
+// embedding one. It redacts itself when logged, formatted or marshaled;
+// use Value to get the actual content.
+type Secret string
+
+// Value returns the secret in plain text
+func (s Secret) Value() string {
+	return string(s)
+}
+
+// String implements fmt.Stringer
+func (s Secret) String() string {
+	return s.redact()
+}
+
+// GoString implements fmt.GoStringer, used by the %#v verb
+func (s Secret) GoString() string {
+	return fmt.Sprintf("envvar.Secret(%q)", s.redact())
+}
+
+// LogValue implements slog.LogValuer
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.redact())
+}
+
+// MarshalText implements encoding.TextMarshaler, used when dumping configuration
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.redact()), nil
+}
+
+// redact returns the placeholder, keeping empty secrets visibly empty
+func (s Secret) redact() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// ParseSecret returns the variable named by key as a Secret, see Lookup
+func ParseSecret(key string) (Secret, error) {
+	s, _, err := Lookup(key)
+	if err != nil {
+		return "", fmt.Errorf("invalid secret for %s: %w", key, err)
+	}
+	return Secret(s), nil
+}
@@ -0,0 +1,144 @@
+package listen
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// handoffPrefix starts the names of sockets handed over by a restarting
+// server, followed by the escaped address they were opened for
+const handoffPrefix = "addr="
+
+// readyMessage is written by the new process once it serves requests
+const readyMessage = "READY"
+
+// Errors returned when handing sockets over
+var (
+	ErrNotListener = errors.New("listener has no file descriptor")
+	ErrNotReady    = errors.New("process exited before it was ready")
+)
+
+// handoffName names the sockets of addr when handing them over
+func handoffName(addr string) string {
+	return handoffPrefix + url.QueryEscape(addr)
+}
+
+// Handoff passes listening sockets to a new process of the server, which
+// takes them over in Listen rather than opening its addresses again. No
+// connection is refused while both processes run, as both accept on the
+// same sockets until the old one shuts down.
+type Handoff struct {
+	files []*os.File
+	names []string
+}
+
+// filer is implemented by the listeners of sockets, e.g. *net.TCPListener
+type filer interface {
+	File() (*os.File, error)
+}
+
+// Add adds the listeners opened for addr. Unix socket files are no longer
+// removed when these listeners close, since the new process still uses them.
+func (h *Handoff) Add(addr string, listeners ...net.Listener) error {
+	for _, l := range listeners {
+		fl, ok := l.(filer)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrNotListener, l.Addr())
+		}
+		if ul, ok := l.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf("listener.File: %w", err)
+		}
+		h.files = append(h.files, f)
+		h.names = append(h.names, handoffName(addr))
+	}
+	return nil
+}
+
+// Start starts cmd with the sockets and waits until the new process calls
+// Ready. The process is killed when it is not ready before ctx is done.
+func (h *Handoff) Start(ctx context.Context, cmd *exec.Cmd) error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return fmt.Errorf("os.Pipe: %w", err)
+	}
+	defer r.Close()
+
+	cmd.ExtraFiles = append(append(cmd.ExtraFiles, h.files...), w)
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env,
+		envListenParentPID+"="+strconv.Itoa(os.Getpid()),
+		envListenFDs+"="+strconv.Itoa(len(h.files)),
+		envListenFDNames+"="+strings.Join(h.names, ":"),
+		envReadyFD+"="+strconv.Itoa(firstFD+len(cmd.ExtraFiles)-1),
+	)
+
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return fmt.Errorf("cmd.Start: %w", err)
+	}
+
+	// The pipe reaches EOF without message when the process exits early
+	ready := make(chan error, 1)
+	go func() {
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		if strings.TrimSpace(line) != readyMessage {
+			ready <- ErrNotReady
+			return
+		}
+		ready <- nil
+	}()
+
+	select {
+	case err = <-ready:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return fmt.Errorf("process %d: %w", cmd.Process.Pid, err)
+	}
+
+	return nil
+}
+
+// Close closes the copies of the sockets held for the new process
+func (h *Handoff) Close() {
+	for _, f := range h.files {
+		f.Close()
+	}
+}
+
+// Ready tells the server that started this process with Handoff.Start that
+// it serves requests, so that the old process can shut down. It does
+// nothing when the process was started otherwise.
+func Ready() error {
+	fd, err := strconv.Atoi(os.Getenv(envReadyFD))
+	if err != nil {
+		return nil //nolint:nilerr // not started by Handoff.Start
+	}
+	os.Unsetenv(envReadyFD)
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.WriteString(readyMessage + "\n"); err != nil {
+		return fmt.Errorf("write ready: %w", err)
+	}
+	return nil
+}
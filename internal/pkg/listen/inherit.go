@@ -0,0 +1,123 @@
+package listen
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// firstFD is the first inherited file descriptor, after stdin, stdout and stderr
+const firstFD = 3
+
+// Variables describing inherited sockets, see sd_listen_fds(3). A server
+// restarting itself sets LISTEN_PARENT_PID instead of LISTEN_PID, since the
+// PID of the new process is not known before it starts.
+const (
+	envListenPID       = "LISTEN_PID"
+	envListenParentPID = "LISTEN_PARENT_PID"
+	envListenFDs       = "LISTEN_FDS"
+	envListenFDNames   = "LISTEN_FDNAMES"
+	envReadyFD         = "LISTEN_READY_FD"
+)
+
+// inheritedListener is a socket inherited from systemd, named by its
+// FileDescriptorName=, or from a restarting server, named after its address
+type inheritedListener struct {
+	name     string
+	listener net.Listener
+	taken    bool
+}
+
+// inherited holds the sockets inherited by the process, read once as the
+// variables are unset afterwards so that child processes do not take them for their own
+var inherited = struct {
+	sync.Mutex
+	once    sync.Once
+	sockets []*inheritedListener
+	err     error
+}{}
+
+// inheritedListeners returns the inherited sockets accepted by match and not
+// taken yet, marking them as taken
+func inheritedListeners(match func(name string) bool) ([]net.Listener, error) {
+	inherited.Lock()
+	defer inherited.Unlock()
+
+	inherited.once.Do(func() {
+		inherited.sockets, inherited.err = readInherited()
+	})
+	if inherited.err != nil {
+		return nil, inherited.err
+	}
+
+	var listeners []net.Listener
+	for _, s := range inherited.sockets {
+		if !s.taken && match(s.name) {
+			s.taken = true
+			listeners = append(listeners, s.listener)
+		}
+	}
+	return listeners, nil
+}
+
+// readInherited reads the sockets passed by systemd or by a restarting parent
+func readInherited() ([]*inheritedListener, error) {
+	defer func() {
+		os.Unsetenv(envListenPID)
+		os.Unsetenv(envListenParentPID)
+		os.Unsetenv(envListenFDs)
+		os.Unsetenv(envListenFDNames)
+	}()
+
+	pid, _ := strconv.Atoi(os.Getenv(envListenPID))
+	ppid, _ := strconv.Atoi(os.Getenv(envListenParentPID))
+	if pid != os.Getpid() && ppid != os.Getppid() {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(os.Getenv(envListenFDs))
+	if err != nil || n < 1 {
+		return nil, nil //nolint:nilerr // no sockets were passed
+	}
+	names := strings.Split(os.Getenv(envListenFDNames), ":")
+
+	sockets := make([]*inheritedListener, 0, n)
+	for i := range n {
+		fd := firstFD + i
+		syscall.CloseOnExec(fd)
+
+		name := "unknown" // As named by systemd when FileDescriptorName= is not set
+		if i < len(names) && names[i] != "" {
+			name = names[i]
+		}
+
+		f := os.NewFile(uintptr(fd), name)
+		l, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("net.FileListener %s: %w", name, err)
+		}
+		sockets = append(sockets, &inheritedListener{name: name, listener: l, taken: false})
+	}
+
+	return sockets, nil
+}
+
+// systemdListeners returns the sockets passed by systemd, only those named
+// name unless empty
+func systemdListeners(name string) ([]net.Listener, error) {
+	listeners, err := inheritedListeners(func(n string) bool {
+		return !strings.HasPrefix(n, handoffPrefix) && (name == "" || n == name)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(listeners) == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrNoSystemdSocket, name)
+	}
+
+	return listeners, nil
+}
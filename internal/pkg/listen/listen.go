@@ -0,0 +1,202 @@
+// Package listen opens the network listeners of servers from address strings,
+// covering TCP, Unix domain sockets and systemd socket activation.
+package listen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Address prefixes selecting the kind of listener
+const (
+	UnixPrefix    = "unix:"
+	SystemdPrefix = "systemd"
+)
+
+// Errors returned when opening listeners
+var (
+	ErrAddrInUse       = errors.New("address already in use")
+	ErrInvalidMode     = errors.New("invalid file mode")
+	ErrInvalidOwner    = errors.New("invalid owner")
+	ErrNoSystemdSocket = errors.New("no socket passed by systemd")
+)
+
+// options holds the settings of Unix domain sockets
+type options struct {
+	mode  fs.FileMode
+	owner Owner
+}
+
+// Option is a function that configures Listen
+type Option func(*options)
+
+// WithMode sets the permissions of Unix socket files, e.g. 0o660 to let the
+// group of a reverse proxy connect
+func WithMode(mode fs.FileMode) Option {
+	return func(o *options) {
+		o.mode = mode
+	}
+}
+
+// WithOwner sets the owner of Unix socket files
+func WithOwner(owner Owner) Option {
+	return func(o *options) {
+		o.owner = owner
+	}
+}
+
+// Listen opens the listeners of addr, which is one of:
+//   - "host:port": a TCP address, e.g. ":8080" or "127.0.0.1:9090"
+//   - "unix:/path/to.sock": a Unix domain socket
+//   - "systemd": every socket passed by systemd socket activation
+//   - "systemd:name": the sockets of systemd named by FileDescriptorName=
+//
+// Sockets handed over by a restarting server for addr are used rather than
+// opening new ones, see Handoff.
+//
+// Defaults: Unix sockets keep the mode given by the umask and the owner of the process.
+func Listen(ctx context.Context, addr string, opts ...Option) ([]net.Listener, error) {
+	o := &options{mode: 0, owner: Owner{uid: -1, gid: -1, text: ""}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	handedOver, err := inheritedListeners(func(name string) bool { return name == handoffName(addr) })
+	if err != nil {
+		return nil, err
+	}
+	if len(handedOver) > 0 {
+		for _, l := range handedOver {
+			// The socket file is this process's to remove now
+			if ul, ok := l.(*net.UnixListener); ok {
+				ul.SetUnlinkOnClose(true)
+			}
+		}
+		return handedOver, nil
+	}
+
+	switch {
+	case strings.HasPrefix(addr, UnixPrefix):
+		l, err := listenUnix(ctx, strings.TrimPrefix(strings.TrimPrefix(addr, UnixPrefix), "//"), o)
+		if err != nil {
+			return nil, err
+		}
+		return []net.Listener{l}, nil
+	case addr == SystemdPrefix || strings.HasPrefix(addr, SystemdPrefix+":"):
+		return systemdListeners(strings.TrimPrefix(strings.TrimPrefix(addr, SystemdPrefix), ":"))
+	default:
+		var lc net.ListenConfig
+		l, err := lc.Listen(ctx, "tcp", addr)
+		if err != nil {
+			return nil, fmt.Errorf("net.Listen: %w", err)
+		}
+		return []net.Listener{l}, nil
+	}
+}
+
+// listenUnix listens on a Unix socket, replacing the file left by a process
+// that did not exit cleanly but not the socket of one still running
+func listenUnix(ctx context.Context, path string, o *options) (net.Listener, error) {
+	if fi, err := os.Stat(path); err == nil && fi.Mode().Type() == fs.ModeSocket {
+		d := net.Dialer{Timeout: time.Second}
+		if conn, err := d.DialContext(ctx, "unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%w: %s", ErrAddrInUse, path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("os.Remove: %w", err)
+		}
+	}
+
+	var lc net.ListenConfig
+	l, err := lc.Listen(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("net.Listen: %w", err)
+	}
+
+	if o.mode != 0 {
+		if err := os.Chmod(path, o.mode); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("os.Chmod: %w", err)
+		}
+	}
+	if o.owner.text != "" {
+		if err := os.Chown(path, o.owner.uid, o.owner.gid); err != nil {
+			l.Close()
+			return nil, fmt.Errorf("os.Chown: %w", err)
+		}
+	}
+
+	return l, nil
+}
+
+// FileMode is a file permission written in octal, e.g. "0660"
+type FileMode fs.FileMode
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (m *FileMode) UnmarshalText(text []byte) error {
+	n, err := strconv.ParseUint(string(text), 8, 32)
+	if err != nil || n > uint64(fs.ModePerm) {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, text)
+	}
+	*m = FileMode(n)
+	return nil
+}
+
+// String returns the mode in octal, as accepted by UnmarshalText
+func (m FileMode) String() string {
+	return fmt.Sprintf("%04o", uint32(m))
+}
+
+// Owner is the user and group owning a file, written "user", "user:group"
+// or ":group" with names or numeric IDs. The zero value changes nothing.
+type Owner struct {
+	uid  int // -1 when unchanged
+	gid  int // -1 when unchanged
+	text string
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, resolving names to IDs
+func (o *Owner) UnmarshalText(text []byte) error {
+	owner := Owner{uid: -1, gid: -1, text: string(text)}
+	name, group, _ := strings.Cut(string(text), ":")
+
+	if name != "" {
+		uid, err := strconv.Atoi(name)
+		if err != nil {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidOwner, err)
+			}
+			uid, _ = strconv.Atoi(u.Uid)
+		}
+		owner.uid = uid
+	}
+	if group != "" {
+		gid, err := strconv.Atoi(group)
+		if err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrInvalidOwner, err)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+		owner.gid = gid
+	}
+
+	*o = owner
+	return nil
+}
+
+// String returns the owner as it was written
+func (o Owner) String() string {
+	return o.text
+}
@@ -0,0 +1,214 @@
+package listen_test
+
+import (
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"go-starter/internal/pkg/listen"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListen(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		// Given:
+		// When:
+		ls, err := listen.Listen(t.Context(), "127.0.0.1:0")
+
+		// Then:
+		require.NoError(t, err)
+		require.Len(t, ls, 1)
+		t.Cleanup(func() { ls[0].Close() })
+		assert.Equal(t, "tcp", ls[0].Addr().Network())
+	})
+
+	t.Run("unix socket with mode and owner", func(t *testing.T) {
+		// Given:
+		path := filepath.Join(t.TempDir(), "app.sock")
+		var owner listen.Owner
+		require.NoError(t, owner.UnmarshalText([]byte(":"+strconv.Itoa(os.Getgid()))))
+
+		// When:
+		ls, err := listen.Listen(t.Context(), "unix:"+path, listen.WithMode(0o660), listen.WithOwner(owner))
+
+		// Then:
+		require.NoError(t, err)
+		require.Len(t, ls, 1)
+		fi, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, fs.ModeSocket|0o660, fi.Mode())
+
+		conn, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		conn.Close()
+
+		ls[0].Close()
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("stale unix socket", func(t *testing.T) {
+		// Given: the socket file of a process that did not exit cleanly
+		path := filepath.Join(t.TempDir(), "app.sock")
+		l, err := net.Listen("unix", path)
+		require.NoError(t, err)
+		l.(*net.UnixListener).SetUnlinkOnClose(false)
+		l.Close()
+		require.FileExists(t, path)
+
+		// When:
+		ls, err := listen.Listen(t.Context(), "unix://"+path)
+
+		// Then:
+		require.NoError(t, err)
+		ls[0].Close()
+	})
+
+	t.Run("unix socket in use", func(t *testing.T) {
+		// Given:
+		path := filepath.Join(t.TempDir(), "app.sock")
+		ls, err := listen.Listen(t.Context(), "unix:"+path)
+		require.NoError(t, err)
+		t.Cleanup(func() { ls[0].Close() })
+
+		// When:
+		_, err = listen.Listen(t.Context(), "unix:"+path)
+
+		// Then:
+		require.ErrorIs(t, err, listen.ErrAddrInUse)
+	})
+
+	t.Run("no systemd socket", func(t *testing.T) {
+		// Given:
+		// When:
+		_, err := listen.Listen(t.Context(), "systemd:http")
+
+		// Then:
+		require.ErrorIs(t, err, listen.ErrNoSystemdSocket)
+	})
+}
+
+func TestListen_Systemd(t *testing.T) {
+	if os.Getenv("LISTEN_FDS") != "" {
+		// Child process started below, as systemd would
+		ls, err := listen.Listen(t.Context(), "systemd:http")
+		require.NoError(t, err)
+		require.Len(t, ls, 1)
+		assert.Equal(t, os.Getenv("WANT_ADDR"), ls[0].Addr().String())
+		assert.Empty(t, os.Getenv("LISTEN_FDS"))
+		return
+	}
+
+	// Given: a socket passed as the second file descriptor, named "http"
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	f, err := l.(*net.TCPListener).File()
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	// When: LISTEN_PID is the PID of the shell, kept by exec
+	cmd := exec.CommandContext(t.Context(), "sh", "-c", `LISTEN_PID=$$ exec "$0" -test.run=^TestListen_Systemd$`, os.Args[0])
+	cmd.Env = append(os.Environ(), "LISTEN_FDS=2", "LISTEN_FDNAMES=admin:http", "WANT_ADDR="+l.Addr().String())
+	cmd.ExtraFiles = []*os.File{f, f}
+	out, err := cmd.CombinedOutput()
+
+	// Then:
+	require.NoError(t, err, string(out))
+}
+
+func TestFileMode_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    listen.FileMode
+		wantErr error
+	}{
+		{text: "0660", want: 0o660, wantErr: nil},
+		{text: "600", want: 0o600, wantErr: nil},
+		{text: "0888", want: 0, wantErr: listen.ErrInvalidMode},
+		{text: "17777", want: 0, wantErr: listen.ErrInvalidMode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			// Given:
+			var got listen.FileMode
+
+			// When:
+			err := got.UnmarshalText([]byte(tt.text))
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, "0"+tt.text[len(tt.text)-3:], got.String())
+		})
+	}
+}
+
+func TestOwner_UnmarshalText(t *testing.T) {
+	// Given:
+	var owner listen.Owner
+
+	// When:
+	// Then:
+	require.NoError(t, owner.UnmarshalText([]byte("0:0")))
+	assert.Equal(t, "0:0", owner.String())
+	require.ErrorIs(t, owner.UnmarshalText([]byte("no-such-user-1234")), listen.ErrInvalidOwner)
+}
+
+func TestHandoff(t *testing.T) {
+	if mode := os.Getenv("HANDOFF_CHILD"); mode != "" {
+		// Child process started below, as a restarted server
+		ls, err := listen.Listen(t.Context(), "127.0.0.1:0")
+		require.NoError(t, err)
+		require.Len(t, ls, 1)
+		require.Equal(t, os.Getenv("WANT_ADDR"), ls[0].Addr().String())
+		if mode == "ready" {
+			require.NoError(t, listen.Ready())
+		}
+		return
+	}
+
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr error
+	}{
+		{name: "ready", mode: "ready", wantErr: nil},
+		{name: "exits before ready", mode: "exit", wantErr: listen.ErrNotReady},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			ls, err := listen.Listen(t.Context(), "127.0.0.1:0")
+			require.NoError(t, err)
+			t.Cleanup(func() { ls[0].Close() })
+
+			var h listen.Handoff
+			t.Cleanup(h.Close)
+			require.NoError(t, h.Add("127.0.0.1:0", ls...))
+
+			cmd := exec.CommandContext(t.Context(), os.Args[0], "-test.run=^TestHandoff$")
+			cmd.Env = append(os.Environ(), "HANDOFF_CHILD="+tt.mode, "WANT_ADDR="+ls[0].Addr().String())
+
+			// When:
+			err = h.Start(t.Context(), cmd)
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, cmd.Wait())
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package migrate applies SQL migrations following the conventions of
+// golang-migrate: files named {version}_{title}.up.sql and
+// {version}_{title}.down.sql, with the current version kept in the
+// schema_migrations table, so that both tools can be used on the same database.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by the migrator
+var (
+	ErrDirty            = errors.New("database is dirty, fix it and force the version with golang-migrate")
+	ErrUnknownVersion   = errors.New("database version has no migration file")
+	ErrInvalidMigration = errors.New("invalid migration files")
+)
+
+// lockID is the key of the advisory lock preventing concurrent migrations
+const lockID int64 = 0x676f2d6d69677261 // "go-migra"
+
+// fileRe matches migration file names, e.g. 000001_create_post_table.up.sql
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Conn is a single database session, as advisory locks are held per session,
+// e.g. a *pgx.Conn or a *pgxpool.Conn
+type Conn interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Migration is a version with its up and down scripts
+type Migration struct {
+	Version uint64
+	Name    string
+	Up      string // Path of the up script
+	Down    string // Path of the down script, empty when the migration is irreversible
+}
+
+// Migrator applies the migrations found in a file system
+type Migrator struct {
+	fsys       fs.FS
+	migrations []Migration // Sorted by version
+}
+
+// New reads the migrations at the root of fsys, e.g. an embed.FS
+func New(fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("fs.ReadDir: %w", err)
+	}
+
+	byVersion := map[uint64]*Migration{}
+	for _, e := range entries {
+		m := fileRe.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidMigration, e.Name(), err)
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2], Up: "", Down: ""}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("%w: version %d has different names", ErrInvalidMigration, version)
+		}
+		if m[3] == "up" {
+			mig.Up = e.Name()
+		} else {
+			mig.Down = e.Name()
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("%w: version %d has no up script", ErrInvalidMigration, m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return &Migrator{fsys: fsys, migrations: migrations}, nil
+}
+
+// Migrations returns every migration, sorted by version
+func (m *Migrator) Migrations() []Migration {
+	return slices.Clone(m.migrations)
+}
+
+// Version returns the current version of the database, 0 when no migration was applied
+func (m *Migrator) Version(ctx context.Context, conn Conn) (uint64, bool, error) {
+	if err := ensureTable(ctx, conn); err != nil {
+		return 0, false, err
+	}
+	return version(ctx, conn)
+}
+
+// Up applies the next n pending migrations, all of them when n is 0,
+// and returns the migrations applied
+func (m *Migrator) Up(ctx context.Context, conn Conn, n int) ([]Migration, error) {
+	return m.migrate(ctx, conn, n, true)
+}
+
+// Down rolls back the last n applied migrations, all of them when n is 0,
+// and returns the migrations rolled back
+func (m *Migrator) Down(ctx context.Context, conn Conn, n int) ([]Migration, error) {
+	return m.migrate(ctx, conn, n, false)
+}
+
+// migrate applies the plan under an advisory lock, one transaction per migration
+func (m *Migrator) migrate(ctx context.Context, conn Conn, n int, up bool) ([]Migration, error) {
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return nil, fmt.Errorf("pg_advisory_lock: %w", err)
+	}
+	defer func() {
+		//nolint:contextcheck // unlock even when ctx is canceled
+		_, _ = conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", lockID)
+	}()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	current, dirty, err := version(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	if dirty {
+		return nil, fmt.Errorf("%w: version %d", ErrDirty, current)
+	}
+
+	steps, err := m.Plan(current, n, up)
+	if err != nil {
+		return nil, err
+	}
+
+	logger := slogr.FromContext(ctx)
+	done := make([]Migration, 0, len(steps))
+	for _, s := range steps {
+		if err := m.apply(ctx, conn, s, up); err != nil {
+			return done, err
+		}
+		logger.Info("[migrate] applied migration",
+			slog.Uint64("version", s.Version),
+			slog.String("name", s.Name),
+			slog.Bool("up", up))
+		done = append(done, s)
+	}
+
+	return done, nil
+}
+
+// Plan returns the migrations to apply, in order, to go n steps up or down from
+// the current version. It fails when the current version has no migration file.
+func (m *Migrator) Plan(current uint64, n int, up bool) ([]Migration, error) {
+	idx := -1 // Index of the current migration, -1 when none was applied
+	if current != 0 {
+		idx = slices.IndexFunc(m.migrations, func(mig Migration) bool { return mig.Version == current })
+		if idx < 0 {
+			return nil, fmt.Errorf("%w: %d", ErrUnknownVersion, current)
+		}
+	}
+
+	var steps []Migration
+	if up {
+		steps = slices.Clone(m.migrations[idx+1:])
+	} else {
+		steps = slices.Clone(m.migrations[:idx+1])
+		slices.Reverse(steps)
+	}
+	if n > 0 && n < len(steps) {
+		steps = steps[:n]
+	}
+
+	return steps, nil
+}
+
+// apply runs a script and records the new version in a single transaction
+func (m *Migrator) apply(ctx context.Context, conn Conn, mig Migration, up bool) error {
+	file := mig.Up
+	if !up {
+		file = mig.Down
+		if file == "" {
+			return fmt.Errorf("%w: version %d has no down script", ErrInvalidMigration, mig.Version)
+		}
+	}
+	script, err := fs.ReadFile(m.fsys, file)
+	if err != nil {
+		return fmt.Errorf("fs.ReadFile: %w", err)
+	}
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("conn.Begin: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, string(script)); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	if _, err := tx.Exec(ctx, "TRUNCATE schema_migrations"); err != nil {
+		return fmt.Errorf("truncate schema_migrations: %w", err)
+	}
+	next := mig.Version
+	if !up {
+		next = m.previous(mig.Version)
+	}
+	if next != 0 {
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", int64(next)); err != nil { //nolint:gosec // versions are small
+			return fmt.Errorf("insert schema_migrations: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("tx.Commit: %w", err)
+	}
+	return nil
+}
+
+// previous returns the version before v, 0 when v is the first one
+func (m *Migrator) previous(v uint64) uint64 {
+	var prev uint64
+	for _, mig := range m.migrations {
+		if mig.Version >= v {
+			break
+		}
+		prev = mig.Version
+	}
+	return prev
+}
+
+// ensureTable creates the version table of golang-migrate when missing
+func ensureTable(ctx context.Context, conn Conn) error {
+	_, err := conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)")
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+	return nil
+}
+
+// version reads the current version, 0 when no migration was applied
+func version(ctx context.Context, conn Conn) (uint64, bool, error) {
+	var (
+		v     int64
+		dirty bool
+	)
+	err := conn.QueryRow(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&v, &dirty)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("select schema_migrations: %w", err)
+	}
+	return uint64(v), dirty, nil //nolint:gosec // versions are never negative
+}
@@ -0,0 +1,126 @@
+package migrate_test
+
+import (
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"go-starter/database"
+	"go-starter/internal/pkg/migrate"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   []string
+		want    []migrate.Migration
+		wantErr error
+	}{
+		{
+			name:  "sorted by version",
+			files: []string{"10_b.up.sql", "10_b.down.sql", "2_a.up.sql", "README.md"},
+			want: []migrate.Migration{
+				{Version: 2, Name: "a", Up: "2_a.up.sql", Down: ""},
+				{Version: 10, Name: "b", Up: "10_b.up.sql", Down: "10_b.down.sql"},
+			},
+			wantErr: nil,
+		},
+		{
+			name:    "missing up script",
+			files:   []string{"1_a.down.sql"},
+			want:    nil,
+			wantErr: migrate.ErrInvalidMigration,
+		},
+		{
+			name:    "different names",
+			files:   []string{"1_a.up.sql", "1_b.down.sql"},
+			want:    nil,
+			wantErr: migrate.ErrInvalidMigration,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			fsys := fstest.MapFS{}
+			for _, f := range tt.files {
+				fsys[f] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+			}
+
+			// When:
+			m, err := migrate.New(fsys)
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, m.Migrations())
+		})
+	}
+}
+
+func TestMigrator_Plan(t *testing.T) {
+	tests := []struct {
+		name         string
+		current      uint64
+		n            int
+		up           bool
+		wantVersions []uint64
+		wantErr      error
+	}{
+		{name: "up from scratch", current: 0, n: 0, up: true, wantVersions: []uint64{1, 2, 3}, wantErr: nil},
+		{name: "up one step", current: 1, n: 1, up: true, wantVersions: []uint64{2}, wantErr: nil},
+		{name: "up to date", current: 3, n: 0, up: true, wantVersions: []uint64{}, wantErr: nil},
+		{name: "down two steps", current: 3, n: 2, up: false, wantVersions: []uint64{3, 2}, wantErr: nil},
+		{name: "down all", current: 2, n: 0, up: false, wantVersions: []uint64{2, 1}, wantErr: nil},
+		{name: "down from scratch", current: 0, n: 1, up: false, wantVersions: []uint64{}, wantErr: nil},
+		{name: "unknown version", current: 7, n: 0, up: true, wantVersions: nil, wantErr: migrate.ErrUnknownVersion},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			m, err := migrate.New(fstest.MapFS{
+				"1_a.up.sql":   &fstest.MapFile{Data: []byte("")},
+				"2_b.up.sql":   &fstest.MapFile{Data: []byte("")},
+				"3_c.up.sql":   &fstest.MapFile{Data: []byte("")},
+				"3_c.down.sql": &fstest.MapFile{Data: []byte("")},
+			})
+			require.NoError(t, err)
+
+			// When:
+			steps, err := m.Plan(tt.current, tt.n, tt.up)
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			versions := []uint64{}
+			for _, s := range steps {
+				versions = append(versions, s.Version)
+			}
+			assert.Equal(t, tt.wantVersions, versions)
+		})
+	}
+}
+
+func TestNew_EmbeddedMigrations(t *testing.T) {
+	// Given:
+	fsys, err := fs.Sub(database.Migrations, "migrations")
+	require.NoError(t, err)
+
+	// When:
+	m, err := migrate.New(fsys)
+
+	// Then:
+	require.NoError(t, err)
+	for _, mig := range m.Migrations() {
+		assert.NotEmpty(t, mig.Down, "version %d has no down script", mig.Version)
+	}
+	assert.NotEmpty(t, m.Migrations())
+}
@@ -0,0 +1,143 @@
+package pgnotify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Handler is called for every notification received by a Listener
+type Handler func(ctx context.Context, n *pgconn.Notification)
+
+// Listener receives Postgres LISTEN/NOTIFY notifications on a dedicated
+// connection and reconnects with exponential backoff when it drops.
+type Listener struct {
+	connConfig *pgx.ConnConfig
+	channels   []string
+	onConnect  func(ctx context.Context)
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+// Option is a function that configures a Listener
+type Option func(*Listener)
+
+// WithOnConnect sets a callback invoked every time the listener (re)connects.
+// Notifications sent while disconnected are lost, so subscribers can use this
+// to resynchronise their state.
+func WithOnConnect(onConnect func(ctx context.Context)) Option {
+	return func(l *Listener) {
+		l.onConnect = onConnect
+	}
+}
+
+// WithBackoff sets the minimum and maximum delay between reconnect attempts
+func WithBackoff(minBackoff, maxBackoff time.Duration) Option {
+	return func(l *Listener) {
+		l.minBackoff = minBackoff
+		l.maxBackoff = maxBackoff
+	}
+}
+
+// NewListener creates a listener for the given channels.
+// The connection config is usually taken from pgxpool.Pool.Config().ConnConfig.
+func NewListener(connConfig *pgx.ConnConfig, channels []string, opts ...Option) *Listener {
+	l := &Listener{
+		connConfig: connConfig,
+		channels:   channels,
+		onConnect:  nil,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+	}
+	for _, o := range opts {
+		o(l)
+	}
+
+	return l
+}
+
+// Listen delivers notifications to fn until the context is canceled
+func (l *Listener) Listen(ctx context.Context, fn Handler) error {
+	logger := slogr.FromContext(ctx).With(slog.Any("channels", l.channels))
+
+	backoff := l.minBackoff
+	for {
+		err := l.listen(ctx, fn, func() { backoff = l.minBackoff })
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		logger.Warn("[pgnotify] connection lost, reconnecting",
+			slog.Any("err", err),
+			slog.Duration("backoff", backoff),
+		)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, l.maxBackoff)
+	}
+}
+
+// listen runs a single connection until it fails
+func (l *Listener) listen(ctx context.Context, fn Handler, connected func()) error {
+	conn, err := pgx.ConnectConfig(ctx, l.connConfig)
+	if err != nil {
+		return fmt.Errorf("pgx.ConnectConfig: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		_ = conn.Close(closeCtx)
+	}()
+
+	for _, ch := range l.channels {
+		if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{ch}.Sanitize()); err != nil {
+			return fmt.Errorf("listen %s: %w", ch, err)
+		}
+	}
+
+	connected()
+	if l.onConnect != nil {
+		l.onConnect(ctx)
+	}
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("conn.WaitForNotification: %w", err)
+		}
+		fn(ctx, n)
+	}
+}
+
+// ErrPayloadTooLarge is returned when a payload exceeds the NOTIFY limit
+var ErrPayloadTooLarge = errors.New("notify payload too large")
+
+// maxPayloadSize is the default limit of a NOTIFY payload in bytes
+const maxPayloadSize = 8000
+
+// Execer is the subset of pgx connections needed to send notifications
+type Execer interface {
+	Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error)
+}
+
+// Notify sends a payload on the channel, e.g. to fan out to other replicas
+func Notify(ctx context.Context, db Execer, channel string, payload string) error {
+	if len(payload) >= maxPayloadSize {
+		return fmt.Errorf("%w: %d bytes", ErrPayloadTooLarge, len(payload))
+	}
+	if _, err := db.Exec(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return fmt.Errorf("pg_notify: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package reload
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Change is a setting that differs between two configurations
+type Change struct {
+	Key string // Variable name from the env tag, or the field path without one
+	Old string // Previous value, formatted with fmt so that secrets stay redacted
+	New string // New value
+	Hot bool   // Whether the setting may change without a restart
+}
+
+// Diff lists the settings that differ between two configurations of the same
+// struct type. Fields follow the envvar.Load tags (env, prefix), and a field
+// tagged reload:"hot" marks itself, or every field of a nested struct, as
+// reloadable without a restart.
+func Diff[T any](old, next T) []Change {
+	var changes []Change
+	diff(reflect.ValueOf(old), reflect.ValueOf(next), "", "", false, &changes)
+	return changes
+}
+
+// diff compares the fields of two structs, recursing into nested structs
+func diff(old, next reflect.Value, prefix, path string, hot bool, changes *[]Change) {
+	t := old.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		fieldHot := hot || f.Tag.Get("reload") == "hot"
+		o, n := old.Field(i), next.Field(i)
+
+		name, ok := f.Tag.Lookup("env")
+		if !ok && f.Type.Kind() == reflect.Struct {
+			diff(o, n, prefix+f.Tag.Get("prefix"), path+f.Name+".", fieldHot, changes)
+			continue
+		}
+
+		if reflect.DeepEqual(o.Interface(), n.Interface()) {
+			continue
+		}
+
+		key := path + f.Name
+		if ok {
+			key = prefix + name
+		}
+		*changes = append(*changes, Change{
+			Key: key,
+			Old: format(o),
+			New: format(n),
+			Hot: fieldHot,
+		})
+	}
+}
+
+// format prints a value, dereferencing pointers
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "<nil>"
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,225 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ErrRestartRequired is returned when a reload changes settings that are only read at startup
+var ErrRestartRequired = errors.New("configuration change requires a restart")
+
+// Loader loads and validates a fresh configuration
+type Loader[T any] func() (T, error)
+
+// Applier puts the hot reloadable settings of a new configuration into effect
+type Applier[T any] func(ctx context.Context, cfg T) error
+
+// Reloader holds the current configuration and replaces it on SIGHUP or when
+// one of the watched files changes. Only fields tagged reload:"hot" may change,
+// any other change is rejected and logged.
+type Reloader[T any] struct {
+	load     Loader[T]
+	apply    Applier[T]
+	files    []string
+	debounce time.Duration
+
+	mu      sync.Mutex // serializes reloads
+	current atomic.Pointer[T]
+}
+
+// Option is a function that configures a Reloader
+type Option[T any] func(*Reloader[T])
+
+// WithFiles sets the files that trigger a reload when they change
+func WithFiles[T any](paths ...string) Option[T] {
+	return func(r *Reloader[T]) {
+		r.files = paths
+	}
+}
+
+// WithDebounce sets how long file changes are coalesced before reloading,
+// since editors usually write a file in several steps
+func WithDebounce[T any](d time.Duration) Option[T] {
+	return func(r *Reloader[T]) {
+		r.debounce = d
+	}
+}
+
+// New loads the initial configuration with load. Later reloads pass the new
+// configuration to apply before making it current.
+// Defaults: no watched files, changes are debounced for 100ms.
+func New[T any](load Loader[T], apply Applier[T], opts ...Option[T]) (*Reloader[T], error) {
+	r := &Reloader[T]{
+		load:     load,
+		apply:    apply,
+		files:    nil,
+		debounce: 100 * time.Millisecond,
+		mu:       sync.Mutex{},
+		current:  atomic.Pointer[T]{},
+	}
+	for _, o := range opts {
+		o(r)
+	}
+
+	cfg, err := load()
+	if err != nil {
+		return nil, fmt.Errorf("reload.New: %w", err)
+	}
+	r.current.Store(&cfg)
+
+	return r, nil
+}
+
+// Current returns the configuration in effect
+func (r *Reloader[T]) Current() T {
+	return *r.current.Load()
+}
+
+// Reload loads the configuration again and applies it if only hot reloadable
+// settings changed. The current configuration is kept on any error.
+func (r *Reloader[T]) Reload(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger := slogr.FromContext(ctx)
+
+	next, err := r.load()
+	if err != nil {
+		return fmt.Errorf("reload.Reload: %w", err)
+	}
+
+	changes := Diff(r.Current(), next)
+	if len(changes) == 0 {
+		logger.Info("[reload] configuration unchanged")
+		return nil
+	}
+
+	var restart []Change
+	for _, c := range changes {
+		if !c.Hot {
+			restart = append(restart, c)
+		}
+	}
+	if len(restart) > 0 {
+		for _, c := range restart {
+			logger.Warn("[reload] rejected change, restart required",
+				slog.String("key", c.Key), slog.String("old", c.Old), slog.String("new", c.New))
+		}
+		return fmt.Errorf("reload.Reload: %w: %d setting(s)", ErrRestartRequired, len(restart))
+	}
+
+	if err := r.apply(ctx, next); err != nil {
+		return fmt.Errorf("reload.Reload: apply: %w", err)
+	}
+	r.current.Store(&next)
+
+	for _, c := range changes {
+		logger.Info("[reload] applied change",
+			slog.String("key", c.Key), slog.String("old", c.Old), slog.String("new", c.New))
+	}
+
+	return nil
+}
+
+// Run reloads the configuration on SIGHUP and when a watched file changes,
+// until the context is canceled. Failed reloads are logged and do not stop Run.
+func (r *Reloader[T]) Run(ctx context.Context) error {
+	logger := slogr.FromContext(ctx)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	changed, err := r.watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-hup:
+			logger.Info("[reload] SIGHUP received")
+		case <-changed:
+			logger.Info("[reload] configuration file changed")
+		}
+
+		if err := r.Reload(ctx); err != nil {
+			logger.Error("[reload] failed", slog.Any("err", err))
+		}
+	}
+}
+
+// watch notifies on the returned channel, at most once per debounce period,
+// when one of the files is written, created, renamed or removed. Directories
+// are watched rather than files so that atomic replacements are seen, as done
+// by editors and by Kubernetes ConfigMap and Secret volumes.
+func (r *Reloader[T]) watch(ctx context.Context) (<-chan struct{}, error) {
+	changed := make(chan struct{}, 1)
+	if len(r.files) == 0 {
+		return changed, nil
+	}
+
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("fsnotify.NewWatcher: %w", err)
+	}
+
+	files := map[string]bool{}
+	for _, f := range r.files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			w.Close()
+			return nil, fmt.Errorf("filepath.Abs: %w", err)
+		}
+		files[abs] = true
+		if err := w.Add(filepath.Dir(abs)); err != nil {
+			w.Close()
+			return nil, fmt.Errorf("watcher.Add: %w", err)
+		}
+	}
+
+	go func() {
+		defer w.Close()
+
+		logger := slogr.FromContext(ctx)
+		timer := time.NewTimer(0)
+		<-timer.C
+
+		for {
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case ev := <-w.Events:
+				// Kubernetes swaps a ..data symlink, so any change in the directory counts then
+				if files[filepath.Clean(ev.Name)] || filepath.Base(ev.Name) == "..data" {
+					timer.Reset(r.debounce)
+				}
+			case err := <-w.Errors:
+				logger.Error("[reload] watcher error", slog.Any("err", err))
+			case <-timer.C:
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed, nil
+}
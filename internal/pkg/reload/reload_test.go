@@ -0,0 +1,137 @@
+package reload_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/envvar"
+	"go-starter/internal/pkg/reload"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Addr     string        `env:"ADDR"`
+	Password envvar.Secret `env:"PASSWORD"`
+	Level    string        `env:"LEVEL"    reload:"hot"`
+	Limits   testLimits    `prefix:"LIMIT_" reload:"hot"`
+}
+
+type testLimits struct {
+	RPS int `env:"RPS"`
+}
+
+func TestDiff(t *testing.T) {
+	testCases := []struct {
+		desc string
+		old  testConfig
+		next testConfig
+		exp  []reload.Change
+	}{
+		{
+			desc: "unchanged",
+			old:  testConfig{Addr: ":8080", Level: "INFO"},
+			next: testConfig{Addr: ":8080", Level: "INFO"},
+		},
+		{
+			desc: "hot and restart only changes",
+			old:  testConfig{Addr: ":8080", Level: "INFO", Limits: testLimits{RPS: 1}},
+			next: testConfig{Addr: ":9090", Level: "DEBUG", Limits: testLimits{RPS: 2}},
+			exp: []reload.Change{
+				{Key: "ADDR", Old: ":8080", New: ":9090", Hot: false},
+				{Key: "LEVEL", Old: "INFO", New: "DEBUG", Hot: true},
+				{Key: "LIMIT_RPS", Old: "1", New: "2", Hot: true},
+			},
+		},
+		{
+			desc: "secrets are redacted",
+			old:  testConfig{Password: "old"},
+			next: testConfig{Password: "new"},
+			exp: []reload.Change{
+				{Key: "PASSWORD", Old: "[REDACTED]", New: "[REDACTED]", Hot: false},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			// When:
+			act := reload.Diff(tc.old, tc.next)
+
+			// Then:
+			assert.Equal(t, tc.exp, act)
+		})
+	}
+}
+
+func TestReloader_Reload(t *testing.T) {
+	// Given:
+	next := testConfig{Addr: ":8080", Level: "INFO"}
+	var applied atomic.Int32
+	r, err := reload.New(
+		func() (testConfig, error) { return next, nil },
+		func(context.Context, testConfig) error {
+			applied.Add(1)
+			return nil
+		})
+	require.NoError(t, err)
+
+	// When: only hot settings change
+	next.Level = "DEBUG"
+	err = r.Reload(context.Background())
+
+	// Then: they are applied
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG", r.Current().Level)
+	assert.Equal(t, int32(1), applied.Load())
+
+	// When: a restart only setting changes
+	next.Level = "WARN"
+	next.Addr = ":9090"
+	err = r.Reload(context.Background())
+
+	// Then: the whole reload is rejected
+	require.ErrorIs(t, err, reload.ErrRestartRequired)
+	assert.Equal(t, testConfig{Addr: ":8080", Level: "DEBUG"}, r.Current())
+	assert.Equal(t, int32(1), applied.Load())
+}
+
+func TestReloader_Run_FileChange(t *testing.T) {
+	// Given:
+	path := filepath.Join(t.TempDir(), "config.env")
+	require.NoError(t, os.WriteFile(path, []byte("LEVEL=INFO\n"), 0o600))
+
+	load := func() (testConfig, error) {
+		src, err := envvar.ReadFile(path)
+		if err != nil {
+			return testConfig{}, err
+		}
+		var c testConfig
+		return c, envvar.Load(&c, envvar.WithSources(src))
+	}
+	r, err := reload.New(load,
+		func(context.Context, testConfig) error { return nil },
+		reload.WithFiles[testConfig](path),
+		reload.WithDebounce[testConfig](10*time.Millisecond))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		assert.NoError(t, r.Run(ctx))
+	}()
+
+	// When: the file is written, waiting for the watcher to start first
+	assert.Eventually(t, func() bool {
+		require.NoError(t, os.WriteFile(path, []byte("LEVEL=DEBUG\n"), 0o600))
+		return r.Current().Level == "DEBUG"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// Then:
+	assert.Equal(t, "DEBUG", r.Current().Level)
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// permanentError marks an error that must not be retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Do returns it immediately instead of retrying
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// config holds the retry policy
+type config struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+}
+
+// Option is a function that configures the retry policy
+type Option func(*config)
+
+// WithMaxAttempts sets the total number of attempts, including the first one
+func WithMaxAttempts(n int) Option {
+	return func(c *config) {
+		c.maxAttempts = n
+	}
+}
+
+// WithBackoff sets the base and maximum delay of the exponential backoff
+func WithBackoff(baseDelay, maxDelay time.Duration) Option {
+	return func(c *config) {
+		c.baseDelay = baseDelay
+		c.maxDelay = maxDelay
+	}
+}
+
+// Do calls fn until it succeeds, returns a Permanent error, runs out of
+// attempts or the context is done. Attempts are spaced with exponential
+// backoff and full jitter.
+// Defaults: 3 attempts, 100ms base delay capped at 2s.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	c := config{
+		maxAttempts: 3,
+		baseDelay:   100 * time.Millisecond,
+		maxDelay:    2 * time.Second,
+	}
+	for _, o := range opts {
+		o(&c)
+	}
+
+	var err error
+	for attempt := range c.maxAttempts {
+		if attempt > 0 {
+			timer := time.NewTimer(Backoff(attempt, c.baseDelay, c.maxDelay))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return errors.Join(err, ctx.Err())
+			case <-timer.C:
+			}
+		}
+
+		err = fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Backoff returns a random delay in [0, min(maxDelay, baseDelay*2^(attempt-1)))
+func Backoff(attempt int, baseDelay, maxDelay time.Duration) time.Duration {
+	ceiling := maxDelay
+	if attempt-1 < 32 {
+		if d := baseDelay << (attempt - 1); d > 0 && d < maxDelay {
+			ceiling = d
+		}
+	}
+	if ceiling <= 0 {
+		return 0
+	}
+	return rand.N(ceiling) //nolint:gosec // jitter does not need a secure source
+}
@@ -0,0 +1,108 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/retry"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	t.Parallel()
+
+	errTransient := errors.New("transient")
+
+	testCases := []struct {
+		desc      string
+		results   []error
+		wantCalls int
+		wantErr   error
+	}{
+		{
+			desc:      "success on first attempt",
+			results:   []error{nil},
+			wantCalls: 1,
+		},
+		{
+			desc:      "success after retries",
+			results:   []error{errTransient, errTransient, nil},
+			wantCalls: 3,
+		},
+		{
+			desc:      "attempts exhausted",
+			results:   []error{errTransient, errTransient, errTransient},
+			wantCalls: 3,
+			wantErr:   errTransient,
+		},
+		{
+			desc:      "permanent error",
+			results:   []error{retry.Permanent(errTransient)},
+			wantCalls: 1,
+			wantErr:   errTransient,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			calls := 0
+			fn := func(context.Context) error {
+				err := tc.results[calls]
+				calls++
+				return err
+			}
+
+			// When:
+			err := retry.Do(context.Background(), fn,
+				retry.WithMaxAttempts(3),
+				retry.WithBackoff(time.Millisecond, time.Millisecond))
+
+			// Then:
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+			assert.Equal(t, tc.wantCalls, calls)
+		})
+	}
+}
+
+func TestDo_ContextCanceled(t *testing.T) {
+	t.Parallel()
+
+	// Given:
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+
+	// When:
+	err := retry.Do(ctx, func(context.Context) error {
+		calls++
+		cancel()
+		return errors.New("transient")
+	}, retry.WithBackoff(time.Hour, time.Hour))
+
+	// Then:
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	for attempt := 1; attempt < 100; attempt++ {
+		got := retry.Backoff(attempt, 100*time.Millisecond, time.Second)
+		assert.GreaterOrEqual(t, got, time.Duration(0))
+		assert.Less(t, got, time.Second)
+		if attempt == 1 {
+			assert.Less(t, got, 100*time.Millisecond)
+		}
+	}
+}
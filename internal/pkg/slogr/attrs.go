@@ -0,0 +1,91 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// attrsCtxKey is the context key used to store the attributes added with WithAttrs
+type attrsCtxKey struct{}
+
+// WithAttrs returns a new context carrying attrs in addition to those already
+// in ctx, replacing the ones with the same key. Handlers wrapped with
+// ContextHandler add them to every record logged with this context, e.g. with
+// slog.InfoContext(ctx, ...), without passing a logger around.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	prev := Attrs(ctx)
+	merged := make([]slog.Attr, 0, len(prev)+len(attrs))
+	for _, a := range prev {
+		if !slices.ContainsFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key }) {
+			merged = append(merged, a)
+		}
+	}
+	merged = append(merged, attrs...)
+
+	return context.WithValue(ctx, attrsCtxKey{}, merged)
+}
+
+// Attrs returns the attributes added to ctx with WithAttrs
+func Attrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsCtxKey{}).([]slog.Attr)
+	return attrs
+}
+
+// contextHandler adds the attributes carried by the context to records
+type contextHandler struct {
+	next slog.Handler
+	keys []string // Keys already added with WithAttrs, which take precedence
+}
+
+// ContextHandler returns a handler adding the attributes of WithAttrs to every
+// record, unless the logger or the record already has an attribute with the
+// same key, so that request-scoped loggers do not repeat them.
+func ContextHandler(next slog.Handler) slog.Handler {
+	return &contextHandler{next: next, keys: nil}
+}
+
+// Enabled implements slog.Handler
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := Attrs(ctx)
+	if len(attrs) == 0 {
+		return h.next.Handle(ctx, r) //nolint:wrapcheck // errors of the wrapped handler are returned as is
+	}
+
+	keys := slices.Clone(h.keys)
+	r.Attrs(func(a slog.Attr) bool {
+		keys = append(keys, a.Key)
+		return true
+	})
+
+	r = r.Clone()
+	for _, a := range attrs {
+		if !slices.Contains(keys, a.Key) {
+			r.AddAttrs(a)
+		}
+	}
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // errors of the wrapped handler are returned as is
+}
+
+// WithAttrs implements slog.Handler
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	keys := slices.Clone(h.keys)
+	for _, a := range attrs {
+		keys = append(keys, a.Key)
+	}
+	return &contextHandler{next: h.next.WithAttrs(attrs), keys: keys}
+}
+
+// WithGroup implements slog.Handler. Context attributes of records logged
+// after the group is opened are added to the group.
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{next: h.next.WithGroup(name), keys: h.keys}
+}
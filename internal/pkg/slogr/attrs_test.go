@@ -0,0 +1,93 @@
+package slogr_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(context.Context, *slog.Logger)
+		want string
+	}{
+		{
+			name: "context attributes",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(ctx, "loaded", "rows", 2)
+			},
+			want: `msg=loaded rows=2 request-id=r1 post-id=p2` + "\n",
+		},
+		{
+			name: "no context",
+			log: func(_ context.Context, l *slog.Logger) {
+				l.Info("loaded")
+			},
+			want: `msg=loaded` + "\n",
+		},
+		{
+			name: "logger attributes take precedence",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.With("request-id", "r0").InfoContext(ctx, "loaded")
+			},
+			want: `msg=loaded request-id=r0 post-id=p2` + "\n",
+		},
+		{
+			name: "record attributes take precedence",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(ctx, "loaded", "post-id", "p3")
+			},
+			want: `msg=loaded post-id=p3 request-id=r1` + "\n",
+		},
+		{
+			name: "derived context replaces attributes",
+			log: func(ctx context.Context, l *slog.Logger) {
+				l.InfoContext(slogr.WithAttrs(ctx, slog.String("post-id", "p4"), slog.String("user-id", "u1")), "loaded")
+			},
+			want: `msg=loaded request-id=r1 post-id=p4 user-id=u1` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var buf bytes.Buffer
+			logger := slog.New(slogr.ContextHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				AddSource: false,
+				Level:     slog.LevelInfo,
+				ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+					if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+						return slog.Attr{}
+					}
+					return a
+				},
+			})))
+			ctx := slogr.WithAttrs(t.Context(), slog.String("request-id", "r1"))
+			ctx = slogr.WithAttrs(ctx, slog.String("post-id", "p2"))
+
+			// When:
+			tt.log(ctx, logger)
+
+			// Then:
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestAttrs(t *testing.T) {
+	// Given:
+	parent := slogr.WithAttrs(t.Context(), slog.String("request-id", "r1"))
+
+	// When:
+	child := slogr.WithAttrs(parent, slog.String("post-id", "p2"))
+
+	// Then:
+	assert.Equal(t, []slog.Attr{slog.String("request-id", "r1")}, slogr.Attrs(parent))
+	assert.Equal(t, []slog.Attr{slog.String("request-id", "r1"), slog.String("post-id", "p2")}, slogr.Attrs(child))
+	assert.Empty(t, slogr.Attrs(t.Context()))
+}
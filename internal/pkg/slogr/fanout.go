@@ -0,0 +1,61 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// fanoutHandler sends records to several handlers
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// Fanout returns a handler sending every record to each of the handlers that
+// is enabled for its level, so that sinks may log at different levels
+func Fanout(handlers ...slog.Handler) slog.Handler {
+	return &fanoutHandler{handlers: handlers}
+}
+
+// Enabled implements slog.Handler, reporting whether any handler is enabled
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements slog.Handler. A failing handler does not prevent the others from handling the record.
+// Records forced with WithLevel are sent to every handler.
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, hh := range h.handlers {
+		if !hh.Enabled(ctx, r.Level) && !forced(ctx) {
+			continue
+		}
+		if err := hh.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup implements slog.Handler
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
@@ -0,0 +1,100 @@
+package slogr
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Format is the output format of a log handler
+type Format string
+
+// Supported log formats
+const (
+	FormatJSON   Format = "json"   // One JSON object per line, for log collectors
+	FormatText   Format = "text"   // logfmt style key=value pairs
+	FormatPretty Format = "pretty" // Colorized and aligned, for humans during development
+)
+
+// ErrUnknownFormat is returned when parsing an unsupported log format
+var ErrUnknownFormat = errors.New("unknown log format")
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (f *Format) UnmarshalText(text []byte) error {
+	switch v := Format(strings.ToLower(string(text))); v {
+	case FormatJSON, FormatText, FormatPretty:
+		*f = v
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, text)
+	}
+}
+
+// Sink is a destination of log records, with its own format and level
+type Sink struct {
+	Writer io.Writer
+	Format Format
+	Level  slog.Leveler // Pass a *slog.LevelVar to change the level while running
+
+	// ReplaceAttr rewrites attributes before they are written, e.g. Redact
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+}
+
+// SetDefault configures the default logger to write to every sink,
+// adding the attributes carried by the context of records
+func SetDefault(sinks ...Sink) {
+	slog.SetDefault(slog.New(ContextHandler(Sinks(sinks...))))
+}
+
+// Sinks returns a handler writing to every sink.
+// The source of records is added when a sink starts at the debug level.
+func Sinks(sinks ...Sink) slog.Handler {
+	handlers := make([]slog.Handler, 0, len(sinks))
+	for _, s := range sinks {
+		handlers = append(handlers, NewHandler(s.Writer, s.Format, &slog.HandlerOptions{
+			Level:       s.Level,
+			AddSource:   s.Format != FormatPretty && s.Level.Level() == slog.LevelDebug,
+			ReplaceAttr: s.ReplaceAttr,
+		}))
+	}
+
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return Fanout(handlers...)
+}
+
+// NewHandler creates a handler writing records to w in the given format.
+// Pretty output is colorized when w is a terminal and NO_COLOR is not set.
+func NewHandler(w io.Writer, format Format, opts *slog.HandlerOptions) slog.Handler {
+	switch format {
+	case FormatText:
+		return slog.NewTextHandler(w, opts)
+	case FormatPretty:
+		return NewPrettyHandler(w, &PrettyOptions{
+			HandlerOptions: *opts,
+			NoColor:        !isTerminal(w) || os.Getenv("NO_COLOR") != "",
+			TimeFormat:     "",
+		})
+	case FormatJSON:
+		return slog.NewJSONHandler(w, opts)
+	default:
+		return slog.NewJSONHandler(w, opts)
+	}
+}
+
+// isTerminal reports whether w is a character device, such as a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
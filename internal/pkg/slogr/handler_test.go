@@ -0,0 +1,147 @@
+package slogr_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    slogr.Format
+		wantErr error
+	}{
+		{name: "json", text: "json", want: slogr.FormatJSON, wantErr: nil},
+		{name: "text", text: "text", want: slogr.FormatText, wantErr: nil},
+		{name: "pretty upper case", text: "PRETTY", want: slogr.FormatPretty, wantErr: nil},
+		{name: "unknown", text: "xml", want: "", wantErr: slogr.ErrUnknownFormat},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var f slogr.Format
+
+			// When:
+			err := f.UnmarshalText([]byte(tt.text))
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, f)
+		})
+	}
+}
+
+func TestPrettyHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		level slog.Level
+		log   func(*slog.Logger)
+		want  string
+	}{
+		{
+			name:  "aligned attributes",
+			level: slog.LevelInfo,
+			log: func(l *slog.Logger) {
+				l.Info("[server] started", "addr", ":8080")
+			},
+			want: "12:30:45.000 INF [server] started                         addr=:8080\n",
+		},
+		{
+			name:  "groups and quoting",
+			level: slog.LevelInfo,
+			log: func(l *slog.Logger) {
+				l.With("id", 1).WithGroup("req").Warn("slow", "path", "/a b", "err", errors.New("boom"))
+			},
+			want: "12:30:45.000 WRN slow                                     id=1 req.path=\"/a b\" req.err=boom\n",
+		},
+		{
+			name:  "debug shows the source",
+			level: slog.LevelDebug,
+			log: func(l *slog.Logger) {
+				l.Debug("details")
+			},
+			want: "12:30:45.000 DBG details source=handler_test.go:",
+		},
+		{
+			name:  "below level",
+			level: slog.LevelInfo,
+			log: func(l *slog.Logger) {
+				l.Debug("details")
+			},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var buf bytes.Buffer
+			h := slogr.NewPrettyHandler(&buf, &slogr.PrettyOptions{
+				HandlerOptions: slog.HandlerOptions{AddSource: false, Level: tt.level, ReplaceAttr: nil},
+				NoColor:        true,
+				TimeFormat:     "",
+			})
+			logger := slog.New(&fixedTime{Handler: h})
+
+			// When:
+			tt.log(logger)
+
+			// Then:
+			if tt.want == "" {
+				assert.Empty(t, buf.String())
+				return
+			}
+			assert.Contains(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestFanout(t *testing.T) {
+	// Given:
+	var debug, errs bytes.Buffer
+	logger := slog.New(slogr.Fanout(
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&errs, &slog.HandlerOptions{Level: slog.LevelError}),
+	)).With("component", "test")
+
+	// When:
+	logger.Debug("debug message")
+	logger.Error("error message")
+
+	// Then:
+	assert.Contains(t, debug.String(), "debug message")
+	assert.Contains(t, debug.String(), "error message")
+	assert.NotContains(t, errs.String(), "debug message")
+	assert.Contains(t, errs.String(), "msg=\"error message\" component=test")
+}
+
+// fixedTime sets the time of every record, so that the output is predictable
+type fixedTime struct {
+	slog.Handler
+}
+
+func (h *fixedTime) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = time.Date(2025, 1, 2, 12, 30, 45, 0, time.UTC)
+	return h.Handler.Handle(ctx, r) //nolint:wrapcheck // test helper
+}
+
+func (h *fixedTime) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &fixedTime{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *fixedTime) WithGroup(name string) slog.Handler {
+	return &fixedTime{Handler: h.Handler.WithGroup(name)}
+}
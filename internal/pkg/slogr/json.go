@@ -5,13 +5,8 @@ import (
 	"os"
 )
 
-// SetDefaultJSON configures the default logger to use a JSON handler
-func SetDefaultJSON(level slog.Level) {
-	h := slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-		Level:       level,
-		AddSource:   level == slog.LevelDebug,
-		ReplaceAttr: nil,
-	})
-
-	slog.SetDefault(slog.New(h))
+// SetDefaultJSON configures the default logger to use a JSON handler.
+// Pass a *slog.LevelVar to change the level while running.
+func SetDefaultJSON(level slog.Leveler) {
+	SetDefault(Sink{Writer: os.Stdout, Format: FormatJSON, Level: level, ReplaceAttr: nil})
 }
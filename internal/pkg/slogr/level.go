@@ -0,0 +1,52 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+)
+
+// forceCtxKey marks records that must be handled regardless of the handler levels
+type forceCtxKey struct{}
+
+// levelHandler logs records at or above its level, even below the level of the wrapped handler
+type levelHandler struct {
+	next  slog.Handler
+	level slog.Leveler
+}
+
+// WithLevel returns a logger that also logs the records at or above level that
+// logger would discard, e.g. to log at DEBUG for a single request while the
+// global level is INFO. It cannot raise the level of logger.
+func WithLevel(logger *slog.Logger, level slog.Leveler) *slog.Logger {
+	return slog.New(&levelHandler{next: logger.Handler(), level: level})
+}
+
+// Enabled implements slog.Handler
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() || h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler. Records the wrapped handler is not enabled for
+// are marked in ctx, so that Fanout and Sample let them through.
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.next.Enabled(ctx, r.Level) {
+		ctx = context.WithValue(ctx, forceCtxKey{}, true)
+	}
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // errors of the wrapped handler are returned as is
+}
+
+// WithAttrs implements slog.Handler
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+// WithGroup implements slog.Handler
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), level: h.level}
+}
+
+// forced reports whether the record being handled was let through by WithLevel
+func forced(ctx context.Context) bool {
+	v, _ := ctx.Value(forceCtxKey{}).(bool)
+	return v
+}
@@ -0,0 +1,41 @@
+package slogr_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLevel(t *testing.T) {
+	// Given:
+	var console, file bytes.Buffer
+	base := slog.New(slogr.Sample(
+		slogr.Fanout(
+			slog.NewTextHandler(&console, &slog.HandlerOptions{AddSource: false, Level: slog.LevelInfo, ReplaceAttr: nil}),
+			slog.NewTextHandler(&file, &slog.HandlerOptions{AddSource: false, Level: slog.LevelWarn, ReplaceAttr: nil}),
+		),
+		slogr.WithSampleInitial(1),
+		slogr.WithSampleThereafter(0),
+		slogr.WithSampleInterval(time.Minute),
+	))
+	debug := slogr.WithLevel(base, slog.LevelDebug).With("request-id", "1")
+
+	// When:
+	base.Debug("hidden")
+	for range 3 {
+		debug.Debug("details")
+		debug.Info("START")
+	}
+
+	// Then:
+	assert.NotContains(t, console.String(), "hidden")
+	assert.Equal(t, 3, strings.Count(console.String(), "msg=details request-id=1"), "forced records are not sampled")
+	assert.Equal(t, 1, strings.Count(console.String(), "msg=START"), "enabled records are still sampled")
+	assert.Equal(t, 3, strings.Count(file.String(), "msg=details"), "forced records reach every sink")
+}
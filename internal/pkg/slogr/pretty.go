@@ -0,0 +1,219 @@
+package slogr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// ANSI escape codes used by the pretty handler
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+)
+
+// messageWidth is the width messages are padded to, so that attributes line up
+const messageWidth = 40
+
+// PrettyOptions configures a pretty handler
+type PrettyOptions struct {
+	slog.HandlerOptions
+
+	NoColor    bool   // Disables ANSI colors, e.g. when not writing to a terminal
+	TimeFormat string // Defaults to "15:04:05.000"
+}
+
+// prettyHandler writes human friendly, single line records:
+//
+//	15:04:05.000 INF message                                  key=value source=file.go:12
+//
+// The source is always shown for debug records.
+type prettyHandler struct {
+	opts   PrettyOptions
+	w      io.Writer
+	mu     *sync.Mutex
+	attrs  string // Preformatted attributes added with WithAttrs
+	groups []string
+}
+
+// NewPrettyHandler creates a handler writing colorized, aligned records to w
+func NewPrettyHandler(w io.Writer, opts *PrettyOptions) slog.Handler {
+	o := *opts
+	if o.TimeFormat == "" {
+		o.TimeFormat = "15:04:05.000"
+	}
+	if o.Level == nil {
+		o.Level = slog.LevelInfo
+	}
+
+	return &prettyHandler{
+		opts:   o,
+		w:      w,
+		mu:     &sync.Mutex{},
+		attrs:  "",
+		groups: nil,
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *prettyHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.opts.Level.Level()
+}
+
+// Handle implements slog.Handler
+func (h *prettyHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+
+	if !r.Time.IsZero() {
+		b.WriteString(h.color(ansiDim, r.Time.Format(h.opts.TimeFormat)))
+		b.WriteByte(' ')
+	}
+
+	b.WriteString(h.level(r.Level))
+	b.WriteByte(' ')
+
+	msg := r.Message
+	if rep := h.opts.ReplaceAttr; rep != nil {
+		msg = rep(nil, slog.String(slog.MessageKey, msg)).Value.String()
+	}
+	b.WriteString(msg)
+	if n := utf8.RuneCountInString(msg); n < messageWidth && (r.NumAttrs() > 0 || h.attrs != "") {
+		b.WriteString(strings.Repeat(" ", messageWidth-n))
+	}
+
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		h.writeAttr(&b, h.groups, a)
+		return true
+	})
+
+	if (h.opts.AddSource || r.Level <= slog.LevelDebug) && r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := frames.Next()
+		b.WriteString(h.color(ansiDim, " source="+filepath.Base(f.File)+":"+strconv.Itoa(f.Line)))
+	}
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	if err != nil {
+		return fmt.Errorf("io.WriteString: %w", err)
+	}
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *prettyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.attrs)
+	for _, a := range attrs {
+		h.writeAttr(&b, h.groups, a)
+	}
+
+	h2 := *h
+	h2.attrs = b.String()
+	return &h2
+}
+
+// WithGroup implements slog.Handler
+func (h *prettyHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	h2 := *h
+	h2.groups = append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &h2
+}
+
+// writeAttr writes " key=value", with the key qualified by its groups
+func (h *prettyHandler) writeAttr(b *strings.Builder, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if rep := h.opts.ReplaceAttr; rep != nil && a.Value.Kind() != slog.KindGroup {
+		a = rep(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			h.writeAttr(b, groups, ga)
+		}
+		return
+	}
+
+	key := a.Key
+	if len(groups) > 0 {
+		key = strings.Join(groups, ".") + "." + key
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(h.color(ansiCyan, key+"="))
+	value := formatPrettyValue(a.Value)
+	if a.Key == "err" || a.Key == "error" {
+		value = h.color(ansiRed, value)
+	}
+	b.WriteString(value)
+}
+
+// level returns the colored, three letter abbreviation of a level
+func (h *prettyHandler) level(l slog.Level) string {
+	switch {
+	case l < slog.LevelInfo:
+		return h.color(ansiMagenta, "DBG")
+	case l < slog.LevelWarn:
+		return h.color(ansiGreen, "INF")
+	case l < slog.LevelError:
+		return h.color(ansiYellow, "WRN")
+	default:
+		return h.color(ansiRed, "ERR")
+	}
+}
+
+// color wraps s in an ANSI color code, unless colors are disabled
+func (h *prettyHandler) color(code, s string) string {
+	if h.opts.NoColor {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+// formatPrettyValue formats a value, quoting strings that would be ambiguous
+func formatPrettyValue(v slog.Value) string {
+	var s string
+	switch v.Kind() {
+	case slog.KindString:
+		s = v.String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny, slog.KindBool, slog.KindFloat64, slog.KindInt64,
+		slog.KindUint64, slog.KindGroup, slog.KindLogValuer:
+		s = v.String()
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
@@ -0,0 +1,191 @@
+package slogr
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces sensitive values in log records
+const Redacted = "[REDACTED]"
+
+// Patterns of sensitive values commonly found in free text. Matches of
+// CardNumberPattern are only redacted when they pass the Luhn check, so that
+// timestamps, order IDs or phone numbers of the same length are kept.
+var (
+	EmailPattern       = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	BearerTokenPattern = regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	CardNumberPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+)
+
+// patternChecks tell the matches of a pattern that are sensitive values from
+// the others, for the patterns too broad on their own
+var patternChecks = map[*regexp.Regexp]func(string) bool{
+	CardNumberPattern: luhnValid,
+}
+
+// ValuePattern names one of the predefined patterns of sensitive values
+type ValuePattern string
+
+// Supported value patterns
+const (
+	PatternEmail       ValuePattern = "email"
+	PatternBearerToken ValuePattern = "bearer"
+	PatternCardNumber  ValuePattern = "card"
+)
+
+// ErrUnknownPattern is returned when parsing an unsupported value pattern
+var ErrUnknownPattern = errors.New("unknown redaction pattern")
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *ValuePattern) UnmarshalText(text []byte) error {
+	v := ValuePattern(strings.ToLower(string(text)))
+	if v.Regexp() == nil {
+		return fmt.Errorf("%w: %q", ErrUnknownPattern, text)
+	}
+	*p = v
+	return nil
+}
+
+// Regexp returns the regular expression of the pattern, nil if unknown
+func (p ValuePattern) Regexp() *regexp.Regexp {
+	switch p {
+	case PatternEmail:
+		return EmailPattern
+	case PatternBearerToken:
+		return BearerTokenPattern
+	case PatternCardNumber:
+		return CardNumberPattern
+	default:
+		return nil
+	}
+}
+
+// redactor holds the rules applied by Redact
+type redactor struct {
+	keys   []string
+	values []*regexp.Regexp
+}
+
+// RedactOption is a function that configures Redact
+type RedactOption func(*redactor)
+
+// WithRedactKeys redacts the whole value of attributes whose key, or the key of
+// one of their groups, matches one of the patterns. Patterns are case-insensitive
+// and may use path.Match wildcards, e.g. "*token*".
+func WithRedactKeys(patterns ...string) RedactOption {
+	return func(r *redactor) {
+		for _, p := range patterns {
+			r.keys = append(r.keys, strings.ToLower(p))
+		}
+	}
+}
+
+// WithRedactValues replaces the parts of string values matching one of the regular expressions
+func WithRedactValues(res ...*regexp.Regexp) RedactOption {
+	return func(r *redactor) {
+		r.values = append(r.values, res...)
+	}
+}
+
+// Redact returns a function to use as slog.HandlerOptions.ReplaceAttr, replacing
+// sensitive values with [REDACTED]. Strings, errors and fmt.Stringer values
+// are checked against the value patterns, other kinds only against the keys.
+func Redact(opts ...RedactOption) func(groups []string, a slog.Attr) slog.Attr {
+	r := &redactor{keys: nil, values: nil}
+	for _, o := range opts {
+		o(r)
+	}
+
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// Built-in attributes, except the message, are never sensitive
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey || a.Key == slog.SourceKey) {
+			return a
+		}
+
+		if r.matchKey(a.Key) || slices.ContainsFunc(groups, r.matchKey) {
+			return slog.String(a.Key, Redacted)
+		}
+
+		s, ok := stringValue(a.Value)
+		if !ok || len(r.values) == 0 {
+			return a
+		}
+		redacted := s
+		for _, re := range r.values {
+			check, ok := patternChecks[re]
+			if !ok {
+				redacted = re.ReplaceAllString(redacted, Redacted)
+				continue
+			}
+			redacted = re.ReplaceAllStringFunc(redacted, func(m string) string {
+				if !check(m) {
+					return m
+				}
+				return Redacted
+			})
+		}
+		if redacted == s {
+			return a
+		}
+		return slog.String(a.Key, redacted)
+	}
+}
+
+// matchKey reports whether key matches one of the key patterns
+func (r *redactor) matchKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, p := range r.keys {
+		if ok, _ := path.Match(p, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// luhnValid reports whether the digits of s, ignoring separators, pass the
+// Luhn checksum of card numbers
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n > 0 && sum%10 == 0
+}
+
+// stringValue returns the text of values that may contain free text
+func stringValue(v slog.Value) (string, bool) {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String(), true
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return x.Error(), true
+		case fmt.Stringer:
+			return x.String(), true
+		}
+		return "", false
+	case slog.KindBool, slog.KindDuration, slog.KindFloat64, slog.KindInt64,
+		slog.KindUint64, slog.KindTime, slog.KindGroup, slog.KindLogValuer:
+		return "", false
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,116 @@
+package slogr_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want string
+	}{
+		{
+			name: "sensitive key",
+			log:  func(l *slog.Logger) { l.Info("login", "password", "hunter2", "user", "bob") },
+			want: `msg=login password=[REDACTED] user=bob`,
+		},
+		{
+			name: "key wildcard and case",
+			log:  func(l *slog.Logger) { l.Info("refresh", "Refresh_Token", "abc", "expires", 60) },
+			want: `msg=refresh Refresh_Token=[REDACTED] expires=60`,
+		},
+		{
+			name: "sensitive group",
+			log:  func(l *slog.Logger) { l.Info("request", slog.Group("authorization", "scheme", "basic")) },
+			want: `msg=request authorization.scheme=[REDACTED]`,
+		},
+		{
+			name: "email in value",
+			log:  func(l *slog.Logger) { l.Info("sent", "to", "Bob <bob@example.com>") },
+			want: `msg=sent to="Bob <[REDACTED]>"`,
+		},
+		{
+			name: "bearer token in error",
+			log: func(l *slog.Logger) {
+				l.Error("upstream", "err", errors.New("rejected header Bearer eyJhbGciOi.x-y_z"))
+			},
+			want: `msg=upstream err="rejected header [REDACTED]"`,
+		},
+		{
+			name: "card number in message",
+			log:  func(l *slog.Logger) { l.Info("paid with 4111 1111 1111 1111", "amount", 42) },
+			want: `msg="paid with [REDACTED]" amount=42`,
+		},
+		{
+			name: "card-like number failing the Luhn check",
+			log:  func(l *slog.Logger) { l.Info("shipped", "order-id", "1234567890123456", "at", "1737158782000") },
+			want: `msg=shipped order-id=1234567890123456 at=1737158782000`,
+		},
+		{
+			name: "nothing sensitive",
+			log:  func(l *slog.Logger) { l.Info("START", "user-agent", "curl/8.0", "request-id", "host/abc-000001") },
+			want: `msg=START user-agent=curl/8.0 request-id=host/abc-000001`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+				AddSource: false,
+				Level:     slog.LevelInfo,
+				ReplaceAttr: slogr.Redact(
+					slogr.WithRedactKeys("password", "*token*", "authorization"),
+					slogr.WithRedactValues(slogr.EmailPattern, slogr.BearerTokenPattern, slogr.CardNumberPattern),
+				),
+			}))
+
+			// When:
+			tt.log(logger)
+
+			// Then:
+			assert.Contains(t, buf.String(), tt.want)
+		})
+	}
+}
+
+func TestValuePattern_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		want    slogr.ValuePattern
+		wantErr error
+	}{
+		{name: "email", text: "email", want: slogr.PatternEmail, wantErr: nil},
+		{name: "bearer upper case", text: "BEARER", want: slogr.PatternBearerToken, wantErr: nil},
+		{name: "card", text: "card", want: slogr.PatternCardNumber, wantErr: nil},
+		{name: "unknown", text: "ssn", want: "", wantErr: slogr.ErrUnknownPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var p slogr.ValuePattern
+
+			// When:
+			err := p.UnmarshalText([]byte(tt.text))
+
+			// Then:
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+			assert.NotNil(t, p.Regexp())
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package slogr
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the timestamp added to rotated file names, sortable as text
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.WriteCloser appending to a file that is rotated once
+// it reaches a size or an age. Rotated files are renamed with a timestamp,
+// e.g. server-2025-01-02T15-04-05.000.log, optionally gzipped, and removed
+// according to the retention settings.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+	now        func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	cleanup  sync.WaitGroup
+	cleanMu  sync.Mutex // Serializes compressAndPrune between rotations
+}
+
+// RotateOption is a function that configures a RotatingFile
+type RotateOption func(*RotatingFile)
+
+// WithMaxSize rotates the file before it grows beyond n bytes, 0 disables it
+func WithMaxSize(n int64) RotateOption {
+	return func(f *RotatingFile) {
+		f.maxSize = n
+	}
+}
+
+// WithRotateInterval rotates the file once it has been open for d, 0 disables it
+func WithRotateInterval(d time.Duration) RotateOption {
+	return func(f *RotatingFile) {
+		f.interval = d
+	}
+}
+
+// WithMaxBackups keeps at most n rotated files, 0 keeps them all
+func WithMaxBackups(n int) RotateOption {
+	return func(f *RotatingFile) {
+		f.maxBackups = n
+	}
+}
+
+// WithMaxAge removes rotated files older than d, 0 keeps them regardless of age
+func WithMaxAge(d time.Duration) RotateOption {
+	return func(f *RotatingFile) {
+		f.maxAge = d
+	}
+}
+
+// WithCompress gzips rotated files
+func WithCompress(compress bool) RotateOption {
+	return func(f *RotatingFile) {
+		f.compress = compress
+	}
+}
+
+// WithRotateClock sets the time source, useful for tests
+func WithRotateClock(now func() time.Time) RotateOption {
+	return func(f *RotatingFile) {
+		f.now = now
+	}
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory if needed.
+// Defaults: rotates at 100MB, keeps every rotated file uncompressed.
+func OpenRotatingFile(path string, opts ...RotateOption) (*RotatingFile, error) {
+	f := &RotatingFile{
+		path:       path,
+		maxSize:    100 << 20,
+		interval:   0,
+		maxBackups: 0,
+		maxAge:     0,
+		compress:   false,
+		now:        time.Now,
+		mu:         sync.Mutex{},
+		file:       nil,
+		size:       0,
+		openedAt:   time.Time{},
+		cleanup:    sync.WaitGroup{},
+		cleanMu:    sync.Mutex{},
+	}
+	for _, o := range opts {
+		o(f)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("os.MkdirAll: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+// Write implements io.Writer, rotating the file first when needed
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	// a failed rotation is retried on the next write, p is still appended to
+	// the current file when it could be reopened
+	var rotateErr error
+	bySize := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	byAge := f.interval > 0 && f.now().Sub(f.openedAt) >= f.interval
+	if bySize || byAge {
+		if rotateErr = f.rotate(); f.file == nil {
+			return 0, rotateErr
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	if err != nil {
+		return n, errors.Join(rotateErr, fmt.Errorf("file.Write: %w", err))
+	}
+	return n, rotateErr
+}
+
+// Rotate renames the current file and starts a new one
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Close closes the file, waiting for rotated files to be compressed and cleaned up
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	file := f.file
+	f.file = nil
+	f.mu.Unlock()
+
+	f.cleanup.Wait()
+
+	if file == nil {
+		return nil
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+	return nil
+}
+
+// open opens the file for appending and records its current size
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+	fi, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("file.Stat: %w", err)
+	}
+
+	f.file = file
+	f.size = fi.Size()
+	f.openedAt = f.now()
+	return nil
+}
+
+// reopen opens the file again after it was closed by rotate, leaving f.file
+// nil rather than closed when that fails
+func (f *RotatingFile) reopen() error {
+	if err := f.open(); err != nil {
+		f.file = nil
+		return err
+	}
+	return nil
+}
+
+// rotate must be called with the lock held. When the file cannot be renamed,
+// it is reopened to go on appending to it, and f.file is nil when that fails.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return errors.Join(fmt.Errorf("file.Close: %w", err), f.reopen())
+	}
+
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + f.now().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return errors.Join(fmt.Errorf("os.Rename: %w", err), f.reopen())
+	}
+
+	if err := f.reopen(); err != nil {
+		return err
+	}
+
+	f.cleanup.Add(1)
+	go func() {
+		defer f.cleanup.Done()
+		if err := f.compressAndPrune(backup); err != nil {
+			fmt.Fprintf(os.Stderr, "slogr: rotate %s: %v\n", f.path, err)
+		}
+	}()
+
+	return nil
+}
+
+// compressAndPrune compresses the new backup and removes the backups exceeding the retention
+func (f *RotatingFile) compressAndPrune(backup string) error {
+	f.cleanMu.Lock()
+	defer f.cleanMu.Unlock()
+
+	var errs []error
+	if f.compress {
+		if err := gzipFile(backup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if f.maxBackups > 0 || f.maxAge > 0 {
+		ext := filepath.Ext(f.path)
+		matches, err := filepath.Glob(strings.TrimSuffix(f.path, ext) + "-*" + ext + "*")
+		if err != nil {
+			return fmt.Errorf("filepath.Glob: %w", err)
+		}
+		// the pattern also matches files such as server-access.log next to server.log
+		backups := slices.DeleteFunc(matches, func(m string) bool { return !f.isBackup(m) })
+		// names embed a sortable timestamp, newest first
+		slices.Sort(backups)
+		slices.Reverse(backups)
+
+		for i, b := range backups {
+			expired := false
+			if f.maxAge > 0 {
+				if fi, err := os.Stat(b); err == nil && f.now().Sub(fi.ModTime()) > f.maxAge {
+					expired = true
+				}
+			}
+			if (f.maxBackups > 0 && i >= f.maxBackups) || expired {
+				if err := os.Remove(b); err != nil && !errors.Is(err, os.ErrNotExist) {
+					errs = append(errs, fmt.Errorf("os.Remove: %w", err))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// isBackup reports whether name is a backup of the file, named with a
+// backupTimeFormat timestamp and optionally gzipped
+func (f *RotatingFile) isBackup(name string) bool {
+	ext := filepath.Ext(f.path)
+	stamp, ok := strings.CutPrefix(name, strings.TrimSuffix(f.path, ext)+"-")
+	if !ok {
+		return false
+	}
+	stamp = strings.TrimSuffix(stamp, ".gz")
+	if stamp, ok = strings.CutSuffix(stamp, ext); !ok {
+		return false
+	}
+	_, err := time.Parse(backupTimeFormat, stamp)
+	return err == nil
+}
+
+// gzipFile replaces path with a gzipped path.gz
+func gzipFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("os.Open: %w", err)
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("os.OpenFile: %w", err)
+	}
+
+	zw := gzip.NewWriter(dst)
+	if _, err := io.Copy(zw, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("io.Copy: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		dst.Close()
+		return fmt.Errorf("gzip.Close: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("file.Close: %w", err)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("os.Remove: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,179 @@
+package slogr_test
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []slogr.RotateOption
+		writes      []string
+		step        time.Duration // Clock advance before each write
+		wantCurrent string
+		wantBackups []string // Contents of the rotated files, oldest first
+	}{
+		{
+			name:        "no rotation",
+			opts:        []slogr.RotateOption{slogr.WithMaxSize(100)},
+			writes:      []string{"a\n", "b\n"},
+			step:        time.Second,
+			wantCurrent: "a\nb\n",
+			wantBackups: nil,
+		},
+		{
+			name:        "by size",
+			opts:        []slogr.RotateOption{slogr.WithMaxSize(4)},
+			writes:      []string{"a\n", "b\n", "c\n"},
+			step:        time.Second,
+			wantCurrent: "c\n",
+			wantBackups: []string{"a\nb\n"},
+		},
+		{
+			name:        "by interval",
+			opts:        []slogr.RotateOption{slogr.WithMaxSize(0), slogr.WithRotateInterval(time.Hour)},
+			writes:      []string{"a\n", "b\n", "c\n"},
+			step:        40 * time.Minute,
+			wantCurrent: "b\nc\n",
+			wantBackups: []string{"a\n"},
+		},
+		{
+			name:        "compressed",
+			opts:        []slogr.RotateOption{slogr.WithMaxSize(2), slogr.WithCompress(true)},
+			writes:      []string{"a\n", "b\n"},
+			step:        time.Second,
+			wantCurrent: "b\n",
+			wantBackups: []string{"a\n"},
+		},
+		{
+			name:        "max backups",
+			opts:        []slogr.RotateOption{slogr.WithMaxSize(2), slogr.WithMaxBackups(2)},
+			writes:      []string{"a\n", "b\n", "c\n", "d\n"},
+			step:        time.Second,
+			wantCurrent: "d\n",
+			wantBackups: []string{"b\n", "c\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			path := filepath.Join(t.TempDir(), "logs", "server.log")
+			now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+			clock := func() time.Time { return now }
+			f, err := slogr.OpenRotatingFile(path, append(tt.opts, slogr.WithRotateClock(clock))...)
+			require.NoError(t, err)
+
+			// When:
+			for _, w := range tt.writes {
+				now = now.Add(tt.step)
+				_, err := f.Write([]byte(w))
+				require.NoError(t, err)
+			}
+			require.NoError(t, f.Close())
+
+			// Then:
+			current, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCurrent, string(current))
+			assert.Equal(t, tt.wantBackups, readBackups(t, filepath.Dir(path)))
+		})
+	}
+}
+
+func TestRotatingFile_KeepsOtherFiles(t *testing.T) {
+	// Given: files next to the log whose names look like backups
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+	others := []string{"server-access.log", "server-access.log.gz", "server-2025.log"}
+	for _, name := range others {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("other\n"), 0o600))
+	}
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	f, err := slogr.OpenRotatingFile(path, slogr.WithMaxSize(2), slogr.WithMaxBackups(1),
+		slogr.WithRotateClock(func() time.Time { return now }))
+	require.NoError(t, err)
+
+	// When: rotated more often than backups are kept
+	for _, w := range []string{"a\n", "b\n", "c\n"} {
+		now = now.Add(time.Second)
+		_, err := f.Write([]byte(w))
+		require.NoError(t, err)
+	}
+	require.NoError(t, f.Close())
+
+	// Then: only backups are pruned
+	for _, name := range others {
+		assert.FileExists(t, filepath.Join(dir, name))
+	}
+	backups, err := filepath.Glob(filepath.Join(dir, "server-2025-*.log"))
+	require.NoError(t, err)
+	assert.Len(t, backups, 1)
+}
+
+func TestRotatingFile_RenameFails(t *testing.T) {
+	// Given: a directory where the next backup would be renamed to
+	dir := t.TempDir()
+	path := filepath.Join(dir, "server.log")
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	f, err := slogr.OpenRotatingFile(path, slogr.WithMaxSize(2), slogr.WithRotateClock(func() time.Time { return now }))
+	require.NoError(t, err)
+	blocker := filepath.Join(dir, "server-2025-01-02T12-00-02.000.log")
+	require.NoError(t, os.MkdirAll(filepath.Join(blocker, "keep"), 0o755))
+
+	// When: the rotation fails, then succeeds once the directory is gone
+	now = now.Add(time.Second)
+	_, err = f.Write([]byte("a\n"))
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	_, rotateErr := f.Write([]byte("b\n"))
+	require.NoError(t, os.RemoveAll(blocker))
+	now = now.Add(time.Second)
+	_, err = f.Write([]byte("c\n"))
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Then: the file is reopened and nothing is lost
+	require.ErrorContains(t, rotateErr, "os.Rename")
+	current, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "c\n", string(current))
+	assert.Equal(t, []string{"a\nb\n"}, readBackups(t, dir))
+}
+
+// readBackups returns the contents of the rotated files in dir, oldest first
+func readBackups(t *testing.T, dir string) []string {
+	t.Helper()
+
+	names, err := filepath.Glob(filepath.Join(dir, "server-*.log*"))
+	require.NoError(t, err)
+	sort.Strings(names)
+
+	var backups []string
+	for _, name := range names {
+		r, err := os.Open(name)
+		require.NoError(t, err)
+		defer r.Close()
+
+		var src io.Reader = r
+		if filepath.Ext(name) == ".gz" {
+			zr, err := gzip.NewReader(r)
+			require.NoError(t, err)
+			src = zr
+		}
+		b, err := io.ReadAll(src)
+		require.NoError(t, err)
+		backups = append(backups, string(b))
+	}
+	return backups
+}
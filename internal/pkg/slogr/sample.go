@@ -0,0 +1,127 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// sampler holds the counters shared by a sampling handler and the handlers derived from it
+type sampler struct {
+	initial    int
+	thereafter int
+	interval   time.Duration
+	now        func() time.Time
+
+	mu     sync.Mutex
+	window time.Time
+	counts map[sampleKey]int
+}
+
+// sampleKey identifies repetitive records
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// sampleHandler drops repetitive records below the warning level
+type sampleHandler struct {
+	next    slog.Handler
+	sampler *sampler
+}
+
+// SampleOption is a function that configures a sampling handler
+type SampleOption func(*sampler)
+
+// WithSampleInitial keeps the first n records of each message per interval, 100 by default
+func WithSampleInitial(n int) SampleOption {
+	return func(s *sampler) {
+		s.initial = n
+	}
+}
+
+// WithSampleThereafter keeps every nth record of each message once the initial
+// records have been logged, 100 by default, 0 drops them all
+func WithSampleThereafter(n int) SampleOption {
+	return func(s *sampler) {
+		s.thereafter = n
+	}
+}
+
+// WithSampleInterval sets the period after which the counters are reset, 1s by default
+func WithSampleInterval(d time.Duration) SampleOption {
+	return func(s *sampler) {
+		s.interval = d
+	}
+}
+
+// WithSampleClock sets the time source, useful for tests
+func WithSampleClock(now func() time.Time) SampleOption {
+	return func(s *sampler) {
+		s.now = now
+	}
+}
+
+// Sample returns a handler that keeps every WARN and ERROR record but rate
+// limits the INFO and DEBUG records with the same level and message, so that
+// logs stay readable under load. Counters are shared by handlers derived with
+// WithAttrs and WithGroup, e.g. request-scoped loggers.
+func Sample(next slog.Handler, opts ...SampleOption) slog.Handler {
+	s := &sampler{
+		initial:    100,
+		thereafter: 100,
+		interval:   time.Second,
+		now:        time.Now,
+		mu:         sync.Mutex{},
+		window:     time.Time{},
+		counts:     make(map[sampleKey]int),
+	}
+	for _, o := range opts {
+		o(s)
+	}
+
+	return &sampleHandler{next: next, sampler: s}
+}
+
+// Enabled implements slog.Handler
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler. Records forced with WithLevel are not sampled.
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < slog.LevelWarn && !forced(ctx) && !h.sampler.keep(sampleKey{level: r.Level, msg: r.Message}) {
+		return nil
+	}
+	return h.next.Handle(ctx, r) //nolint:wrapcheck // errors of the wrapped handler are returned as is
+}
+
+// WithAttrs implements slog.Handler
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{next: h.next.WithAttrs(attrs), sampler: h.sampler}
+}
+
+// WithGroup implements slog.Handler
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{next: h.next.WithGroup(name), sampler: h.sampler}
+}
+
+// keep counts a record and reports whether it should be logged.
+// All counters are reset every interval, which also bounds their number.
+func (s *sampler) keep(key sampleKey) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.window) >= s.interval {
+		s.window = now
+		clear(s.counts)
+	}
+
+	s.counts[key]++
+	n := s.counts[key]
+	if n <= s.initial {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.initial)%s.thereafter == 0
+}
@@ -0,0 +1,80 @@
+package slogr_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"go-starter/internal/pkg/slogr"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      slog.Level
+		records    int
+		initial    int
+		thereafter int
+		want       int
+	}{
+		{name: "under the initial count", level: slog.LevelInfo, records: 3, initial: 5, thereafter: 10, want: 3},
+		{name: "every nth thereafter", level: slog.LevelInfo, records: 25, initial: 5, thereafter: 10, want: 7},
+		{name: "drop thereafter", level: slog.LevelDebug, records: 25, initial: 5, thereafter: 0, want: 5},
+		{name: "warnings are kept", level: slog.LevelWarn, records: 25, initial: 5, thereafter: 0, want: 25},
+		{name: "errors are kept", level: slog.LevelError, records: 25, initial: 5, thereafter: 0, want: 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Given:
+			var buf bytes.Buffer
+			now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+			logger := slog.New(slogr.Sample(
+				slog.NewTextHandler(&buf, &slog.HandlerOptions{AddSource: false, Level: slog.LevelDebug, ReplaceAttr: nil}),
+				slogr.WithSampleInitial(tt.initial),
+				slogr.WithSampleThereafter(tt.thereafter),
+				slogr.WithSampleInterval(time.Second),
+				slogr.WithSampleClock(func() time.Time { return now }),
+			))
+
+			// When:
+			for i := range tt.records {
+				// Request-scoped loggers share the counters
+				logger.With("i", i).Log(t.Context(), tt.level, "END")
+			}
+
+			// Then:
+			assert.Equal(t, tt.want, strings.Count(buf.String(), "msg=END"))
+		})
+	}
+}
+
+func TestSample_Interval(t *testing.T) {
+	// Given:
+	var buf bytes.Buffer
+	now := time.Date(2025, 1, 2, 12, 0, 0, 0, time.UTC)
+	logger := slog.New(slogr.Sample(
+		slog.NewTextHandler(&buf, nil),
+		slogr.WithSampleInitial(2),
+		slogr.WithSampleThereafter(0),
+		slogr.WithSampleInterval(time.Second),
+		slogr.WithSampleClock(func() time.Time { return now }),
+	))
+
+	// When:
+	for range 5 {
+		logger.Info("START")
+		logger.Info("END")
+	}
+	now = now.Add(time.Second)
+	for range 5 {
+		logger.Info("START")
+	}
+
+	// Then:
+	assert.Equal(t, 4, strings.Count(buf.String(), "msg=START"))
+	assert.Equal(t, 2, strings.Count(buf.String(), "msg=END"))
+}
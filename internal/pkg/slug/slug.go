@@ -0,0 +1,54 @@
+// Package slug makes URL friendly identifiers out of titles
+package slug
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gosimple/unidecode"
+)
+
+// MaxLength bounds the length of a slug made by Make
+const MaxLength = 80
+
+// Make returns the slug of s: transliterated to ASCII, lowercased, with runs
+// of other characters than letters and digits replaced by a hyphen, and cut
+// at MaxLength on a word boundary when possible. It is empty when s has no
+// letter or digit, e.g. "Crème brûlée!" is "creme-brulee".
+func Make(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(unidecode.Unidecode(s)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+			continue
+		}
+		hyphen = true
+	}
+
+	slug := b.String()
+	if len(slug) <= MaxLength {
+		return slug
+	}
+	slug = slug[:MaxLength]
+	if i := strings.LastIndexByte(slug, '-'); i > 0 {
+		return slug[:i]
+	}
+	return slug
+}
+
+// Unique returns base when free, or else the first free of base-2, base-3 and so on
+func Unique(base string, free func(slug string) bool) string {
+	if free(base) {
+		return base
+	}
+	for n := 2; ; n++ {
+		if slug := base + "-" + strconv.Itoa(n); free(slug) {
+			return slug
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package slug_test
+
+import (
+	"strings"
+	"testing"
+
+	"go-starter/internal/pkg/slug"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMake(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		given string
+		want  string
+	}{
+		{"ascii", "Hello, World!", "hello-world"},
+		{"accents", "Crème brûlée à 5 €!", "creme-brulee-a-5-eu"},
+		{"cyrillic", "Привет мир", "privet-mir"},
+		{"han", "北京", "bei-jing"},
+		{"separators", "  --Go__1.24--  ", "go-1-24"},
+		{"no letter or digit", "?!", ""},
+		{"empty", "", ""},
+		{"long", strings.Repeat("word ", 20), strings.TrimSuffix(strings.Repeat("word-", 16), "-")},
+		{"long word", strings.Repeat("a", 100), strings.Repeat("a", slug.MaxLength)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// When:
+			got := slug.Make(tc.given)
+
+			// Then:
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestUnique(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"free", nil, "hello-world"},
+		{"taken", []string{"hello-world"}, "hello-world-2"},
+		{"suffixes taken", []string{"hello-world", "hello-world-2", "hello-world-3"}, "hello-world-4"},
+		{"gap", []string{"hello-world", "hello-world-3"}, "hello-world-2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			// Given:
+			taken := map[string]bool{}
+			for _, s := range tc.taken {
+				taken[s] = true
+			}
+
+			// When:
+			got := slug.Unique("hello-world", func(s string) bool { return !taken[s] })
+
+			// Then:
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}